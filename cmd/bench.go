@@ -0,0 +1,153 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/open-policy-agent/opa/v1/loader"
+	"github.com/open-policy-agent/opa/v1/util"
+
+	"github.com/open-policy-agent/eopa/pkg/rego_vm"
+	"github.com/open-policy-agent/eopa/pkg/selftest"
+)
+
+// defaultCompareCount is the number of evaluations per engine when
+// comparing them, unless set with --count.
+const defaultCompareCount = 1000
+
+// initBench wraps OPA bench: with --entrypoint, it compares the VM
+// performance against topdown instead of benchmarking a query.
+func initBench(opa *cobra.Command) *cobra.Command {
+	var policies, data []string
+	var entrypoint string
+
+	opa.Long += `
+Compare the VM performance against topdown, evaluating the entrypoint with both
+engines repeatedly, by giving --entrypoint instead of a query:
+
+	eopa bench --policy p.rego --data d.json --input i.json --entrypoint data.x.allow
+
+The report holds the latency distribution of each engine and the speedup of the
+VM. The engines must agree on the result of the entrypoint. --count is then the
+number of evaluations per engine, 1000 by default. With --format json, the
+report is printed as JSON, to be checked for performance regressions in CI.
+`
+
+	opa.Flags().StringArrayVar(&policies, "policy", nil, "set policy file(s) or directory path(s) to compare the engines with. This flag can be repeated.")
+	opa.Flags().StringVar(&entrypoint, "entrypoint", "", "set the query to compare the engines with, such as data.x.allow")
+
+	// OPA's data flag keeps its values to itself: record them as well.
+	if f := opa.Flags().Lookup("data"); f != nil {
+		f.Value = &recordedFlag{Value: f.Value, values: &data}
+	}
+
+	preRun := opa.PreRunE
+	opa.PreRunE = func(c *cobra.Command, args []string) error {
+		if entrypoint == "" {
+			if preRun == nil {
+				return nil
+			}
+			return preRun(c, args)
+		}
+		if len(args) > 0 {
+			return errors.New("specify either a query or --entrypoint")
+		}
+		return nil
+	}
+
+	run := opa.RunE
+	opa.RunE = func(c *cobra.Command, args []string) error {
+		if entrypoint == "" {
+			return run(c, args)
+		}
+		c.SilenceErrors = true
+		c.SilenceUsage = true
+
+		// Keep topdown as the "rego" target to compare the VM against.
+		rego_vm.SetDefault(false)
+
+		count := defaultCompareCount
+		if f := c.Flags().Lookup("count"); f != nil && f.Changed {
+			var err error
+			if count, err = c.Flags().GetInt("count"); err != nil {
+				return err
+			}
+		}
+
+		format := "pretty"
+		if f := c.Flags().Lookup("format"); f != nil {
+			format = f.Value.String()
+		}
+		if format != "pretty" && format != "json" {
+			return fmt.Errorf("unsupported format %q when comparing the engines", format)
+		}
+
+		inputPath, err := c.Flags().GetString("input")
+		if err != nil {
+			return err
+		}
+
+		return benchCompare(c, policies, data, inputPath, entrypoint, count, format)
+	}
+
+	return opa
+}
+
+func benchCompare(c *cobra.Command, policies, data []string, inputPath, entrypoint string, count int, format string) error {
+	result, err := loader.NewFileLoader().All(append(policies, data...))
+	if err != nil {
+		return err
+	}
+
+	opts := selftest.BenchOptions{
+		Modules:    make(map[string]string, len(result.Modules)),
+		Data:       result.Documents,
+		Entrypoint: entrypoint,
+		Count:      count,
+	}
+	for name, m := range result.Modules {
+		opts.Modules[name] = string(m.Raw)
+	}
+
+	if inputPath != "" {
+		bs, err := os.ReadFile(inputPath)
+		if err != nil {
+			return err
+		}
+		if err := util.Unmarshal(bs, &opts.Input); err != nil {
+			return fmt.Errorf("input %s: %w", inputPath, err)
+		}
+	}
+
+	report, err := selftest.Bench(c.Context(), opts)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(c.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return report.Write(c.OutOrStdout())
+}
+
+// recordedFlag records the values set, in order, before passing them on
+// to the flag value it wraps.
+type recordedFlag struct {
+	pflag.Value
+	values *[]string
+}
+
+func (f *recordedFlag) Set(s string) error {
+	*f.values = append(*f.values, s)
+	return f.Value.Set(s)
+}
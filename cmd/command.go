@@ -316,6 +316,8 @@ func EOPACommand() *cobra.Command {
 			addInstructionLimitFlag(c, &instructionLimit)
 			addOptimizationFlagsAndDescription(c, &optLevel, &enableOptPassFlags, &disableOptPassFlags)
 			root.AddCommand(initExec(c)) // wrap OPA exec
+		case "bench":
+			root.AddCommand(setDefaults(initBench(c))) // wrap OPA bench
 		case "version":
 			root.AddCommand(initVersion()) // override version
 		default:
@@ -327,6 +329,7 @@ func EOPACommand() *cobra.Command {
 	root.AddCommand(initBundle())
 	root.AddCommand(liaCtl())
 	root.AddCommand(regal())
+	root.AddCommand(initSelftest())
 
 	root.AddCommand(loginCmd())
 	root.AddCommand(pullCmd())
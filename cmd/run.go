@@ -33,6 +33,7 @@ import (
 	"github.com/open-policy-agent/eopa/pkg/plugins/grpc"
 	"github.com/open-policy-agent/eopa/pkg/plugins/impact"
 	"github.com/open-policy-agent/eopa/pkg/preview"
+	"github.com/open-policy-agent/eopa/pkg/rego_vm"
 	"github.com/open-policy-agent/eopa/pkg/storage"
 	"github.com/open-policy-agent/eopa/pkg/vm"
 )
@@ -327,7 +328,9 @@ func initRuntime(ctx context.Context, params *runCmdParams, args []string) (*run
 	ekmHook := ekm.NewEKM()
 	previewHook := preview.NewHook()
 	evalCacheHook := vm.NewCacheHook()
-	hs := hooks.New(ekmHook, previewHook, evalCacheHook)
+	objectIndexHook := vm.NewObjectIndexHook()
+	builtinPolicyHook := vm.NewBuiltinPolicyHook()
+	hs := hooks.New(ekmHook, previewHook, evalCacheHook, objectIndexHook, builtinPolicyHook, a)
 
 	params.rt.Hooks = hs
 
@@ -338,6 +341,7 @@ func initRuntime(ctx context.Context, params *runCmdParams, args []string) (*run
 
 	params.rt.Logger = logger
 	ekmHook.SetLogger(logger)
+	a.SetLogger(logger)
 
 	params.rt.ExtraDiscoveryOpts = []func(*discovery.Discovery){
 		discovery.Factories(map[string]plugins.Factory{
@@ -362,6 +366,19 @@ func initRuntime(ctx context.Context, params *runCmdParams, args []string) (*run
 
 	plugins.InitBundles(nil)(rt.Manager) // To release memory holding the init bundles.
 
+	warmOpts, err := rego_vm.WarmingConfig(rt.Manager.Config)
+	if err != nil {
+		return nil, err
+	}
+	if warmOpts != nil {
+		if err := rego_vm.EnableWarming(rt.Manager, *warmOpts); err != nil {
+			return nil, err
+		}
+	}
+
+	// Registered last, to be stopped last: the mapped data is released right before the store is closed.
+	rt.Manager.Register(bundle.MappedBundlesPluginName, a.MappedBundlesPlugin(rt.Manager))
+
 	rt.SetDistributedTracingLogging()
 
 	previewHook.Init(rt.Manager)
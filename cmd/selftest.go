@@ -0,0 +1,56 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/open-policy-agent/eopa/pkg/builtins"
+	"github.com/open-policy-agent/eopa/pkg/rego_vm"
+	"github.com/open-policy-agent/eopa/pkg/selftest"
+)
+
+func initSelftest() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "selftest",
+		Short: "Run EOPA conformance checks",
+	}
+	cmd.AddCommand(selftestBuiltins())
+	return cmd
+}
+
+func selftestBuiltins() *cobra.Command {
+	return &cobra.Command{
+		Use:   "builtins",
+		Short: "Check the natively implemented builtins against topdown and expected results",
+		Long: `Evaluate a battery of inputs through every builtin the VM implements natively,
+with both the VM and topdown, and report the failing cases.
+
+The builtins topdown implements fail if the VM disagrees with topdown. The EOPA
+extension builtins have no topdown implementation of their own: they fail if
+either result differs from the expected one.
+
+Exits with a non-zero status if any case fails.`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			c.SilenceUsage = true
+
+			// Register the EOPA builtins, but keep topdown as the
+			// "rego" target to compare the VM against.
+			builtins.Init()
+			rego_vm.SetDefault(false)
+
+			failures, err := selftest.Builtins(c.Context(), c.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			if failures > 0 {
+				return fmt.Errorf("%d builtin case(s) failed", failures)
+			}
+			return nil
+		},
+	}
+}
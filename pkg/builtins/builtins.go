@@ -83,6 +83,31 @@ var DefaultBuiltins = [...]*ast.Builtin{
 	vaultSend,
 	neo4jQuery,
 	redisQuery,
+
+	// EOPA extension builtins.
+	objectGetMerged,
+	objectHasPath,
+	numbersParseBase,
+	numbersToBase,
+	setToSortedArray,
+	arrayToSet,
+	arrayIndexOf,
+	objectDiff,
+	walkUntil,
+	hash,
+	sizeBytes,
+	isValidEmail,
+	isValidHostname,
+	parseURL,
+	sample,
+	objectMerge,
+	countAtLeast,
+	countAtMost,
+	jwtDecodeSegment,
+	find,
+	matches,
+	pointerGet,
+	pointerSet,
 }
 
 func getRequestStringWithDefault(obj ast.Object, key string, def string) (string, error) {
@@ -0,0 +1,328 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package builtins
+
+import (
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/types"
+
+	"github.com/open-policy-agent/eopa/pkg/vm"
+)
+
+// EOPA extension builtins implemented natively by the VM. Outside of
+// the VM, they're evaluated with vm.NativeBuiltin.
+var (
+	objectGetMerged = &ast.Builtin{
+		Name:        vm.ObjectGetMergedName,
+		Description: "Returns the value at the path within the object, deep-merged over the default if both are objects. If the path does not exist, returns the default.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("object", types.NewObject(nil, types.NewDynamicProperty(types.A, types.A))).Description("object to get the value from"),
+				types.Named("key", types.A).Description("key or path (array of keys) to look up"),
+				types.Named("default", types.A).Description("default to merge the value over, or to use if the path does not exist"),
+			),
+			types.Named("value", types.A).Description("value found, merged over the default"),
+		),
+	}
+
+	objectHasPath = &ast.Builtin{
+		Name:        vm.ObjectHasPathName,
+		Description: "Returns whether the path exists within the object, even if the value at the path is null. The path is looked up like with `object.get`.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("object", types.NewObject(nil, types.NewDynamicProperty(types.A, types.A))).Description("object to look the path up in"),
+				types.Named("key", types.A).Description("key or path (array of keys) to look up"),
+			),
+			types.Named("result", types.B).Description("true if the path exists"),
+		),
+	}
+
+	numbersParseBase = &ast.Builtin{
+		Name:        vm.NumbersParseBaseName,
+		Description: "Parses an integer from its string representation in the given base, between 2 and 36.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("x", types.S).Description("string to parse"),
+				types.Named("base", types.N).Description("base of the string representation"),
+			),
+			types.Named("n", types.N).Description("the parsed integer"),
+		),
+	}
+
+	numbersToBase = &ast.Builtin{
+		Name:        vm.NumbersToBaseName,
+		Description: "Returns the string representation of an integer in the given base, between 2 and 36. Digits above 9 are lowercase letters.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("n", types.N).Description("integer to format"),
+				types.Named("base", types.N).Description("base of the string representation"),
+			),
+			types.Named("x", types.S).Description("the formatted integer"),
+		),
+	}
+
+	setToSortedArray = &ast.Builtin{
+		Name:        vm.SetToSortedArrayName,
+		Description: "Returns the elements of a set as an array, sorted in the same order as `sort`.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("set", types.NewSet(types.A)).Description("set to convert"),
+			),
+			types.Named("array", types.NewArray(nil, types.A)).Description("sorted elements of the set"),
+		),
+	}
+
+	arrayToSet = &ast.Builtin{
+		Name:        vm.ArrayToSetName,
+		Description: "Returns the elements of an array as a set, removing duplicates.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("array", types.NewArray(nil, types.A)).Description("array to convert"),
+			),
+			types.Named("set", types.NewSet(types.A)).Description("distinct elements of the array"),
+		),
+	}
+
+	arrayIndexOf = &ast.Builtin{
+		Name:        vm.ArrayIndexOfName,
+		Description: "Returns the index of the first element of the array equal to the value, or -1 if there is none.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("array", types.NewArray(nil, types.A)).Description("array to search"),
+				types.Named("x", types.A).Description("value to find"),
+			),
+			types.Named("index", types.N).Description("index of the first element equal to x, or -1"),
+		),
+	}
+
+	objectDiff = &ast.Builtin{
+		Name:        vm.ObjectDiffName,
+		Description: "Returns a JSON patch (RFC 6902) turning the first object into the second. Objects are compared member by member, arrays of equal length element by element, and any other change replaces the value.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("a", types.NewObject(nil, types.NewDynamicProperty(types.A, types.A))).Description("object to turn into b"),
+				types.Named("b", types.NewObject(nil, types.NewDynamicProperty(types.A, types.A))).Description("object a is turned into"),
+			),
+			types.Named("patch", types.NewArray(nil, types.NewObject(nil, types.NewDynamicProperty(types.S, types.A)))).Description("JSON patch operations"),
+		),
+	}
+
+	walkUntil = &ast.Builtin{
+		Name:        vm.WalkUntilName,
+		Description: "Walks the value like `walk`, but stops at the first value matching the pattern, returning its path and the value. An object pattern matches the objects having all its members, and any other pattern matches equal values. Values are visited before their children. Undefined if no value matches.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("x", types.A).Description("value to walk"),
+				types.Named("pattern", types.A).Description("pattern the value to find matches"),
+			),
+			types.Named("output", types.NewArray([]types.Type{types.NewArray(nil, types.A), types.A}, nil)).Description("pair of the path and the value found"),
+		),
+	}
+
+	hash = &ast.Builtin{
+		Name:        vm.HashName,
+		Description: "Returns a structural hash of the value, without serializing it. Equal values hash equally, regardless of how they are represented. The hash is not cryptographic.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("x", types.A).Description("value to hash"),
+			),
+			types.Named("y", types.S).Description("hex-encoded 64-bit hash of the value"),
+		),
+	}
+
+	sizeBytes = &ast.Builtin{
+		Name:        vm.SizeBytesName,
+		Description: "Returns the size of the compact JSON serialization of the value, in bytes, without serializing it. Sets are serialized as arrays, and strings are not HTML escaped.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("x", types.A).Description("value to measure"),
+			),
+			types.Named("n", types.N).Description("size of the value serialized, in bytes"),
+		),
+	}
+
+	isValidEmail = &ast.Builtin{
+		Name:        vm.IsValidEmailName,
+		Description: "Returns whether the string is a bare email address (RFC 5322), without a display name or angle brackets, whose domain is a valid hostname as checked by `eopa.is_valid_hostname`.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("x", types.S).Description("string to check"),
+			),
+			types.Named("result", types.B).Description("true if the string is a valid email address"),
+		),
+	}
+
+	isValidHostname = &ast.Builtin{
+		Name:        vm.IsValidHostnameName,
+		Description: "Returns whether the string is a hostname of dot-separated labels of letters, digits and hyphens (RFC 1123), with an optional trailing dot. Internationalized domain names are valid if they convert to ASCII (IDNA 2008), and are checked in their ASCII form.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("x", types.S).Description("string to check"),
+			),
+			types.Named("result", types.B).Description("true if the string is a valid hostname"),
+		),
+	}
+
+	parseURL = &ast.Builtin{
+		Name:        vm.ParseURLName,
+		Description: "Parses a URL into its components. The query is decoded into an object of arrays of values. The username is present only if the URL has user information, and the hostname is returned as in the URL, without converting internationalized domain names. The string is parsed as a URL reference, so relative references such as paths are accepted. Undefined if the string cannot be parsed as a URL reference, such as with an invalid percent-encoding.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("url", types.S).Description("URL to parse"),
+			),
+			types.Named("components", types.NewObject(nil, types.NewDynamicProperty(types.S, types.A))).Description("scheme, host, hostname, port, path, query, fragment and username of the URL"),
+		),
+	}
+
+	sample = &ast.Builtin{
+		Name:        vm.SampleName,
+		Description: "Returns n elements of the collection picked at random without replacement, in their order in the collection, or the whole collection if it has no more than n elements. The same seed picks the same elements of the same collection; a null seed draws one from the source of randomness of the evaluation.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("collection", types.NewAny(types.NewArray(nil, types.A), types.NewSet(types.A))).Description("array or set to sample"),
+				types.Named("n", types.N).Description("number of elements to pick"),
+				types.Named("seed", types.A).Description("value seeding the picks, or null"),
+			),
+			types.Named("sample", types.NewAny(types.NewArray(nil, types.A), types.NewSet(types.A))).Description("elements picked, of the type of the collection"),
+		),
+		Nondeterministic: true,
+	}
+
+	objectMerge = &ast.Builtin{
+		Name:        vm.ObjectMergeName,
+		Description: `Merges the second object over the first. Objects at the same key are merged recursively, and the keys of either kept. With the "arrays" option "concat", arrays at the same key are concatenated, the elements of b after those of a; with "replace", the default, they are replaced like any other value of b conflicting with a value of a.`,
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("a", types.NewObject(nil, types.NewDynamicProperty(types.A, types.A))).Description("object to merge into"),
+				types.Named("b", types.NewObject(nil, types.NewDynamicProperty(types.A, types.A))).Description("object to merge over a"),
+				types.Named("options", types.NewObject(nil, types.NewDynamicProperty(types.S, types.A))).Description(`merge options: "arrays" is "concat" or "replace"`),
+			),
+			types.Named("merged", types.NewObject(nil, types.NewDynamicProperty(types.A, types.A))).Description("merged object"),
+		),
+	}
+
+	countAtLeast = &ast.Builtin{
+		Name:        vm.CountAtLeastName,
+		Description: "Returns true if the collection or string has at least n elements or characters, without counting past n.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("collection", types.NewAny(
+					types.NewSet(types.A),
+					types.NewArray(nil, types.A),
+					types.NewObject(nil, types.NewDynamicProperty(types.A, types.A)),
+					types.S,
+				)).Description("collection or string to count"),
+				types.Named("n", types.N).Description("minimum count"),
+			),
+			types.Named("result", types.B).Description("true if the count is at least n"),
+		),
+	}
+
+	countAtMost = &ast.Builtin{
+		Name:        vm.CountAtMostName,
+		Description: "Returns true if the collection or string has at most n elements or characters, without counting past n + 1.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("collection", types.NewAny(
+					types.NewSet(types.A),
+					types.NewArray(nil, types.A),
+					types.NewObject(nil, types.NewDynamicProperty(types.A, types.A)),
+					types.S,
+				)).Description("collection or string to count"),
+				types.Named("n", types.N).Description("maximum count"),
+			),
+			types.Named("result", types.B).Description("true if the count is at most n"),
+		),
+	}
+
+	jwtDecodeSegment = &ast.Builtin{
+		Name:        vm.JWTDecodeSegmentName,
+		Description: "Decodes a base64url encoded JSON object, such as the header or payload segment of a JWT, in one step. Equivalent to `json.unmarshal(base64url.decode(segment))` for objects, without the intermediate string.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("segment", types.S).Description("base64url encoded JSON object, with or without padding"),
+			),
+			types.Named("decoded", types.NewObject(nil, types.NewDynamicProperty(types.A, types.A))).Description("decoded object"),
+		),
+	}
+
+	find = &ast.Builtin{
+		Name:        vm.FindName,
+		Description: "Returns the values a JSONPath query selects from the value, in the document order, or an empty array if none. The query starts with `$`, followed by the segments `.name` or `['name']` for object members, `[n]` for array elements, counting from the end if negative, `.*` or `[*]` for all members or elements, and `..` before any of them to apply it to the value and all its descendants. Object members are visited in the key order. Filters, slices and unions are not supported.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("value", types.A).Description("value to query"),
+				types.Named("path", types.S).Description("JSONPath query, such as `$.items[*].id`"),
+			),
+			types.Named("matches", types.NewArray(nil, types.A)).Description("values selected"),
+		),
+	}
+
+	matches = &ast.Builtin{
+		Name:        vm.MatchesName,
+		Description: "Checks if the value structurally matches the pattern. The string `\"_\"` matches any value, an object pattern matches the objects having all its keys, possibly among others, with matching values, an array pattern matches the arrays of the same length with matching elements, and any other pattern matches equal values.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("value", types.A).Description("value to match"),
+				types.Named("pattern", types.A).Description("pattern, such as `{\"kind\": \"Pod\", \"metadata\": {\"namespace\": \"_\"}}`"),
+			),
+			types.Named("result", types.B).Description("true if the value matches the pattern"),
+		),
+	}
+
+	pointerGet = &ast.Builtin{
+		Name:        vm.PointerGetName,
+		Description: "Returns the value at the JSON pointer (RFC 6901), such as `/a/b/0`, or undefined if there is none. The empty pointer refers to the value itself.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("value", types.A).Description("value to read"),
+				types.Named("pointer", types.S).Description("JSON pointer"),
+			),
+			types.Named("result", types.A).Description("value at the pointer"),
+		),
+	}
+
+	pointerSet = &ast.Builtin{
+		Name:        vm.PointerSetName,
+		Description: "Returns a copy of the value with the value at the JSON pointer (RFC 6901) set, adding the object member if it does not exist, or appending to the array if the index is `-` or the array length. Undefined if the parent of the pointer does not exist. The empty pointer replaces the value itself.",
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("value", types.A).Description("value to copy"),
+				types.Named("pointer", types.S).Description("JSON pointer"),
+				types.Named("x", types.A).Description("value to set at the pointer"),
+			),
+			types.Named("result", types.A).Description("copy of the value with x set"),
+		),
+	}
+)
+
+func init() {
+	for _, b := range []*ast.Builtin{
+		objectGetMerged,
+		objectHasPath,
+		numbersParseBase,
+		numbersToBase,
+		setToSortedArray,
+		arrayToSet,
+		arrayIndexOf,
+		objectDiff,
+		walkUntil,
+		hash,
+		sizeBytes,
+		isValidEmail,
+		isValidHostname,
+		parseURL,
+		sample,
+		objectMerge,
+		countAtLeast,
+		countAtMost,
+		jwtDecodeSegment,
+		find,
+		matches,
+		pointerGet,
+		pointerSet,
+	} {
+		RegisterBuiltinFunc(b.Name, vm.NativeBuiltin(b.Name))
+	}
+}
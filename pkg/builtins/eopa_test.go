@@ -0,0 +1,156 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package builtins_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+func TestEOPABuiltins(t *testing.T) {
+	tests := []struct {
+		note   string
+		query  string
+		result string
+	}{
+		{
+			note:   "eopa.object.get_merged",
+			query:  `eopa.object.get_merged({"config": {"log": {"level": "debug"}}}, ["config", "log"], {"level": "info", "format": "json"})`,
+			result: `{"level": "debug", "format": "json"}`,
+		},
+		{
+			note:   "eopa.object.has_path",
+			query:  `[eopa.object.has_path({"a": {"b": null}}, ["a", "b"]), eopa.object.has_path({"a": {"b": null}}, ["a", "c"])]`,
+			result: `[true, false]`,
+		},
+		{
+			note:   "eopa.numbers.parse_base",
+			query:  `eopa.numbers.parse_base("zz", 36)`,
+			result: `1295`,
+		},
+		{
+			note:   "eopa.numbers.to_base",
+			query:  `eopa.numbers.to_base(1295, 36)`,
+			result: `"zz"`,
+		},
+		{
+			note:   "eopa.set.to_sorted_array",
+			query:  `eopa.set.to_sorted_array({"b", 1, "a", null})`,
+			result: `[null, 1, "a", "b"]`,
+		},
+		{
+			note:   "eopa.array.to_set",
+			query:  `eopa.array.to_set(["b", "a", "b"])`,
+			result: `{"a", "b"}`,
+		},
+		{
+			note:   "eopa.array.indexof",
+			query:  `eopa.array.indexof(["a", {"b": 1}, {"b": 1}], {"b": 1})`,
+			result: `1`,
+		},
+		{
+			note:   "eopa.object.diff",
+			query:  `eopa.object.diff({"replicas": 2, "image": "a:1"}, {"replicas": 3, "image": "a:1"})`,
+			result: `[{"op": "replace", "path": "/replicas", "value": 3}]`,
+		},
+		{
+			note:   "eopa.walk_until",
+			query:  `eopa.walk_until({"spec": {"containers": [{"name": "a"}, {"name": "b", "privileged": true}]}}, {"privileged": true})`,
+			result: `[["spec", "containers", 1], {"name": "b", "privileged": true}]`,
+		},
+		{
+			note:   "eopa.size_bytes",
+			query:  `eopa.size_bytes({"kind": "Pod", "spec": {"containers": []}})`,
+			result: `39`,
+		},
+		{
+			note:   "eopa.is_valid_email",
+			query:  `[eopa.is_valid_email("jane.doe@example.com"), eopa.is_valid_email("Jane <jane.doe@example.com>")]`,
+			result: `[true, false]`,
+		},
+		{
+			note:   "eopa.is_valid_hostname",
+			query:  `[eopa.is_valid_hostname("bücher.example"), eopa.is_valid_hostname("a_b.example")]`,
+			result: `[true, false]`,
+		},
+		{
+			note:   "eopa.parse_url",
+			query:  `eopa.parse_url("https://example.com:8443/a?x=1").port`,
+			result: `"8443"`,
+		},
+		{
+			note:   "eopa.hash",
+			query:  `eopa.hash({"b": [1, {2}], "a": null}) == eopa.hash({"a": null, "b": [1.0, {2}]})`,
+			result: `true`,
+		},
+		{
+			note:   "eopa.sample",
+			query:  `eopa.sample([1, 2, 3, 4], 2, "seed") == eopa.sample([1, 2, 3, 4], 2, "seed")`,
+			result: `true`,
+		},
+		{
+			note:   "eopa.object.merge",
+			query:  `eopa.object.merge({"a": [1]}, {"a": [2]}, {"arrays": "concat"})`,
+			result: `{"a": [1, 2]}`,
+		},
+		{
+			note:   "eopa.count_at_least",
+			query:  `eopa.count_at_least({x | x := numbers.range(1, 100)[_]}, 10)`,
+			result: `true`,
+		},
+		{
+			note:   "eopa.count_at_most",
+			query:  `eopa.count_at_most({x | x := numbers.range(1, 100)[_]}, 10)`,
+			result: `false`,
+		},
+		{
+			note:   "eopa.jwt.decode_segment",
+			query:  `eopa.jwt.decode_segment(split("eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiJhbGljZSJ9.c2ln", ".")[1])`,
+			result: `{"sub": "alice"}`,
+		},
+		{
+			note:   "eopa.find",
+			query:  `eopa.find({"items": [{"id": 1}, {"id": 2}]}, "$.items[*].id")`,
+			result: `[1, 2]`,
+		},
+		{
+			note:   "eopa.matches",
+			query:  `eopa.matches({"kind": "Pod", "metadata": {"namespace": "x"}}, {"kind": "Pod", "metadata": {"namespace": "_"}})`,
+			result: `true`,
+		},
+		{
+			note:   "eopa.pointer.get",
+			query:  `eopa.pointer.get({"a": {"b": [1, 2]}}, "/a/b/1")`,
+			result: `2`,
+		},
+		{
+			note:   "eopa.pointer.set",
+			query:  `eopa.pointer.set({"a": {"b": [1, 2]}}, "/a/b/-", 3)`,
+			result: `{"a": {"b": [1, 2, 3]}}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			rs, err := rego.New(rego.Query(tc.query)).Eval(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rs) != 1 {
+				t.Fatalf("expected one result, got %v", rs)
+			}
+
+			result, err := ast.InterfaceToValue(rs[0].Expressions[0].Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := ast.MustParseTerm(tc.result).Value; exp.Compare(result) != 0 {
+				t.Fatalf("expected %v, got %v", exp, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package iropt
+
+import (
+	"errors"
+
+	"github.com/open-policy-agent/opa/v1/ir"
+)
+
+// ConditionalPass returns a copy of the pass, run only over the policies
+// the predicate holds for. Predicates are expected to be cheap next to
+// the pass, like checking the shape of the policy. Conditions compose:
+// a conditional pass made conditional runs only if both predicates hold.
+func ConditionalPass(pass *IROptPass, pred func(*ir.Policy) bool) *IROptPass {
+	p := *pass
+	if cond := pass.cond; cond != nil {
+		p.cond = func(policy *ir.Policy) bool {
+			return cond(policy) && pred(policy)
+		}
+	} else {
+		p.cond = pred
+	}
+	return &p
+}
+
+// ContainsScanStmt reports whether the policy iterates with any
+// ScanStmt, the loops the loop passes optimize.
+func ContainsScanStmt(policy *ir.Policy) bool {
+	if policy.Plans != nil && errors.Is(ir.Walk(&scanFinder{}, policy.Plans), errScanFound) {
+		return true
+	}
+	return policy.Funcs != nil && errors.Is(ir.Walk(&scanFinder{}, policy.Funcs), errScanFound)
+}
+
+var errScanFound = errors.New("scan found")
+
+// scanFinder aborts the walk at the first ScanStmt.
+type scanFinder struct{}
+
+func (*scanFinder) Before(any) {}
+
+func (*scanFinder) After(any) {}
+
+func (f *scanFinder) Visit(x any) (ir.Visitor, error) {
+	if _, ok := x.(*ir.ScanStmt); ok {
+		return nil, errScanFound
+	}
+	return f, nil
+}
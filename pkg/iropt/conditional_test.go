@@ -0,0 +1,58 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package iropt_test
+
+import (
+	"testing"
+
+	"github.com/open-policy-agent/eopa/pkg/iropt"
+	"github.com/open-policy-agent/opa/v1/ir"
+)
+
+func TestConditionalPass(t *testing.T) {
+	flat := &ir.Policy{
+		Plans: &ir.Plans{Plans: []*ir.Plan{{Name: "p", Blocks: []*ir.Block{{Stmts: []ir.Stmt{&ir.ReturnLocalStmt{Source: 1}}}}}}},
+		Funcs: &ir.Funcs{},
+	}
+	loop := &ir.Policy{
+		Plans: &ir.Plans{},
+		Funcs: &ir.Funcs{Funcs: []*ir.Func{{Name: "f", Blocks: []*ir.Block{{Stmts: []ir.Stmt{
+			&ir.BlockStmt{Blocks: []*ir.Block{{Stmts: []ir.Stmt{&ir.ScanStmt{Source: 1, Key: 2, Value: 3, Block: &ir.Block{}}}}}},
+		}}}}}},
+	}
+
+	if iropt.ContainsScanStmt(flat) {
+		t.Error("expected no ScanStmt in the flat policy")
+	}
+	if !iropt.ContainsScanStmt(loop) {
+		t.Error("expected a ScanStmt in the nested block")
+	}
+
+	var runs int
+	pass := iropt.NewIROptPass("count", "count", func(policy *ir.Policy) *ir.Policy {
+		runs++
+		return policy
+	})
+	schedule := []*iropt.IROptPass{
+		iropt.ConditionalPass(pass, iropt.ContainsScanStmt),
+		iropt.ConditionalPass(iropt.ConditionalPass(pass, iropt.ContainsScanStmt), func(*ir.Policy) bool { return false }),
+		pass,
+	}
+
+	for _, tc := range []struct {
+		policy *ir.Policy
+		runs   int
+	}{
+		{policy: flat, runs: 1},
+		{policy: loop, runs: 2},
+	} {
+		runs = 0
+		if _, err := iropt.RunPasses(tc.policy, schedule); err != nil {
+			t.Fatal(err)
+		}
+		if runs != tc.runs {
+			t.Errorf("expected %d runs, got %d", tc.runs, runs)
+		}
+	}
+}
@@ -23,13 +23,14 @@ func NewIROptLevel0Schedule(cliEnableFlags, _ *OptimizationPassFlags) []*IROptPa
 			metricName: "eopa-iropt-pass-licm",
 			f:          LoopInvariantCodeMotionPass,
 		}
-		out = append(out, p)
+		out = append(out, ConditionalPass(p, ContainsScanStmt))
 	}
-	out = append(out, &IROptPass{
+	p := &IROptPass{
 		name:       "Empty Loop Replacement",
 		metricName: "eopa-iropt-pass-empty-loop-replace",
 		f:          EmptyLoopReplacementPass,
-	})
+	}
+	out = append(out, ConditionalPass(p, ContainsScanStmt))
 	return out
 }
 
@@ -46,11 +47,24 @@ type IROptPass struct {
 	name       string
 	metricName string
 	f          func(*ir.Policy) *ir.Policy
+	cond       func(*ir.Policy) bool // Runs the pass only if true, if set.
+}
+
+// NewIROptPass returns a pass running f over the policy, for custom
+// schedules.
+func NewIROptPass(name, metricName string, f func(*ir.Policy) *ir.Policy) *IROptPass {
+	return &IROptPass{name: name, metricName: metricName, f: f}
 }
 
+// RunPasses runs the passes of the schedule in order, skipping the
+// conditional passes whose predicates do not hold for the policy as
+// optimized by the passes before them.
 func RunPasses(policy *ir.Policy, schedule []*IROptPass) (*ir.Policy, error) {
 	out := policy
 	for _, pass := range schedule {
+		if pass.cond != nil && !pass.cond(out) {
+			continue
+		}
 		out = pass.f(out)
 	}
 	return out, nil
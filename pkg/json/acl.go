@@ -0,0 +1,142 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var errReadOnlyResource = errors.New("json: resource is read-only")
+
+// ACLMetaKey is the meta key holding the access-control list of a
+// resource, as written by SetACL.
+const ACLMetaKey = "acl"
+
+// metaWriter is implemented by both the Collections and the
+// WritableCollections.
+type metaWriter interface {
+	WriteMeta(name string, key string, value string) bool
+}
+
+// SetACL restricts reading the resource, and the resources under it, to
+// the principals, storing them in the resource meta. It returns false if
+// the resource does not exist, and an error if the list cannot be
+// encoded. An empty list denies reading to everyone. Resources without
+// access-control lists are readable by all principals, unless a directory
+// above them restricts reading.
+//
+// Resource.SetACL does the same for a resource of WritableCollections.
+func SetACL(c metaWriter, name string, owners []string) (bool, error) {
+	if owners == nil {
+		owners = []string{}
+	}
+
+	bs, err := json.Marshal(owners)
+	if err != nil {
+		return false, fmt.Errorf("acl of %s: %w", name, err)
+	}
+
+	return c.WriteMeta(name, ACLMetaKey, string(bs)), nil
+}
+
+// SetACL sets the access-control list of the resource, through the
+// writable collections holding it, like the SetACL function.
+func (r *resourceImpl) SetACL(owners []string) error {
+	if r.collections == nil {
+		return errReadOnlyResource
+	}
+
+	_, err := SetACL(r.collections, r.name, owners)
+	return err
+}
+
+// ACL returns the principals the access-control list of the resource
+// allows reading it, and false if the resource has none.
+func ACL(r Resource) ([]string, bool) {
+	v, ok := r.Meta(ACLMetaKey)
+	if !ok {
+		return nil, false
+	}
+
+	return parseACL(v), true
+}
+
+// parseACL parses an access-control list, denying everyone if invalid.
+func parseACL(v string) []string {
+	var owners []string
+	if err := json.Unmarshal([]byte(v), &owners); err != nil {
+		return nil
+	}
+	return owners
+}
+
+// newPrincipalView returns a view of the resources under the root the
+// principal may read: those without an access-control list allowing
+// others only, on them or any directory above them.
+func newPrincipalView(root Object, principal string) *readOnlyView {
+	if !aclAllows(root, principal) {
+		return &readOnlyView{root: NewObject(nil)}
+	}
+
+	return &readOnlyView{root: filterACL(root, principal)}
+}
+
+// filterACL returns the resource object without the resources under it
+// the principal may not read. Unchanged directories are shared, not
+// copied.
+func filterACL(obj Object, principal string) Object {
+	if kindImpl(obj) != Directory {
+		return obj
+	}
+
+	names := obj.Names()
+	properties := make(map[string]File, len(names))
+	changed := false
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, "data:") {
+			properties[name] = obj.valueImpl(name)
+			continue
+		}
+
+		child, ok := obj.Value(name).(Object)
+		if !ok {
+			properties[name] = obj.valueImpl(name)
+			continue
+		}
+
+		if !aclAllows(child, principal) {
+			changed = true
+			continue
+		}
+
+		filtered := filterACL(child, principal)
+		changed = changed || filtered != child
+		properties[name] = filtered
+	}
+
+	if !changed {
+		return obj
+	}
+	return NewObject(properties)
+}
+
+// aclAllows checks if the access-control list of the resource object, if
+// any, allows the principal to read it.
+func aclAllows(obj Object, principal string) bool {
+	v, ok := obj.Value("meta:" + ACLMetaKey).(*String)
+	if !ok {
+		return true
+	}
+
+	return slices.Contains(parseACL(v.Value()), principal)
+}
+
+func (s snapshot) WithPrincipal(principal string) Collections {
+	return newPrincipalView(s.ObjectBinary, principal)
+}
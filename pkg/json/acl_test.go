@@ -0,0 +1,83 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithPrincipal(t *testing.T) {
+	w := NewCollections()
+	w.WriteJSON("public/motd", MustNew("hello"))
+	w.WriteJSON("teams/a/config", MustNew(map[string]any{"x": 1}))
+	w.WriteJSON("teams/a/secret", MustNew("a"))
+	w.WriteJSON("teams/b/config", MustNew(map[string]any{"x": 2}))
+	w.WriteJSON("admin/keys", MustNew([]any{"k"}))
+
+	for name, owners := range map[string][]string{
+		"teams/a": {"alice", "bob"},
+		"teams/b": {"carol"},
+		"admin":   nil,
+	} {
+		if ok, err := SetACL(w, name, owners); err != nil || !ok {
+			t.Fatalf("expected ACL set on %q, got %v", name, err)
+		}
+	}
+	if err := w.Resource("teams/a").Resource("secret").SetACL([]string{"alice"}); err != nil {
+		t.Fatalf("expected ACL set on the resource, got %v", err)
+	}
+	if ok, err := SetACL(w, "missing", []string{"alice"}); err != nil || ok {
+		t.Fatalf("expected no ACL set on a missing resource, got %v", err)
+	}
+
+	c := w.Prepare(time.Now())
+
+	if owners, ok := ACL(c.Resource("teams/a")); !ok || !reflect.DeepEqual(owners, []string{"alice", "bob"}) {
+		t.Fatalf("unexpected ACL %v", owners)
+	}
+	if _, ok := ACL(c.Resource("public")); ok {
+		t.Fatal("expected no ACL")
+	}
+	if err := c.Resource("public").SetACL([]string{"alice"}); err == nil {
+		t.Fatal("expected the prepared resource read-only")
+	}
+
+	for principal, expected := range map[string][]string{
+		"alice": {"public/motd", "teams/a/config", "teams/a/secret"},
+		"bob":   {"public/motd", "teams/a/config"},
+		"carol": {"public/motd", "teams/b/config"},
+		"eve":   {"public/motd"},
+	} {
+		view := c.WithPrincipal(principal)
+		if collections := view.Collections(); !reflect.DeepEqual(collections, expected) {
+			t.Errorf("expected %v readable by %s, got %v", expected, principal, collections)
+		}
+		if r := view.Resource("teams/a/secret"); (r != nil) != (principal == "alice") {
+			t.Errorf("unexpected secret access by %s: %v", principal, r)
+		}
+		if r := view.Resource("admin/keys"); r != nil {
+			t.Errorf("expected admin keys hidden from %s", principal)
+		}
+		view.Walk(func(r Resource) bool {
+			if r.Name() == "admin" || r.Name() == "teams/b" && principal != "carol" {
+				t.Errorf("unexpected %q walked by %s", r.Name(), principal)
+			}
+			return true
+		})
+	}
+
+	// Views compose: the view of another principal filters both.
+	bob := c.WithPrincipal("bob")
+	if r := bob.Resource("teams/a/config"); r == nil || r.JSON().String() != `{"x":1}` {
+		t.Fatalf("unexpected resource %v", r)
+	}
+	if collections := bob.WithPrincipal("alice").Collections(); !reflect.DeepEqual(collections, []string{"public/motd", "teams/a/config"}) {
+		t.Fatalf("unexpected collections %v", collections)
+	}
+	if bob.WriteMeta("teams/a/config", ACLMetaKey, "[]") {
+		t.Fatal("expected the view read-only")
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"runtime"
+	"sync"
+	"weak"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+
+	"github.com/open-policy-agent/eopa/pkg/json/utils"
+)
+
+// ASTCache caches the AST conversions of binary arrays and objects,
+// which otherwise rebuild the ast.Value from scratch on every AST call.
+// A binary value is identified by its location within the binary data,
+// so the values read from the same location share their conversion.
+// The converted values are shared, and must not be modified. The cache
+// pins them until it is released; it is not thread safe.
+type ASTCache struct {
+	values map[astCacheKey]ast.Value
+}
+
+type astCacheKey struct {
+	content *utils.MultiReader
+	offset  int64 // Offset of the value offsets.
+	array   bool
+}
+
+func NewASTCache() *ASTCache {
+	return &ASTCache{values: make(map[astCacheKey]ast.Value)}
+}
+
+// AST returns the AST value of j, converting the binary values only
+// once.
+func (c *ASTCache) AST(j Json) ast.Value {
+	key, ok := astCacheKeyOf(j)
+	if !ok {
+		return j.AST()
+	}
+
+	if v, ok := c.values[key]; ok {
+		return v
+	}
+
+	v := j.AST()
+	c.values[key] = v
+	return v
+}
+
+func astCacheKeyOf(j Json) (astCacheKey, bool) {
+	switch j := j.(type) {
+	case ObjectBinary:
+		if reader, ok := j.content.(*snapshotObjectReader); ok {
+			return astCacheKey{reader.content, reader.voffsets, false}, true
+		}
+	case ArrayBinary:
+		if reader, ok := j.content.(*snapshotArrayReader); ok {
+			return astCacheKey{reader.content, reader.offsets, true}, true
+		}
+	}
+
+	return astCacheKey{}, false
+}
+
+// SharedASTCache caches the AST conversions of binary arrays and objects
+// like ASTCache, but is safe for concurrent use, to share the conversions
+// between evaluations. The conversions are kept per binary snapshot, that
+// is, per revision of the data they were read from, until the snapshot is
+// garbage collected: the values of a new revision are converted anew.
+type SharedASTCache struct {
+	mu        sync.Mutex
+	revisions map[weak.Pointer[utils.MultiReader]]map[astCacheOffset]ast.Value
+	pending   map[astCachePending]struct{} // Conversions in progress.
+}
+
+type astCacheOffset struct {
+	offset int64
+	array  bool
+}
+
+type astCachePending struct {
+	revision weak.Pointer[utils.MultiReader]
+	astCacheOffset
+}
+
+func NewSharedASTCache() *SharedASTCache {
+	return &SharedASTCache{
+		revisions: make(map[weak.Pointer[utils.MultiReader]]map[astCacheOffset]ast.Value),
+		pending:   make(map[astCachePending]struct{}),
+	}
+}
+
+// Get returns the AST value of j, if precomputed.
+func (c *SharedASTCache) Get(j Json) (ast.Value, bool) {
+	key, ok := astCacheKeyOf(j)
+	if !ok {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.revisions[weak.Make(key.content)][astCacheOffset{key.offset, key.array}]
+	return v, ok
+}
+
+// Precompute converts j to AST and caches the conversion, unless already
+// cached, or being converted by another call. Only the binary arrays and
+// objects are cached; Precompute does nothing for the other values.
+func (c *SharedASTCache) Precompute(j Json) {
+	key, ok := astCacheKeyOf(j)
+	if !ok {
+		return
+	}
+
+	revision := weak.Make(key.content)
+	offset := astCacheOffset{key.offset, key.array}
+	pending := astCachePending{revision, offset}
+
+	c.mu.Lock()
+	_, cached := c.revisions[revision][offset]
+	_, converting := c.pending[pending]
+	if !cached && !converting {
+		c.pending[pending] = struct{}{}
+	}
+	c.mu.Unlock()
+
+	if cached || converting {
+		return
+	}
+
+	v := j.AST() // Converted without holding the lock.
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pending, pending)
+
+	values, ok := c.revisions[revision]
+	if !ok {
+		values = make(map[astCacheOffset]ast.Value)
+		c.revisions[revision] = values
+		runtime.AddCleanup(key.content, c.evict, revision)
+	}
+
+	values[offset] = v
+}
+
+// Revisions returns the number of data revisions the cache holds
+// conversions for.
+func (c *SharedASTCache) Revisions() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.revisions)
+}
+
+func (c *SharedASTCache) evict(revision weak.Pointer[utils.MultiReader]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.revisions, revision)
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+func TestASTCache(t *testing.T) {
+	obj, err := NewObjectBinary(map[string]any{
+		"a": map[string]any{"b": []any{1, 2}},
+		"c": []any{"d"},
+		"e": map[string]any{"b": []any{1, 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewASTCache()
+
+	// Values read from the same location share their conversion.
+	for _, name := range []string{"a", "c"} {
+		first, second := cache.AST(obj.Value(name)), cache.AST(obj.Value(name))
+		if first != second {
+			t.Fatalf("%s: expected the conversion cached", name)
+		}
+		if first.Compare(obj.Value(name).AST()) != 0 {
+			t.Fatalf("%s: expected %v, got %v", name, obj.Value(name).AST(), first)
+		}
+	}
+
+	// Equal values at different locations don't.
+	if a, e := cache.AST(obj.Value("a")), cache.AST(obj.Value("e")); a == e || a.Compare(e) != 0 {
+		t.Fatal("expected equal, separately cached conversions")
+	}
+
+	// Other values are converted as is.
+	if v := cache.AST(NewString("x")); v.Compare(ast.String("x")) != 0 {
+		t.Fatalf("unexpected value %v", v)
+	}
+	if len(cache.values) != 3 {
+		t.Fatalf("expected 3 cached values, got %d", len(cache.values))
+	}
+}
+
+func TestSharedASTCache(t *testing.T) {
+	cache := NewSharedASTCache()
+
+	func() {
+		obj, err := NewObjectBinary(map[string]any{"a": map[string]any{"b": []any{1, 2}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := cache.Get(obj.Value("a")); ok {
+			t.Fatal("expected no conversion before precomputing")
+		}
+
+		cache.Precompute(obj.Value("a"))
+		first, ok := cache.Get(obj.Value("a"))
+		if !ok || first.Compare(obj.Value("a").AST()) != 0 {
+			t.Fatalf("expected %v, got %v", obj.Value("a").AST(), first)
+		}
+
+		cache.Precompute(obj.Value("a"))
+		if second, _ := cache.Get(obj.Value("a")); first != second {
+			t.Fatal("expected the conversion cached")
+		}
+
+		// Another revision of the same data is converted anew.
+		other, err := NewObjectBinary(map[string]any{"a": map[string]any{"b": []any{1, 2}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cache.Get(other.Value("a")); ok {
+			t.Fatal("expected no conversion for another revision")
+		}
+		cache.Precompute(other.Value("a"))
+
+		// Other values are not cached.
+		cache.Precompute(NewString("x"))
+		if _, ok := cache.Get(NewString("x")); ok {
+			t.Fatal("expected strings not cached")
+		}
+
+		if n := cache.Revisions(); n != 2 {
+			t.Fatalf("expected 2 revisions, got %d", n)
+		}
+	}()
+
+	// The conversions are evicted with their revisions.
+	for deadline := time.Now().Add(5 * time.Second); cache.Revisions() > 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected revisions evicted, got %d", cache.Revisions())
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -5,7 +5,9 @@ package json
 
 import (
 	"bytes"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/open-policy-agent/eopa/pkg/json/utils"
 )
@@ -48,3 +50,29 @@ func buildBinary(data any) (File, error) {
 	v := buffer.Bytes()
 	return newFile(newSnapshotReader(utils.NewMultiReaderFromBytesReader(utils.NewBytesReader(v))), 0), nil
 }
+
+func TestBlobReader(t *testing.T) {
+	w := NewCollections()
+	w.WriteBlob("a", NewBlob([]byte("foo")))
+	w.WriteBlob("b", NewBlob([]byte{}))
+
+	// Writable collections and their prepared (binary) snapshots stream the same bytes.
+
+	for _, c := range []interface{ Resource(name string) Resource }{w, w.Prepare(time.Now())} {
+		for name, expected := range map[string][]byte{"a": []byte("foo"), "b": {}} {
+			r, n, err := c.Resource(name).BlobReader()
+			if err != nil {
+				t.Fatalf("Reader error: %v", err)
+			}
+
+			v, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("Read error: %v", err)
+			}
+
+			if n != int64(len(expected)) || !bytes.Equal(v, expected) {
+				t.Errorf("Incorrect value %q (%d bytes) for %s", v, n, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	gojson "encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Names of the files of the bundles rendered by ToBundle.
+const (
+	bundleDataFile     = "data.json"
+	bundleManifestFile = ".manifest"
+)
+
+// bundleManifest is the subset of the OPA bundle manifest ToBundle writes.
+type bundleManifest struct {
+	Revision string    `json:"revision"`
+	Roots    *[]string `json:"roots,omitempty"`
+}
+
+func (s snapshot) ToBundle(roots []string) ([]byte, error) {
+	return toBundle(s.find(""), roots)
+}
+
+func toBundle(root Resource, roots []string) ([]byte, error) {
+	data, err := bundleData(root, roots)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := bundleManifest{}
+	if roots != nil {
+		manifest.Roots = &roots
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	var d bytes.Buffer
+	if _, err := data.WriteTo(&d); err != nil {
+		return nil, err
+	}
+	if err := writeBundleFile(tw, bundleDataFile, d.Bytes()); err != nil {
+		return nil, err
+	}
+
+	m, err := gojson.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBundleFile(tw, bundleManifestFile, m); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// bundleData renders the resource as bundle data: directories as objects
+// of their resources, by their names, and JSON resources as their
+// contents. Binary resources, and resources outside the roots, have no
+// place in the data.
+func bundleData(r Resource, roots []string) (File, error) {
+	switch r.Kind() {
+	case Directory:
+		if !bundleRootsAllow(roots, r.Name(), true) {
+			return nil, fmt.Errorf("json: resource %q outside the bundle roots %v", r.Name(), roots)
+		}
+
+		resources := r.Resources()
+		properties := make(map[string]File, len(resources))
+		for _, child := range resources {
+			v, err := bundleData(child, roots)
+			if err != nil {
+				return nil, err
+			}
+			properties[path.Base(child.Name())] = v
+		}
+		return NewObject(properties), nil
+
+	case JSON:
+		if !bundleRootsAllow(roots, r.Name(), false) {
+			return nil, fmt.Errorf("json: resource %q outside the bundle roots %v", r.Name(), roots)
+		}
+		return r.JSON(), nil
+
+	default:
+		return nil, fmt.Errorf("json: binary resource %q not representable in bundle data", r.Name())
+	}
+}
+
+// bundleRootsAllow returns true if the slash separated name is under a
+// root, or for directories, holding a root. Without roots, as in OPA,
+// the bundle owns the whole data.
+func bundleRootsAllow(roots []string, name string, dir bool) bool {
+	if roots == nil {
+		return true
+	}
+
+	for _, root := range roots {
+		if root == "" || name == root || strings.HasPrefix(name, root+"/") {
+			return true
+		}
+		if dir && (name == "" || strings.HasPrefix(root, name+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeBundleFile(tw *tar.Writer, name string, bs []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     "/" + name,
+		Mode:     0o600,
+		Typeflag: tar.TypeReg,
+		Size:     int64(len(bs)),
+	}); err != nil {
+		return err
+	}
+
+	_, err := tw.Write(bs)
+	return err
+}
@@ -0,0 +1,66 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/bundle"
+)
+
+func TestToBundle(t *testing.T) {
+	w := NewCollections()
+	w.WriteJSON("a/b", MustNew(map[string]any{"x": []any{1, "y"}}))
+	w.WriteJSON("a/c", NewString("z"))
+	w.WriteJSON("d", NewBool(true))
+	w.WriteDirectory("e")
+	c := w.Prepare(time.Now())
+
+	bs, err := c.ToBundle([]string{"a", "d", "e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := bundle.NewCustomReader(bundle.NewTarballLoaderWithBaseURL(bytes.NewReader(bs), "/")).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"a": map[string]any{"b": map[string]any{"x": []any{float64(1), "y"}}, "c": "z"},
+		"d": true,
+		"e": map[string]any{},
+	}
+	if !reflect.DeepEqual(MustNew(b.Data).JSON(), MustNew(expected).JSON()) {
+		t.Fatalf("unexpected data %v", b.Data)
+	}
+	if !reflect.DeepEqual(*b.Manifest.Roots, []string{"a", "d", "e"}) {
+		t.Fatalf("unexpected roots %v", *b.Manifest.Roots)
+	}
+
+	// Without roots, the bundle owns the whole data.
+	bs, err = c.ToBundle(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err = bundle.NewCustomReader(bundle.NewTarballLoaderWithBaseURL(bytes.NewReader(bs), "/")).Read(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(*b.Manifest.Roots, []string{""}) {
+		t.Fatalf("unexpected roots %v", *b.Manifest.Roots)
+	}
+
+	for _, roots := range [][]string{{"a"}, {"a/b", "d", "e"}} {
+		if _, err := c.ToBundle(roots); err == nil {
+			t.Fatalf("expected data outside roots %v to fail", roots)
+		}
+	}
+
+	w.WriteBlob("a/blob", NewBlob([]byte("foo")))
+	if _, err := w.Prepare(time.Now()).ToBundle(nil); err == nil {
+		t.Fatal("expected binary resource to fail")
+	}
+}
@@ -25,9 +25,10 @@ func IsJSON(bs []byte) bool {
 // Decoder mimics the golang JSON decoder: reading a JSON object out of a byte stream. For simplicity, the Decode() implementation returns the constructed object, instead of taking a pointer as a parameter as the
 // standard package.
 type Decoder struct {
-	strings map[string]*String // for string interning.
-	keys    map[any]*[]string
-	iter    *jsoniter.Iterator
+	strings          map[string]*String // for string interning.
+	keys             map[any]*[]string
+	iter             *jsoniter.Iterator
+	normalizeNumbers bool
 }
 
 func newDecoder(iter *jsoniter.Iterator) *Decoder {
@@ -46,6 +47,19 @@ func NewStringDecoder(s string) *Decoder {
 	return newDecoder(jsoniter.ParseString(config, s))
 }
 
+// NewBytesDecoder returns a Decoder reading the bytes in place, without
+// copying them.
+func NewBytesDecoder(bs []byte) *Decoder {
+	return newDecoder(jsoniter.ParseBytes(config, bs))
+}
+
+// NormalizeNumbers causes the Decoder to canonicalize the numbers, for the
+// equal numbers to serialize, hash and diff identically regardless of their
+// formatting in the source. See NormalizeNumber for the rules.
+func (d *Decoder) NormalizeNumbers() {
+	d.normalizeNumbers = true
+}
+
 func (d *Decoder) error() error {
 	if err := d.iter.Error; err != nil && !errors.Is(err, io.EOF) {
 		return err
@@ -75,6 +89,10 @@ func (d *Decoder) Decode() (Json, error) {
 			return nil, err
 		}
 
+		if d.normalizeNumbers {
+			v = NormalizeNumber(v)
+		}
+
 		return NewFloat(v), nil
 
 	case jsoniter.NilValue:
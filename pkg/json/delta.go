@@ -149,6 +149,10 @@ func (d *deltaReader) ReadBytes(offset int64) ([]byte, error) {
 	return readBytes(d.content, d.deltaOffset(offset)+1)
 }
 
+func (d *deltaReader) ReadBytesSection(offset int64) (*io.SectionReader, error) {
+	return readBytesSection(d.content, d.deltaOffset(offset)+1)
+}
+
 func (d *deltaReader) ReadString(offset int64) (string, error) {
 	return readString(d.content, d.deltaOffset(offset)+1)
 }
@@ -78,6 +78,10 @@ func (d *deltaPatch) ReadBytes(offset int64) ([]byte, error) {
 	return readBytes(d.content, d.offset(offset)+1)
 }
 
+func (d *deltaPatch) ReadBytesSection(offset int64) (*io.SectionReader, error) {
+	return readBytesSection(d.content, d.offset(offset)+1)
+}
+
 func (d *deltaPatch) ReadString(offset int64) (string, error) {
 	return readString(d.content, d.offset(offset)+1)
 }
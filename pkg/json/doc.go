@@ -56,8 +56,30 @@ type Collections interface {
 	// DeltaReader returns a binary level reader for the delta of the collection, if a delta collection.
 	DeltaReader() *utils.MultiReader
 
+	// DeltaBytes returns a copy of the binary level delta of the collection, and true if a delta collection. Unlike DeltaReader, it does not panic
+	// if the collection is not a delta collection, but returns false instead.
+	DeltaBytes() ([]byte, bool)
+
 	io.WriterTo
 
+	// ToBundle returns a gzipped OPA bundle tarball holding the JSON resources as its data, in data.json, and a manifest with the roots, or
+	// without if nil. Directories become the objects of their resources. Binary resources and resources outside the roots fail the export.
+	ToBundle(roots []string) ([]byte, error)
+
+	// MerkleHash returns the hashes of the resources, by their names, the root directory being "". The hash of a directory is derived from
+	// the names and hashes of its resources, bottom-up, and the hashes of JSON resources from their structural hashes. Meta values are not
+	// hashed. DiffMerkle compares the hashes of two collections.
+	MerkleHash() map[string][32]byte
+
+	// TenantView returns a read-only view holding the resources under "shared" of the collections, under "shared", and the resources under
+	// the tenant prefix of the private collections, under "tenant", neither copied. No other resources are reachable through the view, and
+	// writing to it fails.
+	TenantView(tenantPrefix string, private Collections) Collections
+
+	// WithPrincipal returns a read-only view holding the resources the principal may read: those without an access-control list, as written by
+	// SetACL, excluding the principal, on them or any directory above them. The other resources are not reachable through the view.
+	WithPrincipal(principal string) Collections
+
 	// Objects returns the storage objects below. 	If a snapshot based collection, the slice will hold only one entry, the snapshot object. If a
 	// delta based collection, the first entity will be the delta object and the second for the snapshot. Note the meta data may be nil, if it was not provided at the construction time.
 	Objects() []any
@@ -103,6 +125,10 @@ type WritableCollections interface {
 	// Writes a meta key-value pair for a resource, returning if successful. Note, the resource has to exist this to take effect.
 	WriteMeta(name string, key string, value string) bool
 
+	// RemoveByMeta removes the resources with the meta value for the key, returning their number. Directories are removed with the resources
+	// under them. The removals take effect in the collections returned by the next Prepare, all at once.
+	RemoveByMeta(key string, value string) int
+
 	// Prepare prepares the collection for log append. Any changes to the writable collection after this invocation are not reflected to the returned collections.
 	Prepare(timestamp time.Time) Collections
 }
@@ -127,6 +153,10 @@ type Resource interface {
 	// Blob returns a binary resource. Will panic if the resource is not of binary type.
 	Blob() Blob
 
+	// BlobReader returns a reader of the bytes of a binary resource, and their number. Unlike Blob, it does not read the bytes in memory, but streams
+	// them from the binary level representation. Will panic if the resource is not of binary type.
+	BlobReader() (io.Reader, int64, error)
+
 	// Collection returns a JSON collection resource. Will panic if the resource is not of JSON type.
 	JSON() Json
 
@@ -135,4 +165,8 @@ type Resource interface {
 
 	// Walk executes a depth-first search over the resource, stopping the recursion to a particular node if the callback returns false but not the entire walk.
 	Walk(callback func(Resource) bool)
+
+	// SetACL restricts reading the resource, and the resources under it, to the principals, storing them in the resource meta. Only the
+	// resources of WritableCollections, and the resources under them, can be written to: it fails for the others.
+	SetACL(owners []string) error
 }
@@ -20,6 +20,12 @@ const (
 	typeHashSet
 )
 
+// Hash returns the structural hash of the value. Values comparing equal hash
+// equally, regardless of their implementation.
+func Hash(j Json) uint64 {
+	return hash(j)
+}
+
 func hash(value any) uint64 {
 	hasher := xxhash.New()
 	hashImpl(value, hasher)
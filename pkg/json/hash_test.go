@@ -13,3 +13,30 @@ func TestBigFloatHashing(_ *testing.T) {
 	f := Float{value: gojson.Number("23456789012E666")}
 	hash(f)
 }
+
+func TestHash(t *testing.T) {
+	binary, err := NewObjectBinary(map[string]any{"a": []any{1, "b"}, "c": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object2 := NewObject2(2)
+	object2 = object2.Insert(NewString("c"), NewNull())
+	object2 = object2.Insert(NewString("a"), NewArray([]File{NewFloat(gojson.Number("1.0")), NewString("b")}, 2))
+
+	// Object2 does not implement Compare, but equals the others.
+	values := []Json{MustNew(map[string]any{"a": []any{1, "b"}, "c": nil}), binary, object2}
+	for _, v := range values {
+		if Hash(v) != Hash(values[0]) {
+			t.Fatalf("expected %v to hash like %v", v, values[0])
+		}
+	}
+
+	if binary.Compare(values[0]) != 0 {
+		t.Fatalf("expected %v equal to %v", binary, values[0])
+	}
+
+	if Hash(MustNew([]any{1, 2})) == Hash(MustNew([]any{2, 1})) {
+		t.Fatal("expected different hashes")
+	}
+}
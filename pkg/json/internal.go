@@ -42,6 +42,9 @@ type contentReader interface {
 	// ReadString reads a variable length byte array at offset.
 	ReadBytes(offset int64) ([]byte, error)
 
+	// ReadBytesSection returns a reader of the variable length byte array at offset, without reading the bytes.
+	ReadBytesSection(offset int64) (*io.SectionReader, error)
+
 	// ReadString reads a variable length UTF-8 encoded string at offset.
 	ReadString(offset int64) (string, error)
 
@@ -10,6 +10,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"math/big"
 	"reflect"
 	"sort"
 	"strconv"
@@ -19,6 +21,7 @@ import (
 	"github.com/open-policy-agent/eopa/pkg/json/utils"
 
 	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/topdown/builtins"
 )
 
 // Json is the interface every element within the document implements.
@@ -274,58 +277,47 @@ func (f Float) String() string {
 	return f.Value().String()
 }
 
+// Add returns the sum of the numbers. Like Sub and Multiply, it is exact
+// for integers, regardless of their magnitude, and rounds and formats the
+// result as topdown does if a fraction or an exponent is involved.
 func (f Float) Add(addition Float) Float {
-	ia, oka := f.value.Int64()
-	ib, okb := addition.value.Int64()
-
-	if oka == nil && okb == nil {
-		return NewFloat(gojson.Number(strconv.FormatInt(ia+ib, 10)))
-	}
-
-	fa, oka := f.value.Float64()
-	fb, okb := addition.value.Float64()
-
-	if oka != nil || okb != nil {
-		panic("json: corrupted number")
-	}
-
-	return NewFloat(gojson.Number(fmt.Sprintf("%g", fa+fb)))
+	return f.arithmetic(addition, func(a, b int64) int64 { return a + b }, (*big.Int).Add, (*big.Float).Add)
 }
 
 func (f Float) Sub(decrement Float) Float {
-	ia, oka := f.value.Int64()
-	ib, okb := decrement.value.Int64()
-
-	if oka == nil && okb == nil {
-		return NewFloat(gojson.Number(strconv.FormatInt(ia-ib, 10)))
-	}
-
-	fa, oka := f.value.Float64()
-	fb, okb := decrement.value.Float64()
-
-	if oka != nil || okb != nil {
-		panic("json: corrupted number")
-	}
-
-	return NewFloat(gojson.Number(fmt.Sprintf("%g", fa-fb)))
+	return f.arithmetic(decrement, func(a, b int64) int64 { return a - b }, (*big.Int).Sub, (*big.Float).Sub)
 }
 
 func (f Float) Multiply(multiplier Float) Float {
-	ia, oka := f.value.Int64()
-	ib, okb := multiplier.value.Int64()
+	return f.arithmetic(multiplier, func(a, b int64) int64 { return a * b }, (*big.Int).Mul, (*big.Float).Mul)
+}
 
-	if oka == nil && okb == nil {
-		return NewFloat(gojson.Number(strconv.FormatInt(ia*ib, 10)))
+// arithmetic applies the operation on the numbers: with int64s if both
+// numbers are integers small enough not to overflow, with big.Ints if
+// larger integers, and with big.Floats otherwise, formatting the result
+// with the OPA function topdown formats its big.Floats with.
+func (f Float) arithmetic(other Float, small func(a, b int64) int64, ints func(z, a, b *big.Int) *big.Int, floats func(z, a, b *big.Float) *big.Float) Float {
+	const limit = 1 << 31 // Products of the smaller integers fit int64.
+
+	ia, erra := f.value.Int64()
+	ib, errb := other.value.Int64()
+	if erra == nil && errb == nil && -limit < ia && ia < limit && -limit < ib && ib < limit {
+		return NewFloat(gojson.Number(strconv.FormatInt(small(ia, ib), 10)))
 	}
 
-	fa, oka := f.value.Float64()
-	fb, okb := multiplier.value.Float64()
+	if ba, ok := new(big.Int).SetString(string(f.value), 10); ok {
+		if bb, ok := new(big.Int).SetString(string(other.value), 10); ok {
+			return NewFloat(gojson.Number(ints(ba, ba, bb).String()))
+		}
+	}
 
-	if oka != nil || okb != nil {
+	fa, oka := new(big.Float).SetString(string(f.value))
+	fb, okb := new(big.Float).SetString(string(other.value))
+	if !oka || !okb {
 		panic("json: corrupted number")
 	}
 
-	return NewFloat(gojson.Number(fmt.Sprintf("%g", fa*fb)))
+	return NewFloat(gojson.Number(builtins.FloatToNumber(floats(new(big.Float), fa, fb))))
 }
 
 func (f Float) Divide(divisor Float) Float {
@@ -735,6 +727,14 @@ type Object interface {
 	Json
 
 	Names() []string
+
+	// IterEntries returns an iterator over the properties, in the
+	// lexical order of their names. Iterating from multiple goroutines
+	// at once is safe, as long as the object is not modified: the
+	// immutable ObjectBinary reads its properties at their offsets, with
+	// no reader position shared between the iterations.
+	IterEntries() iter.Seq2[string, Json]
+
 	Set(name string, value Json) (Object, bool)
 	setImpl(name string, value File) (Object, bool)
 	Value(name string) Json
@@ -824,6 +824,23 @@ func (o ObjectBinary) NamesIndex(i int) string {
 	return name
 }
 
+func (o ObjectBinary) IterEntries() iter.Seq2[string, Json] {
+	return func(yield func(string, Json) bool) {
+		for i := range o.Len() {
+			name, err := o.content.ObjectNamesIndex(i)
+			checkError(err)
+
+			offset, err := o.content.objectValueOffset(i)
+			checkError(err)
+
+			value, _ := newFile(o.content, offset).(Json)
+			if !yield(name, value) {
+				return
+			}
+		}
+	}
+}
+
 func (o ObjectBinary) Set(name string, value Json) (Object, bool) {
 	return o.setImpl(name, value)
 }
@@ -1044,6 +1061,10 @@ func (o *ObjectMap) Names() []string {
 	return o.keys()
 }
 
+func (o *ObjectMap) IterEntries() iter.Seq2[string, Json] {
+	return objectMapBase[*ObjectMap]{}.IterEntries(o)
+}
+
 func (o *ObjectMap) Set(name string, value Json) (Object, bool) {
 	return o.setImpl(name, value)
 }
@@ -114,6 +114,35 @@ func TestFloat(t *testing.T) {
 	}
 }
 
+func TestFloatArithmetic(t *testing.T) {
+	tests := []struct {
+		note     string
+		op       func(a, b Float) Float
+		a, b     string
+		expected string
+	}{
+		{note: "add", op: Float.Add, a: "1", b: "2", expected: "3"},
+		{note: "add int64 overflow", op: Float.Add, a: "9223372036854775807", b: "1", expected: "9223372036854775808"},
+		{note: "add big", op: Float.Add, a: "123456789012345678901234567890", b: "1", expected: "123456789012345678901234567891"},
+		{note: "add fraction", op: Float.Add, a: "1.5", b: "1", expected: "2.5"},
+		{note: "add integer valued fraction", op: Float.Add, a: "1.5", b: "0.5", expected: "2"},
+		{note: "sub int64 overflow", op: Float.Sub, a: "-9223372036854775808", b: "1", expected: "-9223372036854775809"},
+		{note: "sub big", op: Float.Sub, a: "100000000000000000000000000001", b: "100000000000000000000000000000", expected: "1"},
+		{note: "multiply", op: Float.Multiply, a: "-3", b: "4", expected: "-12"},
+		{note: "multiply int64 overflow", op: Float.Multiply, a: "3037000500", b: "3037000500", expected: "9223372037000250000"},
+		{note: "multiply big", op: Float.Multiply, a: "99999999999999999999", b: "99999999999999999999", expected: "9999999999999999999800000000000000000001"},
+		{note: "multiply fraction", op: Float.Multiply, a: "0.5", b: "3", expected: "1.5"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			if actual := tc.op(NewFloat(json.Number(tc.a)), NewFloat(json.Number(tc.b))); actual.Value() != json.Number(tc.expected) {
+				t.Errorf("expected %s, got %s", tc.expected, actual.Value())
+			}
+		})
+	}
+}
+
 func TestArray(t *testing.T) {
 	// Empty array
 
@@ -0,0 +1,132 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+// MergeSortedCollections merges the resources of the sources, each sorted by
+// the meta value of the key in their walk order, into a single collections.
+// The sources are read incrementally, a resource at a time, and the resources
+// are written in the order of their meta values, ties going to the earlier
+// source. A resource in several sources is thus replaced by its later
+// occurrence in the merge order. The merged resources keep their meta value of
+// the key; the other meta values are not merged.
+//
+// Only the files (JSON and binary resources) are merged, directories being
+// created as needed. It is an error if a file misses the meta value of the key,
+// if a source is not sorted, or if a file replaces a directory.
+func MergeSortedCollections(sources []Collections, key string) (Collections, error) {
+	h := make(mergeHeap, 0, len(sources))
+	for i, source := range sources {
+		it := &mergeIterator{source: i, stack: [][]Resource{{source.Resource("")}}}
+		if ok, err := it.next(key); err != nil {
+			return nil, err
+		} else if ok {
+			h = append(h, it)
+		}
+	}
+	heap.Init(&h)
+
+	merged := NewCollections()
+	for len(h) > 0 {
+		it := h[0]
+
+		r := it.resource
+		if d := merged.Resource(r.Name()); d != nil && d.Kind() == Directory {
+			return nil, fmt.Errorf("json: collections source %d: resource %s conflicts with a directory", it.source, r.Name())
+		}
+
+		switch r.Kind() {
+		case JSON:
+			merged.WriteJSON(r.Name(), r.JSON())
+		case Unstructured:
+			merged.WriteBlob(r.Name(), r.Blob())
+		}
+		merged.WriteMeta(r.Name(), key, it.value)
+
+		if ok, err := it.next(key); err != nil {
+			return nil, err
+		} else if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+
+	return merged.Prepare(time.Now()), nil
+}
+
+// mergeIterator iterates over the files of a source in their walk order,
+// holding the current file and its meta value.
+type mergeIterator struct {
+	source   int
+	stack    [][]Resource // Resources left to visit, per directory level.
+	resource Resource
+	value    string
+}
+
+// next advances to the next file, returning false once the files are
+// exhausted.
+func (it *mergeIterator) next(key string) (bool, error) {
+	for len(it.stack) > 0 {
+		top := len(it.stack) - 1
+		if len(it.stack[top]) == 0 {
+			it.stack = it.stack[:top]
+			continue
+		}
+
+		r := it.stack[top][0]
+		it.stack[top] = it.stack[top][1:]
+
+		switch r.Kind() {
+		case Directory:
+			it.stack = append(it.stack, r.Resources())
+			continue
+		case JSON, Unstructured:
+		default:
+			continue
+		}
+
+		value, ok := r.Meta(key)
+		if !ok {
+			return false, fmt.Errorf("json: collections source %d: resource %s has no meta value for %q", it.source, r.Name(), key)
+		}
+		if it.resource != nil && value < it.value {
+			return false, fmt.Errorf("json: collections source %d: resource %s is not sorted by %q", it.source, r.Name(), key)
+		}
+
+		it.resource, it.value = r, value
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// mergeHeap orders the iterators by their current meta value, and the
+// sources.
+type mergeHeap []*mergeIterator
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].value != h[j].value {
+		return h[i].value < h[j].value
+	}
+	return h[i].source < h[j].source
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x any) { *h = append(*h, x.(*mergeIterator)) }
+
+func (h *mergeHeap) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func newSortedCollections(t *testing.T, resources ...[2]string) Collections {
+	t.Helper()
+
+	w := NewCollections()
+	for _, r := range resources {
+		w.WriteJSON(r[0], MustNew(r[1]))
+		if !w.WriteMeta(r[0], "seq", r[1]) {
+			t.Fatalf("unable to write meta for %s", r[0])
+		}
+	}
+	return w.Prepare(time.Now())
+}
+
+func TestMergeSortedCollections(t *testing.T) {
+	a := newSortedCollections(t, [2]string{"a/x", "1"}, [2]string{"a/y", "4"}, [2]string{"c", "5"})
+	b := newSortedCollections(t, [2]string{"b/x", "2"}, [2]string{"b/y", "3"}, [2]string{"c", "6"})
+
+	w := NewCollections()
+	w.WriteBlob("d", NewBlob([]byte("blob")))
+	w.WriteMeta("d", "seq", "0")
+	blobs := w.Prepare(time.Now())
+
+	merged, err := MergeSortedCollections([]Collections{a, b, blobs, NewCollections().Prepare(time.Now())}, "seq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	merged.Walk(func(r Resource) bool {
+		if r.Kind() == JSON || r.Kind() == Unstructured {
+			names = append(names, r.Name())
+		}
+		return true
+	})
+	if exp := []string{"a/x", "a/y", "b/x", "b/y", "c", "d"}; !slices.Equal(names, exp) {
+		t.Fatalf("expected resources %v, got %v", exp, names)
+	}
+
+	// The later resource in the merge order replaces the earlier.
+	if c := merged.Resource("c"); c.JSON().Compare(MustNew("6")) != 0 {
+		t.Fatalf("unexpected value %v", c.JSON())
+	}
+	if v, ok := merged.Resource("b/y").Meta("seq"); !ok || v != "3" {
+		t.Fatalf("unexpected meta value %q", v)
+	}
+	if string(merged.Resource("d").Blob().Value()) != "blob" {
+		t.Fatal("unexpected blob")
+	}
+
+	// Unsorted sources and missing meta values fail the merge.
+	unsorted := newSortedCollections(t, [2]string{"u/a", "2"}, [2]string{"u/b", "1"})
+	if _, err := MergeSortedCollections([]Collections{a, unsorted}, "seq"); err == nil {
+		t.Fatal("expected unsorted source to fail")
+	}
+	if _, err := MergeSortedCollections([]Collections{a}, "other"); err == nil {
+		t.Fatal("expected missing meta value to fail")
+	}
+	conflict := newSortedCollections(t, [2]string{"a", "2"})
+	if _, err := MergeSortedCollections([]Collections{a, conflict}, "seq"); err == nil {
+		t.Fatal("expected directory conflict to fail")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"path"
+	"slices"
+	"strings"
+)
+
+func (s snapshot) MerkleHash() map[string][32]byte {
+	hashes := make(map[string][32]byte)
+	merkleHash(s.find(""), hashes)
+	return hashes
+}
+
+// merkleHash hashes the resource into the hashes, by its name, and
+// returns its hash. The hash of a directory is derived from the names
+// and hashes of its resources, the hash of a JSON resource from its
+// structural hash, and the hash of a binary resource from its bytes.
+// Meta values are not hashed.
+func merkleHash(r Resource, hashes map[string][32]byte) [32]byte {
+	h := sha256.New()
+
+	kind := r.Kind()
+	h.Write([]byte{byte(kind)})
+
+	switch kind {
+	case Directory:
+		resources := r.Resources()
+		slices.SortFunc(resources, func(a, b Resource) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+
+		var b [8]byte
+		for _, child := range resources {
+			name := path.Base(child.Name())
+			binary.BigEndian.PutUint64(b[:], uint64(len(name)))
+			h.Write(b[:])
+			h.Write([]byte(name))
+
+			ch := merkleHash(child, hashes)
+			h.Write(ch[:])
+		}
+
+	case JSON:
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], Hash(r.JSON()))
+		h.Write(b[:])
+
+	case Unstructured:
+		h.Write(r.Blob().Value())
+	}
+
+	var sum [32]byte
+	h.Sum(sum[:0])
+	hashes[r.Name()] = sum
+	return sum
+}
+
+// DiffMerkle returns the paths of the resources differing between the
+// resource hashes of two collections, as returned by MerkleHash, sorted.
+// The paths are minimal: a directory differing only in some of its
+// resources is not included, but those resources are, while a resource
+// added, removed, or replaced as a whole, such as a directory emptied or
+// turned into a JSON resource, is included without the resources under.
+// The directories with equal hashes are not descended into.
+func DiffMerkle(a, b map[string][32]byte) []string {
+	ca, cb := merkleChildren(a), merkleChildren(b)
+
+	var paths []string
+	var diff func(p string)
+	diff = func(p string) {
+		if a[p] == b[p] {
+			return
+		}
+
+		if len(ca[p]) == 0 || len(cb[p]) == 0 {
+			paths = append(paths, p)
+			return
+		}
+
+		for _, child := range ca[p] {
+			if _, ok := b[child]; ok {
+				diff(child)
+			} else {
+				paths = append(paths, child)
+			}
+		}
+		for _, child := range cb[p] {
+			if _, ok := a[child]; !ok {
+				paths = append(paths, child)
+			}
+		}
+	}
+
+	_, okA := a[""]
+	_, okB := b[""]
+	switch {
+	case okA && okB:
+		diff("")
+	case okA || okB:
+		paths = append(paths, "")
+	}
+
+	slices.Sort(paths)
+	return paths
+}
+
+// merkleChildren returns the names of the resources of each directory of
+// the hashes, by the directory name.
+func merkleChildren(hashes map[string][32]byte) map[string][]string {
+	children := make(map[string][]string)
+	for name := range hashes {
+		if name == "" {
+			continue
+		}
+
+		parent := ""
+		if i := strings.LastIndexByte(name, '/'); i >= 0 {
+			parent = name[:i]
+		}
+		children[parent] = append(children[parent], name)
+	}
+	return children
+}
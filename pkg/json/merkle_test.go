@@ -0,0 +1,102 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMerkleHash(t *testing.T) {
+	base := func() WritableCollections {
+		w := NewCollections()
+		w.WriteJSON("a/b", MustNew(map[string]any{"x": 1, "y": []any{"z"}}))
+		w.WriteJSON("a/c", NewString("c"))
+		w.WriteBlob("d/e", NewBlob([]byte("e")))
+		w.WriteJSON("d/f/g", NewBool(true))
+		w.WriteDirectory("h")
+		return w
+	}
+
+	c := base().Prepare(time.Now())
+	hashes := c.MerkleHash()
+
+	for _, name := range []string{"", "a", "a/b", "a/c", "d", "d/e", "d/f", "d/f/g", "h"} {
+		if _, ok := hashes[name]; !ok {
+			t.Errorf("no hash for %q", name)
+		}
+	}
+	if len(hashes) != 9 {
+		t.Errorf("expected 9 hashes, got %d", len(hashes))
+	}
+
+	// Equal contents hash equally, regardless of the meta values, such as
+	// the preparation timestamps, and of the JSON implementations.
+	w := base()
+	w.WriteJSON("a/b", MustNew(map[string]any{"y": []any{"z"}, "x": 1.0}))
+	w.WriteMeta("a/c", "source", "feedX")
+	if other := w.Prepare(time.Now().Add(time.Second)).MerkleHash(); !reflect.DeepEqual(hashes, other) {
+		t.Fatal("expected equal hashes")
+	}
+	if paths := DiffMerkle(hashes, c.Writable().Prepare(time.Now()).MerkleHash()); len(paths) != 0 {
+		t.Fatalf("expected no differences, got %v", paths)
+	}
+
+	tests := []struct {
+		note   string
+		modify func(w WritableCollections)
+		paths  []string
+	}{
+		{
+			note:   "json modified",
+			modify: func(w WritableCollections) { w.WriteJSON("d/f/g", NewBool(false)) },
+			paths:  []string{"d/f/g"},
+		},
+		{
+			note:   "blob modified",
+			modify: func(w WritableCollections) { w.WriteBlob("d/e", NewBlob([]byte("E"))) },
+			paths:  []string{"d/e"},
+		},
+		{
+			note: "resources added",
+			modify: func(w WritableCollections) {
+				w.WriteJSON("a/i", NewNull())
+				w.WriteJSON("h/j", NewNull())
+			},
+			paths: []string{"a/i", "h"},
+		},
+		{
+			note:   "resources removed",
+			modify: func(w WritableCollections) { w.RemoveByMeta("source", "removed") },
+			paths:  []string{"a/c", "d/f"},
+		},
+		{
+			note: "directory replaced",
+			modify: func(w WritableCollections) {
+				w.RemoveByMeta("source", "removed")
+				w.WriteJSON("d/f", NewString("f"))
+			},
+			paths: []string{"a/c", "d/f"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			w := base()
+			w.WriteMeta("a/c", "source", "removed")
+			w.WriteMeta("d/f", "source", "removed")
+			w.WriteMeta("d/e", "source", "kept")
+			tc.modify(w)
+
+			other := w.Prepare(time.Now()).MerkleHash()
+			if paths := DiffMerkle(hashes, other); !reflect.DeepEqual(paths, tc.paths) {
+				t.Errorf("expected %v, got %v", tc.paths, paths)
+			}
+			if paths := DiffMerkle(other, hashes); !reflect.DeepEqual(paths, tc.paths) {
+				t.Errorf("expected %v reversed, got %v", tc.paths, paths)
+			}
+		})
+	}
+}
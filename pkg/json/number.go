@@ -0,0 +1,109 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	gojson "encoding/json"
+	"strconv"
+	"strings"
+)
+
+// maxNormalizedIntegerDigits is the number of digits up to which integers
+// are written in full when normalized.
+const maxNormalizedIntegerDigits = 21
+
+// NormalizeNumber returns the canonical representation of a JSON number,
+// equal numbers having equal representations. The normalization is exact:
+// the number is not converted to a float64, and no precision is lost.
+//
+//   - Leading zeros, trailing zeros of the fraction and the sign of zero are
+//     removed: "-0.0" is "0", "01.50" is "1.5".
+//   - Integers of up to 21 digits are written without an exponent or a
+//     fraction: "1.0" is "1", "1e3" is "1000", "1.5e1" is "15".
+//   - Numbers with fractions are written without an exponent if their first
+//     significant digit is at most 6 places after the decimal point: "1e-3"
+//     is "0.001", "12.5e-1" is "1.25".
+//   - Other numbers are written in scientific notation, with one digit before
+//     the decimal point and a lowercase exponent: "1e22" is "1e22",
+//     "123e-10" is "1.23e-8".
+//
+// Numbers with exponents too large for an int32 are returned as is, as are
+// the literals that are not valid JSON numbers.
+func NormalizeNumber(n gojson.Number) gojson.Number {
+	s := string(n)
+
+	var neg bool
+	if strings.HasPrefix(s, "-") {
+		neg, s = true, s[1:]
+	}
+
+	mantissa, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return n
+		}
+		mantissa, exp = s[:i], int(e)
+	}
+
+	// The value is digits * 10^exp.
+	digits := mantissa
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		digits = mantissa[:i] + mantissa[i+1:]
+		exp -= len(mantissa) - i - 1
+	}
+
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return n
+	}
+
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return "0"
+	}
+
+	trimmed := strings.TrimRight(digits, "0")
+	exp += len(digits) - len(trimmed)
+	digits = trimmed
+
+	// The exponent of the first digit in scientific notation, checked in
+	// int64 not to overflow.
+	point := int64(exp) + int64(len(digits)) - 1
+	if point < -(1<<31) || point > 1<<31-1 {
+		return n
+	}
+
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+
+	switch {
+	case exp >= 0 && point < maxNormalizedIntegerDigits:
+		b.WriteString(digits)
+		b.WriteString(strings.Repeat("0", exp))
+
+	case exp < 0 && point >= -6:
+		if point >= 0 {
+			b.WriteString(digits[:point+1])
+			b.WriteByte('.')
+			b.WriteString(digits[point+1:])
+		} else {
+			b.WriteString("0.")
+			b.WriteString(strings.Repeat("0", int(-point-1)))
+			b.WriteString(digits)
+		}
+
+	default:
+		b.WriteString(digits[:1])
+		if len(digits) > 1 {
+			b.WriteByte('.')
+			b.WriteString(digits[1:])
+		}
+		b.WriteByte('e')
+		b.WriteString(strconv.FormatInt(point, 10))
+	}
+
+	return gojson.Number(b.String())
+}
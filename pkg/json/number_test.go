@@ -0,0 +1,64 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	gojson "encoding/json"
+	"testing"
+)
+
+func TestNormalizeNumber(t *testing.T) {
+	for _, tc := range []struct {
+		number   string
+		expected string
+	}{
+		{"0", "0"},
+		{"-0.0", "0"},
+		{"0e10", "0"},
+		{"1", "1"},
+		{"1.0", "1"},
+		{"-1.50", "-1.5"},
+		{"1e3", "1000"},
+		{"1E+3", "1000"},
+		{"1.5e1", "15"},
+		{"100", "100"},
+		{"10.0e-1", "1"},
+		{"12.5e-1", "1.25"},
+		{"1e-3", "0.001"},
+		{"1e-6", "0.000001"},
+		{"1e-7", "1e-7"},
+		{"123e-10", "1.23e-8"},
+		{"123456789012345678901", "123456789012345678901"},
+		{"1234567890123456789012", "1.234567890123456789012e21"},
+		{"1e22", "1e22"},
+		{"-1.5e400", "-1.5e400"},
+		{"0.1000000000000000000000000001", "0.1000000000000000000000000001"},
+		{"1e2147483648", "1e2147483648"},
+		{"1e2147483647", "1e2147483647"},
+		{"10e2147483647", "10e2147483647"},
+	} {
+		t.Run(tc.number, func(t *testing.T) {
+			if actual := NormalizeNumber(gojson.Number(tc.number)); string(actual) != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDecoderNormalizeNumbers(t *testing.T) {
+	d := NewStringDecoder(`{"a": [1.0, 1e3, -0.50]}`)
+	d.NormalizeNumbers()
+
+	j, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual, expected := j.String(), `{"a":[1,1000,-0.5]}`; actual != expected {
+		t.Fatalf("expected %s, got %s", expected, actual)
+	}
+	if Hash(j) != Hash(MustNew(map[string]any{"a": []any{1, 1000, -0.5}})) {
+		t.Fatal("expected equal hashes")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"iter"
 	"reflect"
 	"sort"
 	"strconv"
@@ -34,6 +35,17 @@ func (objectMapBase[T]) Value(o T, name string) Json {
 	return nil
 }
 
+func (objectMapBase[T]) IterEntries(o T) iter.Seq2[string, Json] {
+	return func(yield func(string, Json) bool) {
+		for i, name := range o.Names() {
+			value, _ := o.iterate(i).(Json)
+			if !yield(name, value) {
+				return
+			}
+		}
+	}
+}
+
 func (objectMapBase[T]) JSON(o T) any {
 	keys := o.Names()
 	object := make(map[string]any, len(keys))
@@ -403,6 +415,10 @@ func (o *ObjectMapCompact[T]) Names() []string {
 	return o.keys()
 }
 
+func (o *ObjectMapCompact[T]) IterEntries() iter.Seq2[string, Json] {
+	return objectMapBase[*ObjectMapCompact[T]]{}.IterEntries(o)
+}
+
 func (o *ObjectMapCompact[T]) Set(name string, value Json) (Object, bool) {
 	return o.setImpl(name, value)
 }
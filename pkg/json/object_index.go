@@ -0,0 +1,135 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// objectIndexMinLen is the minimum number of properties for a binary
+// object to be indexed automatically: for smaller objects, the binary
+// search of the names is as fast as a hash lookup.
+const objectIndexMinLen = 64
+
+// objectIndexThreshold is the number of name lookups after which a
+// binary object is indexed; zero disables the automatic indexing.
+var objectIndexThreshold atomic.Int64
+
+// objectIndexHinted is set once IndexObject has been called, for the
+// lookups to skip the index otherwise.
+var objectIndexHinted atomic.Bool
+
+// SetObjectIndexThreshold sets the number of name lookups on a binary
+// object after which a hash index of its names is built, serving the
+// subsequent lookups in constant time. This targets large objects used
+// as membership sets, such as allow-lists looked up by the input. The
+// index is kept as long as the binary data. Zero, the default, disables
+// the automatic indexing; IndexObject indexes objects regardless.
+func SetObjectIndexThreshold(n int) {
+	objectIndexThreshold.Store(int64(n))
+}
+
+// IndexObject builds the hash index of the names of a binary object
+// immediately, as a hint the object is about to be used for membership
+// lookups. It reports whether the object is indexable.
+func IndexObject(j Json) (bool, error) {
+	obj, ok := j.(ObjectBinary)
+	if !ok {
+		return false, nil
+	}
+
+	reader, ok := obj.content.(*snapshotObjectReader)
+	if !ok {
+		return false, nil
+	}
+
+	objectIndexHinted.Store(true)
+
+	_, err := reader.index().build(reader)
+	return err == nil, err
+}
+
+// objectIndex maps the names of a binary object to their positions. It
+// is shared by the readers of the object, being cached with the binary
+// data, and indexed by the offset of the name offsets. The thin objects
+// sharing the names of a full object share its index, too.
+type objectIndex struct {
+	lookups atomic.Int64
+	names   atomic.Pointer[map[string]int]
+	mu      sync.Mutex // Serializes the building of names.
+}
+
+// objectIndexKey is the key of an objectIndex in the reader cache.
+type objectIndexKey struct {
+	noffsets int64
+}
+
+// index returns the index of the object, creating it if necessary.
+func (s *snapshotObjectReader) index() *objectIndex {
+	cache := s.content.Cache()
+	key := objectIndexKey{s.noffsets}
+	if idx, ok := cache.Load(key); ok {
+		return idx.(*objectIndex)
+	}
+
+	idx, _ := cache.LoadOrStore(key, &objectIndex{})
+	return idx.(*objectIndex)
+}
+
+// indexedNameIndex looks up the position of the name using the index,
+// counting the lookups to build the index once above the threshold. The
+// last return value is false if the index isn't available and the
+// caller should search for the name instead.
+func (s *snapshotObjectReader) indexedNameIndex(name string) (int, bool, bool) {
+	threshold := objectIndexThreshold.Load()
+	if threshold <= 0 && !objectIndexHinted.Load() {
+		return 0, false, false
+	}
+
+	var idx *objectIndex
+	if threshold > 0 {
+		idx = s.index()
+	} else if v, ok := s.content.Cache().Load(objectIndexKey{s.noffsets}); ok {
+		idx = v.(*objectIndex) // Indexed with IndexObject.
+	} else {
+		return 0, false, false
+	}
+
+	names := idx.names.Load()
+	if names == nil {
+		if threshold <= 0 || idx.lookups.Add(1) < threshold {
+			return 0, false, false
+		}
+
+		var err error
+		if names, err = idx.build(s); err != nil {
+			return 0, false, false // Fall back to searching, which reports the error.
+		}
+	}
+
+	i, ok := (*names)[name]
+	return i, ok, true
+}
+
+func (idx *objectIndex) build(s *snapshotObjectReader) (*map[string]int, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if names := idx.names.Load(); names != nil {
+		return names, nil
+	}
+
+	names := make(map[string]int, s.n)
+	for i := 0; i < s.n; i++ {
+		name, err := s.ObjectNamesIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		names[name] = i
+	}
+
+	idx.names.Store(&names)
+	return &names, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"fmt"
+	"testing"
+)
+
+func newTestObjectBinary(tb testing.TB, n int) ObjectBinary {
+	tb.Helper()
+
+	value := make(map[string]any, n)
+	for i := range n {
+		value[fmt.Sprintf("id-%d", i)] = i
+	}
+
+	obj, err := NewObjectBinary(value)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return obj
+}
+
+func indexed(obj ObjectBinary) bool {
+	reader := obj.content.(*snapshotObjectReader)
+	idx, ok := reader.content.Cache().Load(objectIndexKey{reader.noffsets})
+	return ok && idx.(*objectIndex).names.Load() != nil
+}
+
+func TestObjectIndex(t *testing.T) {
+	defer SetObjectIndexThreshold(0)
+	SetObjectIndexThreshold(3)
+
+	obj := newTestObjectBinary(t, 1000)
+
+	for i := range 5 {
+		if indexed(obj) != (i >= 3) {
+			t.Fatalf("lookup %d: expected indexed %v", i, i >= 3)
+		}
+
+		if v := obj.Value(fmt.Sprintf("id-%d", i)); v == nil || v.Compare(NewFloatInt(int64(i))) != 0 {
+			t.Fatalf("lookup %d: unexpected value %v", i, v)
+		}
+	}
+
+	for _, name := range []string{"id-999", "id-0", "id-500"} {
+		if v := obj.Value(name); v == nil {
+			t.Fatalf("expected %s found", name)
+		}
+	}
+
+	for _, name := range []string{"", "id-1000", "id-"} {
+		if v := obj.Value(name); v != nil {
+			t.Fatalf("expected %q not found, got %v", name, v)
+		}
+	}
+
+	// Below the minimum length, objects are searched.
+	small := newTestObjectBinary(t, objectIndexMinLen-1)
+	for range 5 {
+		small.Value("id-1")
+	}
+	if indexed(small) {
+		t.Fatal("expected small object not indexed")
+	}
+}
+
+func TestIndexObject(t *testing.T) {
+	obj := newTestObjectBinary(t, 1000)
+
+	if ok, err := IndexObject(obj); err != nil || !ok {
+		t.Fatalf("expected object indexed, got %v, %v", ok, err)
+	}
+	if !indexed(obj) {
+		t.Fatal("expected object indexed")
+	}
+	if v := obj.Value("id-42"); v == nil || v.Compare(NewFloatInt(42)) != 0 {
+		t.Fatalf("unexpected value %v", v)
+	}
+
+	if ok, err := IndexObject(NewObject(nil)); err != nil || ok {
+		t.Fatalf("expected object not indexable, got %v, %v", ok, err)
+	}
+}
+
+func BenchmarkObjectIndex(b *testing.B) {
+	for _, threshold := range []int{0, 1} {
+		for _, n := range []int{1000, 1000000} {
+			b.Run(fmt.Sprintf("threshold=%d/n=%d", threshold, n), func(b *testing.B) {
+				defer SetObjectIndexThreshold(0)
+				SetObjectIndexThreshold(threshold)
+
+				obj := newTestObjectBinary(b, n)
+				name := fmt.Sprintf("id-%d", n/2)
+				obj.Value(name)
+
+				b.ResetTimer()
+
+				for range b.N {
+					if obj.Value(name) == nil {
+						b.Fatal("not found")
+					}
+				}
+			})
+		}
+	}
+}
@@ -6,6 +6,7 @@ package json
 import (
 	"bytes"
 	"io"
+	"iter"
 	"sort"
 
 	"github.com/open-policy-agent/opa/v1/ast"
@@ -184,6 +185,10 @@ func (o *ObjectMapCompactStrings[T]) Names() []string {
 	return o.keys()
 }
 
+func (o *ObjectMapCompactStrings[T]) IterEntries() iter.Seq2[string, Json] {
+	return objectMapBase[*ObjectMapCompactStrings[T]]{}.IterEntries(o)
+}
+
 func (o *ObjectMapCompactStrings[T]) Set(name string, value Json) (Object, bool) {
 	return o.setImpl(name, value)
 }
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/open-policy-agent/opa/v1/ast"
@@ -142,3 +143,52 @@ func TestObjectSizes(t *testing.T) {
 		})
 	}
 }
+
+func TestObjectIterEntries(t *testing.T) {
+	values := make(map[string]any, 100)
+	for i := range 100 {
+		values[fmt.Sprintf("k%03d", i)] = fmt.Sprintf("v%d", i)
+	}
+	values["k000"] = map[string]any{"nested": true}
+
+	binary, err := NewObjectBinary(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, o := range map[string]Object{
+		"binary":          binary,
+		"map":             MustNew(values).(Object),
+		"compact":         MustNew(map[string]any{"a": 1, "b": "c"}).(Object),
+		"compact strings": MustNew(map[string]any{"a": "b", "c": "d"}).(Object),
+		"empty":           NewObject(nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			names := o.Names()
+
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Go(func() {
+					var i int
+					for name, value := range o.IterEntries() {
+						if expected := names[i]; name != expected {
+							t.Errorf("expected name %q, got %q", expected, name)
+						}
+						if expected := o.Value(name); value.Compare(expected) != 0 {
+							t.Errorf("expected value %v, got %v", expected, value)
+						}
+						i++
+					}
+					if i != o.Len() {
+						t.Errorf("expected %d entries, got %d", o.Len(), i)
+					}
+				})
+			}
+			wg.Wait()
+
+			for range o.IterEntries() {
+				break // Stopping early must not panic.
+			}
+		})
+	}
+}
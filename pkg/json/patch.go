@@ -7,6 +7,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 )
 
 type Patch []Op
@@ -154,6 +156,8 @@ func (p *Patch) Unmarshal(decoder *Decoder) error {
 	})
 }
 
+var errNonStringKey = errors.New("json patch: non-string key")
+
 type JsonPatchTestFailed struct {
 	path string
 }
@@ -416,3 +420,102 @@ func apply(json File, ptr []string, value File, op PatchOp) (File, error) {
 
 	return nil, fmt.Errorf("json patch: unsupported type '%T'", json)
 }
+
+// Diff returns a patch turning a into b. Objects are compared member by
+// member, and arrays of equal length element by element; any other
+// difference replaces the value as a whole. Removals precede additions
+// within an object, and members are visited in sorted order, so equal
+// documents always produce the same patch.
+func Diff(a, b Json) Patch {
+	return patchDiff(Patch{}, nil, a, b)
+}
+
+func patchDiff(patch Patch, ptr []string, a, b Json) Patch {
+	if am, ok := objectMembers(a); ok {
+		if bm, ok := objectMembers(b); ok {
+			return patchDiffObjects(patch, ptr, am, bm)
+		}
+	}
+
+	if aa, ok := a.(Array); ok {
+		if ba, ok := b.(Array); ok && aa.Len() == ba.Len() {
+			for i := 0; i < aa.Len(); i++ {
+				patch = patchDiff(patch, childPointer(ptr, strconv.Itoa(i)), aa.Value(i), ba.Value(i))
+			}
+			return patch
+		}
+	}
+
+	if Equal(a, b) {
+		return patch
+	}
+
+	return append(patch, Op{Op: PatchOpReplace, Path: NewPointer(ptr), Value: b})
+}
+
+func patchDiffObjects(patch Patch, ptr []string, a, b map[string]Json) Patch {
+	removed := make([]string, 0, len(a))
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	slices.Sort(removed)
+
+	for _, k := range removed {
+		patch = append(patch, Op{Op: PatchOpRemove, Path: NewPointer(childPointer(ptr, k))})
+	}
+
+	keys := make([]string, 0, len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		path := childPointer(ptr, k)
+		if av, ok := a[k]; ok {
+			patch = patchDiff(patch, path, av, b[k])
+		} else {
+			patch = append(patch, Op{Op: PatchOpAdd, Path: NewPointer(path), Value: b[k]})
+		}
+	}
+
+	return patch
+}
+
+// childPointer returns the pointer segments of the child, never
+// sharing the backing array with its siblings.
+func childPointer(ptr []string, seg string) []string {
+	return append(ptr[:len(ptr):len(ptr)], seg)
+}
+
+// objectMembers returns the members of an object with string keys
+// only, as JSON patches can't address any other keys.
+func objectMembers(j Json) (map[string]Json, bool) {
+	switch o := j.(type) {
+	case Object:
+		names := o.Names()
+		m := make(map[string]Json, len(names))
+		for _, name := range names {
+			m[name] = o.Value(name)
+		}
+		return m, true
+
+	case Object2:
+		m := make(map[string]Json, o.Len())
+		if err := o.Iter(func(k, v Json) (bool, error) {
+			s, ok := k.(*String)
+			if !ok {
+				return true, errNonStringKey
+			}
+			m[s.Value()] = v
+			return false, nil
+		}); err != nil {
+			return nil, false
+		}
+		return m, true
+	}
+
+	return nil, false
+}
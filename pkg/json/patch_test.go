@@ -683,3 +683,96 @@ func TestPatchBinary(t *testing.T) {
 		})
 	}
 }
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		note     string
+		a, b     string
+		expected string
+	}{
+		{
+			note:     "equal",
+			a:        `{"a": [1, {"b": 2}]}`,
+			b:        `{"a": [1, {"b": 2}]}`,
+			expected: `[]`,
+		},
+		{
+			note:     "members",
+			a:        `{"a": 1, "b": 2, "c": {"d": 3, "e": 4}}`,
+			b:        `{"b": 2, "c": {"d": 5, "f": 6}, "g": 7}`,
+			expected: `[{"op": "remove", "path": "/a"}, {"op": "remove", "path": "/c/e"}, {"op": "replace", "path": "/c/d", "value": 5}, {"op": "add", "path": "/c/f", "value": 6}, {"op": "add", "path": "/g", "value": 7}]`,
+		},
+		{
+			note:     "arrays of equal length",
+			a:        `{"a": [1, {"b": 2}]}`,
+			b:        `{"a": [3, {"b": 4}]}`,
+			expected: `[{"op": "replace", "path": "/a/0", "value": 3}, {"op": "replace", "path": "/a/1/b", "value": 4}]`,
+		},
+		{
+			note:     "arrays of different length",
+			a:        `{"a": [1]}`,
+			b:        `{"a": [1, 2]}`,
+			expected: `[{"op": "replace", "path": "/a", "value": [1, 2]}]`,
+		},
+		{
+			note:     "type change",
+			a:        `{"a": {"b": 1}}`,
+			b:        `{"a": "b"}`,
+			expected: `[{"op": "replace", "path": "/a", "value": "b"}]`,
+		},
+		{
+			note:     "escaped keys",
+			a:        `{}`,
+			b:        `{"a/b": 1, "c~d": 2}`,
+			expected: `[{"op": "add", "path": "/a~1b", "value": 1}, {"op": "add", "path": "/c~0d", "value": 2}]`,
+		},
+		{
+			note:     "root",
+			a:        `1`,
+			b:        `2`,
+			expected: `[{"op": "replace", "path": "", "value": 2}]`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			a, b := MustNew(mustUnmarshal(t, tc.a)), MustNew(mustUnmarshal(t, tc.b))
+
+			patch := Diff(a, b)
+
+			actual, err := json.Marshal(patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var expected Patch
+			if err := json.Unmarshal([]byte(tc.expected), &expected); err != nil {
+				t.Fatal(err)
+			}
+
+			if exp, err := json.Marshal(expected); err != nil {
+				t.Fatal(err)
+			} else if string(exp) != string(actual) {
+				t.Fatalf("expected %s, got %s", exp, actual)
+			}
+
+			patched, err := patch.ApplyTo(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !Equal(patched, b) {
+				t.Fatalf("expected %v, got %v", b, patched)
+			}
+		})
+	}
+}
+
+func mustUnmarshal(t *testing.T, s string) any {
+	t.Helper()
+
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
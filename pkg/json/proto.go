@@ -0,0 +1,216 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"encoding/base64"
+	gojson "encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// NewFromProto constructs a JSON object out of a Protocol Buffers message, reflecting over its fields. The mapping follows the canonical proto3
+// JSON mapping, except for the 64-bit integers, which are numbers instead of strings:
+//
+//   - Fields are named by their JSON names, and only the populated fields are included.
+//   - Enums are the names of their values, or their numbers if the values are unknown. google.protobuf.NullValue is null.
+//   - Oneofs are the set field only, under its own name; an unset oneof is omitted.
+//   - Bytes are base64 encoded strings, and the float NaN and infinite values the strings "NaN", "Infinity" and "-Infinity".
+//   - Maps are objects keyed by the keys formatted as strings.
+//   - google.protobuf.Timestamp is an RFC 3339 string, google.protobuf.Duration a string of seconds suffixed by "s", like "1.5s".
+//   - google.protobuf.Struct, Value and ListValue are the JSON values they hold, and the wrapper types their wrapped values.
+func NewFromProto(msg proto.Message) (Json, error) {
+	if msg == nil {
+		return NewNull(), nil
+	}
+
+	m := msg.ProtoReflect()
+	if !m.IsValid() {
+		return NewNull(), nil
+	}
+
+	v, err := protoMessage(m)
+	if err != nil {
+		return nil, fmt.Errorf("json: unable to encode protobuf message %s: %w", m.Descriptor().FullName(), err)
+	}
+
+	return New(v)
+}
+
+func protoMessage(m protoreflect.Message) (any, error) {
+	d := m.Descriptor()
+
+	switch d.FullName() {
+	case "google.protobuf.Timestamp":
+		return protoTimestamp(m)
+	case "google.protobuf.Duration":
+		return protoDuration(m)
+	case "google.protobuf.Struct":
+		return protoMap(m.Get(d.Fields().ByName("fields")).Map(), d.Fields().ByName("fields"))
+	case "google.protobuf.ListValue":
+		return protoList(m.Get(d.Fields().ByName("values")).List(), d.Fields().ByName("values"))
+	case "google.protobuf.Value":
+		fd := m.WhichOneof(d.Oneofs().ByName("kind"))
+		if fd == nil {
+			return nil, fmt.Errorf("google.protobuf.Value has no kind")
+		}
+		return protoSingular(m.Get(fd), fd)
+	case "google.protobuf.BoolValue", "google.protobuf.BytesValue", "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int32Value", "google.protobuf.Int64Value", "google.protobuf.StringValue", "google.protobuf.UInt32Value",
+		"google.protobuf.UInt64Value":
+		fd := d.Fields().ByName("value")
+		return protoSingular(m.Get(fd), fd)
+	}
+
+	obj := make(map[string]any, d.Fields().Len())
+
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		var value any
+		switch {
+		case fd.IsList():
+			value, err = protoList(v.List(), fd)
+		case fd.IsMap():
+			value, err = protoMap(v.Map(), fd)
+		default:
+			value, err = protoSingular(v, fd)
+		}
+		if err != nil {
+			return false
+		}
+
+		obj[fd.JSONName()] = value
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func protoList(l protoreflect.List, fd protoreflect.FieldDescriptor) (any, error) {
+	arr := make([]any, l.Len())
+	for i := range arr {
+		v, err := protoSingular(l.Get(i), fd)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = v
+	}
+
+	return arr, nil
+}
+
+func protoMap(m protoreflect.Map, fd protoreflect.FieldDescriptor) (any, error) {
+	obj := make(map[string]any, m.Len())
+
+	var err error
+	m.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+		var value any
+		if value, err = protoSingular(v, fd.MapValue()); err != nil {
+			return false
+		}
+
+		obj[k.String()] = value
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func protoSingular(v protoreflect.Value, fd protoreflect.FieldDescriptor) (any, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool(), nil
+	case protoreflect.StringKind:
+		return v.String(), nil
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return gojson.Number(strconv.FormatInt(v.Int(), 10)), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return gojson.Number(strconv.FormatUint(v.Uint(), 10)), nil
+	case protoreflect.FloatKind:
+		return protoFloat(v.Float(), 32), nil
+	case protoreflect.DoubleKind:
+		return protoFloat(v.Float(), 64), nil
+	case protoreflect.EnumKind:
+		if fd.Enum().FullName() == "google.protobuf.NullValue" {
+			return nil, nil
+		}
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name()), nil
+		}
+		return gojson.Number(strconv.FormatInt(int64(v.Enum()), 10)), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protoMessage(v.Message())
+	}
+
+	return nil, fmt.Errorf("unsupported field kind %v", fd.Kind())
+}
+
+func protoFloat(f float64, bitSize int) any {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+
+	return gojson.Number(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+// Valid ranges of the well-known time types, as in the proto definitions.
+const (
+	protoMinTimestamp = -62135596800 // 0001-01-01T00:00:00Z
+	protoMaxTimestamp = 253402300799 // 9999-12-31T23:59:59Z
+	protoMaxDuration  = 315576000000 // 10000 years
+)
+
+func protoTimestamp(m protoreflect.Message) (any, error) {
+	fields := m.Descriptor().Fields()
+	secs, nanos := m.Get(fields.ByName("seconds")).Int(), m.Get(fields.ByName("nanos")).Int()
+	if secs < protoMinTimestamp || secs > protoMaxTimestamp || nanos < 0 || nanos > 999999999 {
+		return nil, fmt.Errorf("google.protobuf.Timestamp out of range: %ds %dns", secs, nanos)
+	}
+
+	return time.Unix(secs, nanos).UTC().Format(time.RFC3339Nano), nil
+}
+
+func protoDuration(m protoreflect.Message) (any, error) {
+	fields := m.Descriptor().Fields()
+	secs, nanos := m.Get(fields.ByName("seconds")).Int(), m.Get(fields.ByName("nanos")).Int()
+	if secs < -protoMaxDuration || secs > protoMaxDuration || nanos < -999999999 || nanos > 999999999 ||
+		(secs > 0 && nanos < 0) || (secs < 0 && nanos > 0) {
+		return nil, fmt.Errorf("google.protobuf.Duration out of range: %ds %dns", secs, nanos)
+	}
+
+	var b strings.Builder
+	if secs < 0 || nanos < 0 {
+		b.WriteByte('-')
+		secs, nanos = -secs, -nanos
+	}
+
+	b.WriteString(strconv.FormatInt(secs, 10))
+	if nanos != 0 {
+		b.WriteByte('.')
+		b.WriteString(strings.TrimRight(fmt.Sprintf("%09d", nanos), "0"))
+	}
+	b.WriteByte('s')
+
+	return b.String(), nil
+}
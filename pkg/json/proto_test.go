@@ -0,0 +1,195 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testProtoFile declares a message with the field types the mapping handles specially.
+const testProtoFile = `
+name: "test.proto"
+package: "test"
+syntax: "proto3"
+dependency: "google/protobuf/struct.proto"
+dependency: "google/protobuf/timestamp.proto"
+enum_type { name: "Op" value { name: "OP_UNSPECIFIED" number: 0 } value { name: "OP_ADD" number: 1 } }
+message_type {
+  name: "Request"
+  field { name: "user_name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "userName" }
+  field { name: "op" number: 2 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.Op" json_name: "op" }
+  field { name: "id" number: 3 label: LABEL_OPTIONAL type: TYPE_INT64 oneof_index: 0 json_name: "id" }
+  field { name: "name" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING oneof_index: 0 json_name: "name" }
+  field { name: "tags" number: 5 label: LABEL_REPEATED type: TYPE_STRING json_name: "tags" }
+  field { name: "labels" number: 6 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".test.Request.LabelsEntry" json_name: "labels" }
+  field { name: "time" number: 7 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" json_name: "time" }
+  field { name: "attributes" number: 8 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Struct" json_name: "attributes" }
+  nested_type {
+    name: "LabelsEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+    options { map_entry: true }
+  }
+  oneof_decl { name: "subject" }
+}
+`
+
+func newTestProtoMessage(t *testing.T, text string) proto.Message {
+	t.Helper()
+
+	var fdp descriptorpb.FileDescriptorProto
+	if err := prototext.Unmarshal([]byte(testProtoFile), &fdp); err != nil {
+		t.Fatal(err)
+	}
+
+	fd, err := protodesc.NewFile(&fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := dynamicpb.NewMessage(fd.Messages().ByName("Request"))
+	if err := (prototext.UnmarshalOptions{Resolver: dynamicTypes{fd}}).Unmarshal([]byte(text), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	return msg
+}
+
+// dynamicTypes resolves the types of the test file, and the well-known types.
+type dynamicTypes struct {
+	fd protoreflect.FileDescriptor
+}
+
+func (r dynamicTypes) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
+}
+
+func (r dynamicTypes) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
+
+func (r dynamicTypes) FindMessageByName(message protoreflect.FullName) (protoreflect.MessageType, error) {
+	if md := r.fd.Messages().ByName(message.Name()); md != nil && md.FullName() == message {
+		return dynamicpb.NewMessageType(md), nil
+	}
+	return protoregistry.GlobalTypes.FindMessageByName(message)
+}
+
+func (r dynamicTypes) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	return protoregistry.GlobalTypes.FindMessageByURL(url)
+}
+
+func TestNewFromProto(t *testing.T) {
+	attributes, err := structpb.NewStruct(map[string]any{"foo": []any{"bar", 1.5, true, nil}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		note     string
+		msg      proto.Message
+		expected string
+	}{
+		{
+			note: "message",
+			msg: newTestProtoMessage(t, `
+				user_name: "alice"
+				op: OP_ADD
+				id: 9223372036854775807
+				tags: ["a", "b"]
+				labels { key: 1 value: "one" }
+				time { seconds: 1735787045 nanos: 600000000 }
+			`),
+			expected: `{
+				"userName": "alice",
+				"op": "OP_ADD",
+				"id": 9223372036854775807,
+				"tags": ["a", "b"],
+				"labels": {"1": "one"},
+				"time": "2025-01-02T03:04:05.6Z"
+			}`,
+		},
+		{
+			note:     "oneof",
+			msg:      newTestProtoMessage(t, `name: "bob"`),
+			expected: `{"name": "bob"}`,
+		},
+		{
+			note:     "unknown enum value",
+			msg:      newTestProtoMessage(t, `op: 42`),
+			expected: `{"op": 42}`,
+		},
+		{
+			note:     "unpopulated fields",
+			msg:      newTestProtoMessage(t, ``),
+			expected: `{}`,
+		},
+		{
+			note:     "struct",
+			msg:      attributes,
+			expected: `{"foo": ["bar", 1.5, true, null]}`,
+		},
+		{
+			note:     "timestamp",
+			msg:      timestamppb.New(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)),
+			expected: `"2025-01-02T03:04:05Z"`,
+		},
+		{
+			note:     "duration",
+			msg:      durationpb.New(-1500 * time.Millisecond),
+			expected: `"-1.5s"`,
+		},
+		{
+			note:     "wrappers",
+			msg:      wrapperspb.UInt64(math.MaxUint64),
+			expected: `18446744073709551615`,
+		},
+		{
+			note:     "non-finite floats",
+			msg:      wrapperspb.Double(math.Inf(-1)),
+			expected: `"-Infinity"`,
+		},
+		{
+			note:     "bytes",
+			msg:      wrapperspb.Bytes([]byte("foo")),
+			expected: `"Zm9v"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			j, err := NewFromProto(tc.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := NewStringDecoder(tc.expected).Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if j.Compare(expected) != 0 {
+				t.Fatalf("expected %v, got %v", expected, j)
+			}
+		})
+	}
+
+	if _, err := NewFromProto(&timestamppb.Timestamp{Nanos: -1}); err == nil {
+		t.Fatal("expected invalid timestamp to fail")
+	}
+}
@@ -89,6 +89,206 @@ func Extract(json any, ptr string) (any, error) {
 	return result.Interface(), nil
 }
 
+// CloneAt returns a copy of the JSON document with the subtree at the
+// RFC 6901 pointer string deep copied, safe to modify. The rest of the
+// document is shared with the original: only the objects and arrays
+// along the path are copied, shallow, to hold the copied subtree.
+//
+// The function returns an error if no element found.
+func CloneAt(doc Json, ptr string) (Json, error) {
+	p, err := preparePointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloneAt(doc, p)
+}
+
+func cloneAt(doc Json, ptr []string) (Json, error) {
+	if len(ptr) == 0 {
+		return cloneMutable(doc).(Json), nil
+	}
+
+	switch doc := doc.(type) {
+	case Object:
+		v := doc.Value(ptr[0])
+		if v == nil {
+			return nil, errPathNotFound
+		}
+
+		c, err := cloneAt(v, ptr[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		o, _ := doc.Clone(false).(Object).Set(ptr[0], c)
+		return o, nil
+
+	case Array:
+		i, err := parseInt(ptr[0])
+		if err != nil || i < 0 || i >= doc.Len() {
+			return nil, errPathNotFound
+		}
+
+		c, err := cloneAt(doc.Value(i), ptr[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		return doc.Clone(false).(Array).SetIdx(i, c), nil
+	}
+
+	return nil, errPathNotFound
+}
+
+// Lookup returns the value of the JSON document at the RFC 6901 pointer
+// string, or false if there is none. Unlike Extract, the document may hold
+// objects with non-string keys, as built by the VM; their string keys are
+// addressable. Sets have no children. The function returns an error if
+// the pointer is invalid.
+func Lookup(doc Json, ptr string) (Json, bool, error) {
+	p, err := preparePointer(ptr)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, seg := range p {
+		switch d := doc.(type) {
+		case Object, Object2:
+			v, ok := getObject(d, NewString(seg))
+			if !ok {
+				return nil, false, nil
+			}
+			doc = v
+
+		case Array:
+			i, err := parseInt(seg)
+			if err != nil || i < 0 || i >= d.Len() {
+				return nil, false, nil
+			}
+			doc = d.Value(i)
+
+		default:
+			return nil, false, nil
+		}
+	}
+
+	return doc, true, nil
+}
+
+// SetAt returns a copy of the JSON document with the value at the RFC 6901
+// pointer string set, adding it if the object member does not exist, or
+// appending it if the array index is "-" or the array length. The
+// original document is not modified: only the objects and arrays along
+// the path are copied, shallow, and the rest is shared with the original.
+// Objects with non-string keys, as built by the VM, are supported.
+//
+// The function returns an error if the pointer is invalid or the parent of
+// the value is not found.
+func SetAt(doc Json, ptr string, value Json) (Json, error) {
+	p, err := preparePointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+
+	return setAt(doc, p, value)
+}
+
+func setAt(doc Json, ptr []string, value Json) (Json, error) {
+	if len(ptr) == 0 {
+		return value, nil
+	}
+
+	child := func(v Json, ok bool) (Json, error) {
+		if len(ptr) == 1 {
+			return value, nil
+		}
+		if !ok {
+			return nil, errPathNotFound
+		}
+		return setAt(v, ptr[1:], value)
+	}
+
+	switch d := doc.(type) {
+	case Object:
+		v := d.Value(ptr[0])
+		c, err := child(v, v != nil)
+		if err != nil {
+			return nil, err
+		}
+
+		o, _ := d.Clone(false).(Object).Set(ptr[0], c)
+		return o, nil
+
+	case Object2:
+		k := NewString(ptr[0])
+		c, err := child(d.Get(k))
+		if err != nil {
+			return nil, err
+		}
+
+		o := NewObject2(d.Len() + 1)
+		d.iter(func(hash uint64, k, v Json) {
+			o = o.insert(hash, k, v)
+		})
+		return o.Insert(k, c), nil
+
+	case Array:
+		i, err := parseInt(ptr[0])
+		if ptr[0] == "-" {
+			i, err = d.Len(), nil
+		}
+		if err != nil || i < 0 || i > d.Len() || i == d.Len() && len(ptr) > 1 {
+			return nil, errPathNotFound
+		}
+
+		if i == d.Len() {
+			return d.Clone(false).(Array).Append(value), nil
+		}
+
+		c, err := child(d.Value(i), true)
+		if err != nil {
+			return nil, err
+		}
+
+		return d.Clone(false).(Array).SetIdx(i, c), nil
+	}
+
+	return nil, errPathNotFound
+}
+
+// cloneMutable returns a deep copy of the file, with the binary objects and
+// arrays, which copy themselves on every modification, replaced with their
+// modifiable copies.
+func cloneMutable(j File) File {
+	var o Object
+	var a Array
+	switch j := j.(type) {
+	case ObjectBinary:
+		o = j.clone()
+	case Object:
+		o = j.Clone(false).(Object)
+	case ArrayBinary:
+		a = j.clone()
+	case Array:
+		a = j.Clone(false).(Array)
+	default:
+		return j.Clone(true)
+	}
+
+	if o != nil {
+		for _, name := range o.Names() {
+			o, _ = o.setImpl(name, cloneMutable(o.valueImpl(name)))
+		}
+		return o
+	}
+
+	for i := range a.Len() {
+		a = a.SetIdx(i, cloneMutable(a.valueImpl(i))).(Array)
+	}
+	return a
+}
+
 func extractImpl(json reflect.Value, ptr string) (reflect.Value, error) {
 	if ptr == "" {
 		return json, nil
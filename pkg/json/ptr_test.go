@@ -209,3 +209,120 @@ func testBuildJSON(s string) any {
 	}
 	return v
 }
+
+func TestCloneAt(t *testing.T) {
+	doc := MustNew(testBuildJSON(`{"a": {"b": [{"c": 1}, {"c": 2}]}, "d": {"e": 3}}`))
+	bs, err := Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary, err := NewFromBinary(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, doc := range map[string]Json{"map": doc, "binary": binary} {
+		t.Run(name, func(t *testing.T) {
+			original := doc.String()
+
+			clone, err := CloneAt(doc, "/a/b/1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if clone.Compare(doc) != 0 {
+				t.Fatalf("clone %v differs from %v", clone, doc)
+			}
+
+			subtree, err := clone.Extract("/a/b/1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o, changed := subtree.(Object).Set("c", NewFloatInt(4)); changed {
+				t.Fatalf("expected subtree %v modifiable in place, got %v", subtree, o)
+			}
+
+			if doc.String() != original {
+				t.Fatalf("original modified: %v", doc)
+			}
+			if expected := `{"a":{"b":[{"c":1},{"c":4}]},"d":{"e":3}}`; clone.String() != expected {
+				t.Fatalf("expected %v, got %v", expected, clone)
+			}
+
+			if name == "map" {
+				shared, _ := clone.Extract("/d")
+				if original, _ := doc.Extract("/d"); shared != original {
+					t.Fatal("expected unmodified subtree shared")
+				}
+			}
+
+			for _, ptr := range []string{"/x", "/a/b/2", "/a/b/x", "/d/e/f", "invalid"} {
+				if _, err := CloneAt(doc, ptr); err == nil {
+					t.Errorf("expected error for %q", ptr)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupSetAt(t *testing.T) {
+	doc := MustNew(testBuildJSON(`{"a": {"b": [{"c": 1}, {"c": 2}]}, "d": {"e": 3}}`))
+	bs, err := Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary, err := NewFromBinary(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	object2 := NewObject2(2).
+		Insert(NewString("a"), doc.(Object).Value("a")).
+		Insert(NewString("d"), doc.(Object).Value("d"))
+
+	for name, doc := range map[string]Json{"map": doc, "binary": binary, "object2": object2} {
+		t.Run(name, func(t *testing.T) {
+			original := doc.AST().String()
+
+			for ptr, expected := range map[string]string{"": original, "/a/b/1/c": `2`, "/d": `{"e": 3}`} {
+				if v, ok, err := Lookup(doc, ptr); err != nil || !ok || v.AST().String() != expected {
+					t.Errorf("expected %s at %q, got %v, %v, %v", expected, ptr, v, ok, err)
+				}
+			}
+			for _, ptr := range []string{"/x", "/a/b/2", "/a/b/x", "/a/b/-1", "/d/e/f"} {
+				if v, ok, err := Lookup(doc, ptr); err != nil || ok {
+					t.Errorf("expected nothing at %q, got %v, %v", ptr, v, err)
+				}
+			}
+			if _, _, err := Lookup(doc, "invalid"); err == nil {
+				t.Error("expected invalid pointer error")
+			}
+
+			for ptr, expected := range map[string]string{
+				"":         `4`,
+				"/a/b/1/c": `{"a": {"b": [{"c": 1}, {"c": 4}]}, "d": {"e": 3}}`,
+				"/a/b/0":   `{"a": {"b": [4, {"c": 2}]}, "d": {"e": 3}}`,
+				"/a/b/-":   `{"a": {"b": [{"c": 1}, {"c": 2}, 4]}, "d": {"e": 3}}`,
+				"/a/b/2":   `{"a": {"b": [{"c": 1}, {"c": 2}, 4]}, "d": {"e": 3}}`,
+				"/d/f":     `{"a": {"b": [{"c": 1}, {"c": 2}]}, "d": {"e": 3, "f": 4}}`,
+				"/g":       `{"a": {"b": [{"c": 1}, {"c": 2}]}, "d": {"e": 3}, "g": 4}`,
+			} {
+				set, err := SetAt(doc, ptr, NewFloatInt(4))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if actual := set.AST().String(); actual != expected {
+					t.Errorf("expected %s setting %q, got %s", expected, ptr, actual)
+				}
+				if actual := doc.AST().String(); actual != original {
+					t.Fatalf("original modified setting %q: %s", ptr, actual)
+				}
+			}
+
+			for _, ptr := range []string{"/x/y", "/a/b/3", "/a/b/-/c", "/a/b/x", "/d/e/f", "invalid"} {
+				if _, err := SetAt(doc, ptr, NewNull()); err == nil {
+					t.Errorf("expected error for %q", ptr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Query returns the values the JSONPath expression selects from the
+// document, in the document order, or none if it selects nothing. The
+// expressions are a subset of JSONPath (RFC 9535), starting with the root
+// identifier $ followed by the segments:
+//
+//	.name, ['name'], ["name"]  the value of the object member
+//	[n]                        the array element, counting from the end if negative
+//	.*, [*]                    the values of the object members or the array elements
+//	..name, ..[...], ..*       as above, applied to the value and all its descendants
+//
+// Names in the dot notation are letters, digits, underscores and
+// non-ASCII characters, not starting with a digit. The object members are
+// visited in the key order. Sets have no children. Filters, slices and
+// unions are not supported.
+func Query(j Json, path string) ([]Json, error) {
+	segments, err := parseQuery(path)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []Json{j}
+	for _, seg := range segments {
+		var next []Json
+		for _, node := range nodes {
+			if seg.descendants {
+				walkQuery(node, func(n Json) {
+					next = seg.apply(n, next)
+				})
+			} else {
+				next = seg.apply(node, next)
+			}
+		}
+		nodes = next
+	}
+
+	return nodes, nil
+}
+
+type querySegmentKind int
+
+const (
+	queryName querySegmentKind = iota
+	queryIndex
+	queryWildcard
+)
+
+type querySegment struct {
+	kind        querySegmentKind
+	name        string
+	index       int
+	descendants bool
+}
+
+// apply appends the children of the node the segment selects.
+func (s querySegment) apply(node Json, selected []Json) []Json {
+	switch s.kind {
+	case queryName:
+		if v, ok := getObject(node, NewString(s.name)); ok {
+			selected = append(selected, v)
+		}
+
+	case queryIndex:
+		if a, ok := node.(Array); ok {
+			i := s.index
+			if i < 0 {
+				i += a.Len()
+			}
+			if i >= 0 && i < a.Len() {
+				selected = append(selected, a.Value(i))
+			}
+		}
+
+	case queryWildcard:
+		selected = append(selected, queryChildren(node)...)
+	}
+
+	return selected
+}
+
+// walkQuery calls f for the node and its descendants, depth-first.
+func walkQuery(node Json, f func(Json)) {
+	f(node)
+	for _, child := range queryChildren(node) {
+		walkQuery(child, f)
+	}
+}
+
+// queryChildren returns the array elements, or the object member values
+// in the key order.
+func queryChildren(node Json) []Json {
+	switch n := node.(type) {
+	case Array:
+		children := make([]Json, n.Len())
+		for i := range children {
+			children[i] = n.Value(i)
+		}
+		return children
+
+	case Object:
+		names := n.Names()
+		children := make([]Json, len(names))
+		for i, name := range names {
+			children[i] = n.Value(name)
+		}
+		return children
+
+	case Object2:
+		var members [][2]Json
+		iterObject(n, func(k, v Json) {
+			members = append(members, [2]Json{k, v})
+		})
+		// The binary order sorts the strings before the numbers: use
+		// the order of the keys in Rego instead.
+		slices.SortFunc(members, func(a, b [2]Json) int {
+			return a[0].AST().Compare(b[0].AST())
+		})
+
+		children := make([]Json, len(members))
+		for i := range members {
+			children[i] = members[i][1]
+		}
+		return children
+	}
+
+	return nil
+}
+
+func parseQuery(path string) ([]querySegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("json: query %q does not start with $", path)
+	}
+
+	var segments []querySegment
+	for rest := path[1:]; rest != ""; {
+		var seg querySegment
+		if strings.HasPrefix(rest, "..") {
+			seg.descendants = true
+			rest = rest[2:]
+			if !strings.HasPrefix(rest, "[") {
+				rest = "." + rest
+			}
+		}
+
+		var err error
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			if strings.HasPrefix(rest, "*") {
+				seg.kind, rest = queryWildcard, rest[1:]
+				break
+			}
+
+			n := strings.IndexFunc(rest, func(r rune) bool {
+				return !(r == '_' || r >= 0x80 || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+			})
+			if n < 0 {
+				n = len(rest)
+			}
+			if n == 0 || '0' <= rest[0] && rest[0] <= '9' {
+				return nil, fmt.Errorf("json: query %q has invalid name at offset %d", path, len(path)-len(rest))
+			}
+			seg.kind, seg.name, rest = queryName, rest[:n], rest[n:]
+
+		case '[':
+			seg, rest, err = parseQueryBracket(seg, rest[1:])
+			if err != nil {
+				return nil, fmt.Errorf("json: query %q %w at offset %d", path, err, len(path)-len(rest))
+			}
+
+		default:
+			return nil, fmt.Errorf("json: query %q has unexpected %q at offset %d", path, rest[0], len(path)-len(rest))
+		}
+
+		segments = append(segments, seg)
+	}
+
+	return segments, nil
+}
+
+// parseQueryBracket parses the selector of the bracket notation, following
+// the opening bracket, returning the rest after the closing one.
+func parseQueryBracket(seg querySegment, rest string) (querySegment, string, error) {
+	switch {
+	case strings.HasPrefix(rest, "*]"):
+		seg.kind = queryWildcard
+		return seg, rest[2:], nil
+
+	case strings.HasPrefix(rest, "'") || strings.HasPrefix(rest, `"`):
+		quote := rest[0]
+		var name strings.Builder
+		for i := 1; i < len(rest); i++ {
+			switch c := rest[i]; {
+			case c == '\\' && i+1 < len(rest):
+				i++
+				name.WriteByte(rest[i])
+			case c == quote:
+				if i+1 >= len(rest) || rest[i+1] != ']' {
+					return seg, rest[i+1:], fmt.Errorf("has unterminated bracket")
+				}
+				seg.kind, seg.name = queryName, name.String()
+				return seg, rest[i+2:], nil
+			default:
+				name.WriteByte(c)
+			}
+		}
+		return seg, "", fmt.Errorf("has unterminated string")
+
+	default:
+		n := strings.IndexByte(rest, ']')
+		if n < 0 {
+			return seg, "", fmt.Errorf("has unterminated bracket")
+		}
+
+		i, err := strconv.Atoi(rest[:n])
+		if err != nil {
+			return seg, rest, fmt.Errorf("has invalid index %q", rest[:n])
+		}
+		seg.kind, seg.index = queryIndex, i
+		return seg, rest[n+1:], nil
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	doc := MustNew(map[string]any{
+		"items": []any{
+			map[string]any{"id": 1, "tags": []any{"a", "b"}},
+			map[string]any{"id": 2, "tags": []any{}},
+			map[string]any{"name": "no id"},
+		},
+		"meta": map[string]any{"id": "m", "a b": true},
+	})
+
+	tests := []struct {
+		path     string
+		expected string
+		err      bool
+	}{
+		{path: "$", expected: `[` + doc.String() + `]`},
+		{path: "$.items[*].id", expected: `[1,2]`},
+		{path: "$['items'][0][\"tags\"]", expected: `[["a","b"]]`},
+		{path: "$.items[-1].name", expected: `["no id"]`},
+		{path: "$.items[3]", expected: `[]`},
+		{path: "$.meta.*", expected: `[true,"m"]`},
+		{path: "$.meta['a b']", expected: `[true]`},
+		{path: "$..id", expected: `[1,2,"m"]`},
+		{path: "$..tags[*]", expected: `["a","b"]`},
+		{path: "$.missing.id", expected: `[]`},
+		{path: "$.meta[0]", expected: `[]`},
+		{path: "$.items.id", expected: `[]`},
+		{path: "items", err: true},
+		{path: "$.", err: true},
+		{path: "$.1a", err: true},
+		{path: "$[a]", err: true},
+		{path: "$['a'", err: true},
+		{path: "$[?(@.id)]", err: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			result, err := Query(doc, tc.path)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			elements := make([]File, len(result))
+			for i := range result {
+				elements[i] = result[i]
+			}
+			if actual := NewArray(elements, len(elements)).String(); actual != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// SchemaBuilder constructs objects of a fixed shape, records of the same
+// schema. The objects share the key names, sorted once, instead of each
+// holding its own: serialized together, all but the first one refer to
+// the shape of the first one, as the objects of equal keys do.
+type SchemaBuilder struct {
+	keys  *[]string
+	order []int // order[i] is the index of the i-th sorted key in the keys given.
+}
+
+// NewSchemaBuilder returns a builder of the objects with the keys, which
+// must be unique.
+func NewSchemaBuilder(keys []string) (*SchemaBuilder, error) {
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return keys[order[i]] < keys[order[j]]
+	})
+
+	sorted := make([]string, len(keys))
+	for i, j := range order {
+		sorted[i] = keys[j]
+		if i > 0 && sorted[i] == sorted[i-1] {
+			return nil, fmt.Errorf("json: duplicate schema key %q", sorted[i])
+		}
+	}
+
+	return &SchemaBuilder{keys: &sorted, order: order}, nil
+}
+
+// Keys returns the keys of the objects built, sorted.
+func (b *SchemaBuilder) Keys() []string {
+	return slices.Clone(*b.keys)
+}
+
+// Build returns an object with the values for the keys, in the order the
+// keys were given to NewSchemaBuilder.
+func (b *SchemaBuilder) Build(values ...File) (Object, error) {
+	if len(values) != len(b.order) {
+		return nil, fmt.Errorf("json: schema of %d keys given %d values", len(b.order), len(values))
+	}
+
+	sorted := make([]any, len(values))
+	for i, j := range b.order {
+		sorted[i] = values[j]
+	}
+
+	return &ObjectMap{b.keys, sorted}, nil
+}
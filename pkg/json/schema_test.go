@@ -0,0 +1,60 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaBuilder(t *testing.T) {
+	b, err := NewSchemaBuilder([]string{"name", "age", "email"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := b.Keys(); !reflect.DeepEqual(keys, []string{"age", "email", "name"}) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	alice, err := b.Build(NewString("alice"), NewFloatInt(30), NewString("alice@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := b.Build(NewString("bob"), NewFloatInt(40), NewNull())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := MustNew(map[string]any{"name": "alice", "age": 30, "email": "alice@example.com"}); compare(alice, expected) != 0 {
+		t.Fatalf("expected %v, got %v", expected, alice)
+	}
+	if &alice.Names()[0] != &bob.Names()[0] {
+		t.Fatal("expected shared keys")
+	}
+
+	// The records serialize as they would if built otherwise, the second
+	// one referring to the shape of the first one.
+	records := NewArray([]File{alice, bob}, 2)
+	var built, expected bytes.Buffer
+	if _, err := serialize(records, newEncodingCache(), &built, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := serialize(MustNew(records.JSON()), newEncodingCache(), &expected, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(built.Bytes(), expected.Bytes()) {
+		t.Fatal("unexpected serialization")
+	}
+	if !bytes.Contains(built.Bytes(), []byte{typeObjectThin}) {
+		t.Fatal("expected shape reused")
+	}
+
+	if _, err := b.Build(NewNull()); err == nil {
+		t.Fatal("expected values missing to fail")
+	}
+	if _, err := NewSchemaBuilder([]string{"a", "b", "a"}); err == nil {
+		t.Fatal("expected duplicate keys to fail")
+	}
+}
@@ -81,7 +81,11 @@ func (s snapshot) WriteTo(w io.Writer) (int64, error) {
 }
 
 func (s snapshot) Diff(other Collections) (*utils.BytesReader, int64, bool, error) {
-	return diff(s.content, s.Len(), other.(*snapshot).content)
+	o, ok := other.(*snapshot)
+	if !ok {
+		o = other.(*readOnlyView).serialized()
+	}
+	return diff(s.content, s.Len(), o.content)
 }
 
 func (s snapshot) Writable() WritableCollections {
@@ -97,18 +101,39 @@ func (s snapshot) Reader() *utils.MultiReader {
 }
 
 func (s snapshot) DeltaReader() *utils.MultiReader {
-	if reader, ok := s.content.(*deltaObjectReader); ok {
-		return reader.delta
+	if delta, ok := s.deltaReader(); ok {
+		return delta
 	}
 
-	if reader, ok := s.content.(*deltaPatchObjectReader); ok {
+	panic("not reached")
+}
+
+func (s snapshot) DeltaBytes() ([]byte, bool) {
+	delta, ok := s.deltaReader()
+	if !ok {
+		return nil, false
+	}
+
+	data := make([]byte, delta.Len())
+	_, err := delta.ReadAt(data, 0)
+	checkError(err)
+
+	return data, true
+}
+
+func (s snapshot) deltaReader() (*utils.MultiReader, bool) {
+	switch reader := s.content.(type) {
+	case *deltaObjectReader:
+		return reader.delta, true
+
+	case *deltaPatchObjectReader:
 		delta, err := reader.serialize()
 		checkError(err)
 
-		return utils.NewMultiReaderFromBytesReader(delta)
+		return utils.NewMultiReaderFromBytesReader(delta), true
 	}
 
-	panic("not reached")
+	return nil, false
 }
 
 func (s snapshot) Len() int64 {
@@ -174,8 +199,18 @@ func (s snapshot) find(name string) Resource {
 }
 
 type resourceImpl struct {
-	obj  Object
-	name string
+	obj         Object
+	name        string
+	collections metaWriter // The writable collections holding the resource, nil if read-only.
+}
+
+// writableResource makes the resource, if any, writable through the
+// collections holding it.
+func writableResource(r Resource, collections metaWriter) Resource {
+	if r, ok := r.(*resourceImpl); ok {
+		r.collections = collections
+	}
+	return r
 }
 
 func findImpl(obj Object, name string) Resource {
@@ -203,6 +238,37 @@ func findImpl2(obj Object, segs []string, i int) Resource {
 	return findImpl2(cobj, segs, i+1)
 }
 
+// removeImpl removes the resource, and the resources under, from the
+// hierarchy starting at Object, returning the modified hierarchy and true
+// if found.
+func removeImpl(obj Object, segs []string) (Object, bool) {
+	prefix := "data:"
+
+	if kindImpl(obj) != Directory {
+		return obj, false
+	}
+
+	child := obj.Value(prefix + segs[0])
+	if child == nil {
+		return obj, false
+	}
+
+	if len(segs) == 1 {
+		return obj.Remove(prefix + segs[0]), true
+	}
+
+	c, ok := child.(Object)
+	if !ok {
+		return obj, false
+	}
+
+	c, removed := removeImpl(c, segs[1:])
+	if removed {
+		obj, _ = obj.Set(prefix+segs[0], c)
+	}
+	return obj, removed
+}
+
 func kindImpl(obj Object) Kind {
 	kind := obj.Value("kind")
 
@@ -289,7 +355,7 @@ func (r *resourceImpl) Resource(name string) Resource {
 	segs := PathSegments(r.name)
 	i := len(segs)
 	segs = append(segs, PathSegments(name)...)
-	return findImpl2(r.obj, segs, i)
+	return writableResource(findImpl2(r.obj, segs, i), r.collections)
 }
 
 func (r *resourceImpl) Resources() []Resource {
@@ -309,7 +375,7 @@ func (r *resourceImpl) Resources() []Resource {
 
 		name = name[len(prefix):]
 
-		if resource := findImpl2(r.obj, append(segs, name), len(segs)); resource != nil {
+		if resource := writableResource(findImpl2(r.obj, append(segs, name), len(segs)), r.collections); resource != nil {
 			resources = append(resources, resource)
 		}
 	}
@@ -337,6 +403,40 @@ func (r *resourceImpl) Blob() Blob {
 	return nil
 }
 
+func (r *resourceImpl) BlobReader() (io.Reader, int64, error) {
+	if r.Kind() != Unstructured {
+		panic("json: not a blob")
+	}
+
+	if o, ok := r.obj.(ObjectBinary); ok {
+		offset, ok, err := o.content.ObjectValueOffset("data")
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			return nil, 0, fmt.Errorf("json: blob not found")
+		}
+
+		if t, err := o.content.ReadType(offset); err != nil {
+			return nil, 0, err
+		} else if t != typeBinaryFull {
+			return nil, 0, fmt.Errorf("json: not a blob")
+		}
+
+		section, err := o.content.ReadBytesSection(offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		return section, section.Size(), nil
+	}
+
+	blob := r.Blob()
+	if blob == nil {
+		return nil, 0, fmt.Errorf("json: blob not found")
+	}
+	return bytes.NewReader(blob.Value()), int64(len(blob.Value())), nil
+}
+
 func (r *resourceImpl) setBlob(blob Blob) {
 	if r.Kind() != Unstructured {
 		panic("json: not a blob")
@@ -424,7 +524,7 @@ func NewCollections() WritableCollections {
 }
 
 func (s *writableSnapshot) Resource(name string) Resource {
-	return findImpl2(s.data, PathSegments(name), 0)
+	return writableResource(findImpl2(s.data, PathSegments(name), 0), s)
 }
 
 func (s *writableSnapshot) WriteBlob(name string, blob Blob) {
@@ -468,6 +568,23 @@ func (s *writableSnapshot) WriteMeta(name string, key string, value string) bool
 	return true
 }
 
+func (s *writableSnapshot) RemoveByMeta(key string, value string) int {
+	var names []string
+	s.Resource("").Walk(func(r Resource) bool {
+		if v, ok := r.Meta(key); ok && v == value && r.Name() != "" {
+			names = append(names, r.Name())
+			return false // Removed with the directory.
+		}
+		return true
+	})
+
+	for _, name := range names {
+		s.data, _ = removeImpl(s.data, PathSegments(name))
+	}
+
+	return len(names)
+}
+
 func (s *writableSnapshot) Prepare(timestamp time.Time) Collections {
 	s.setMetaRecursively(s.Resource(""), "timestamp", fmt.Sprintf("%d", timestamp.UnixNano()))
 
@@ -847,6 +964,24 @@ func readBytes(content *utils.MultiReader, offset int64) ([]byte, error) {
 	return p, nil
 }
 
+func readBytesSection(content *utils.MultiReader, offset int64) (*io.SectionReader, error) {
+	reader := newBinaryReader(content, offset)
+	n, err := reader.ReadVarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 {
+		return nil, fmt.Errorf("byte array length invalid")
+	}
+
+	if int64(content.Len()) < reader.Offset()+n {
+		return nil, fmt.Errorf("byte array not read: %w", io.ErrUnexpectedEOF)
+	}
+
+	return io.NewSectionReader(content, reader.Offset(), n), nil
+}
+
 func compareBytes(content *utils.MultiReader, offset int64, s []byte) (int, error) {
 	reader := newBinaryReader(content, offset)
 	n, err := reader.ReadVarint()
@@ -897,6 +1032,10 @@ func (s *snapshotReader) ReadBytes(offset int64) ([]byte, error) {
 	return readBytes(s.content, offset+1)
 }
 
+func (s *snapshotReader) ReadBytesSection(offset int64) (*io.SectionReader, error) {
+	return readBytesSection(s.content, offset+1)
+}
+
 func (s *snapshotReader) ReadString(offset int64) (string, error) {
 	return readString(s.content, offset+1)
 }
@@ -966,6 +1105,10 @@ func (s *snapshotArrayReader) ReadBytes(offset int64) ([]byte, error) {
 	return readBytes(s.content, offset+1)
 }
 
+func (s *snapshotArrayReader) ReadBytesSection(offset int64) (*io.SectionReader, error) {
+	return readBytesSection(s.content, offset+1)
+}
+
 func (s *snapshotArrayReader) ReadString(offset int64) (string, error) {
 	return readString(s.content, offset+1)
 }
@@ -1078,6 +1221,10 @@ func (s *snapshotObjectReader) ReadBytes(offset int64) ([]byte, error) {
 	return readBytes(s.content, offset+1)
 }
 
+func (s *snapshotObjectReader) ReadBytesSection(offset int64) (*io.SectionReader, error) {
+	return readBytesSection(s.content, offset+1)
+}
+
 func (s *snapshotObjectReader) ReadString(offset int64) (string, error) {
 	return readString(s.content, offset+1)
 }
@@ -1129,6 +1276,12 @@ func (s *snapshotObjectReader) ObjectNamesIndex(i int) (string, error) {
 }
 
 func (s *snapshotObjectReader) objectNameIndex(name string) (int, bool, error) {
+	if s.n >= objectIndexMinLen {
+		if i, ok, indexed := s.indexedNameIndex(name); indexed {
+			return i, ok, nil
+		}
+	}
+
 	var nestedErr error
 
 	nameB := readOnlyStringBytes(name)
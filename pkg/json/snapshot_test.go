@@ -94,6 +94,50 @@ func TestCollectionsDeltaSerialization(t *testing.T) {
 	}
 }
 
+func TestCollectionsDeltaBytes(t *testing.T) {
+	w1 := NewCollections()
+	w1.WriteJSON("a", NewString("foo"))
+	w2 := NewCollections()
+	w2.WriteJSON("a", NewString("bar"))
+
+	c1 := w1.Prepare(time.Now())
+	c2 := w2.Prepare(time.Now())
+
+	if _, ok := c1.DeltaBytes(); ok {
+		t.Fatalf("snapshot collection reported as a delta")
+	}
+
+	delta, n, _, err := c1.Diff(c2)
+	if err != nil {
+		t.Fatalf("unable to compute a diff: %v", err)
+	}
+
+	snapshot := c1.(*snapshot).content.(*snapshotObjectReader).content
+	d, err := NewCollectionsFromReaders(snapshot, c1.Len(), utils.NewMultiReaderFromBytesReader(delta), n, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to construct a snapshot: %v", err)
+	}
+
+	data, ok := d.DeltaBytes()
+	if !ok {
+		t.Fatalf("delta collection not reported as a delta")
+	}
+	if int64(len(data)) != n {
+		t.Fatalf("unexpected delta length: %d, expected %d", len(data), n)
+	}
+
+	// The delta bytes are sufficient to reconstruct the collection on top of the snapshot.
+
+	e, err := NewCollectionsFromReaders(snapshot, c1.Len(), utils.NewMultiReaderFromBytesReader(utils.NewBytesReader(data)), n, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to construct a snapshot: %v", err)
+	}
+
+	if s := e.Resource("a").JSON().(*String).Value(); s != "bar" {
+		t.Fatalf("unexpected value: %v", s)
+	}
+}
+
 func TestCollectionsNamespace(t *testing.T) {
 	// Populate a writable collection with three collections.
 
@@ -227,6 +271,57 @@ func TestCollectionsMeta(t *testing.T) {
 	}
 }
 
+func TestCollectionsRemoveByMeta(t *testing.T) {
+	w := NewCollections()
+	w.WriteJSON("a/x", NewString("x"))
+	w.WriteJSON("a/y", NewString("y"))
+	w.WriteBlob("b/z", NewBlob([]byte("z")))
+	w.WriteJSON("c/d/e", NewString("e"))
+	w.WriteJSON("c/f", NewString("f"))
+
+	w.WriteMeta("a/x", "source", "feedX")
+	w.WriteMeta("b/z", "source", "feedX")
+	w.WriteMeta("c/d", "source", "feedX")
+	w.WriteMeta("c/d/e", "source", "feedX")
+	w.WriteMeta("c/f", "source", "feedY")
+
+	// Snapshots prepared before are not modified.
+	before := w.Prepare(time.Now())
+
+	// The directory is removed with its resources, and counted once.
+	if n := w.RemoveByMeta("source", "feedX"); n != 3 {
+		t.Errorf("expected 3 resources removed, got %d", n)
+	}
+
+	c := w.Prepare(time.Now())
+	for _, name := range []string{"a/x", "b/z", "c/d", "c/d/e"} {
+		if c.Resource(name) != nil {
+			t.Errorf("resource %s not removed", name)
+		}
+		if before.Resource(name) == nil {
+			t.Errorf("resource %s removed from the snapshot prepared before", name)
+		}
+	}
+	for _, name := range []string{"a/y", "b", "c/f"} {
+		if c.Resource(name) == nil {
+			t.Errorf("resource %s removed", name)
+		}
+	}
+
+	if n := c.Writable().RemoveByMeta("source", "feedZ"); n != 0 {
+		t.Errorf("expected no resources removed, got %d", n)
+	}
+
+	// Removals from the writable copies of binary snapshots.
+	cw := c.Writable()
+	if n := cw.RemoveByMeta("source", "feedY"); n != 1 {
+		t.Errorf("expected 1 resource removed, got %d", n)
+	}
+	if cw.Prepare(time.Now()).Resource("c/f") != nil || c.Resource("c/f") == nil {
+		t.Errorf("resource c/f not removed from the writable copy only")
+	}
+}
+
 func TestBlobSerialization(t *testing.T) {
 	data := []byte("foo")
 
@@ -0,0 +1,30 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+// Roots of the tenant views: the shared resources of the base collections
+// and the private resources of the tenant.
+const (
+	TenantViewSharedRoot = "shared"
+	TenantViewTenantRoot = "tenant"
+)
+
+func (s snapshot) TenantView(tenantPrefix string, private Collections) Collections {
+	return newTenantView(&s, tenantPrefix, private)
+}
+
+// newTenantView returns a view over the shared resources of the base
+// collections and the private resources of a tenant, without copying
+// either. Nothing else is reachable through it.
+func newTenantView(base Collections, tenantPrefix string, private Collections) *readOnlyView {
+	properties := make(map[string]File, 2)
+	if r, ok := base.Resource(TenantViewSharedRoot).(*resourceImpl); ok && r != nil {
+		properties["data:"+TenantViewSharedRoot] = r.obj
+	}
+	if r, ok := private.Resource(tenantPrefix).(*resourceImpl); ok && r != nil {
+		properties["data:"+TenantViewTenantRoot] = r.obj
+	}
+
+	return &readOnlyView{root: NewObject(properties)}
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTenantView(t *testing.T) {
+	w := NewCollections()
+	w.WriteJSON("shared/roles", MustNew([]any{"admin", "viewer"}))
+	w.WriteJSON("shared/limits/default", MustNew(10))
+	w.WriteJSON("tenants/acme/users", MustNew([]any{"alice"}))
+	base := w.Prepare(time.Now())
+
+	w = NewCollections()
+	w.WriteJSON("tenants/acme/users", MustNew([]any{"bob"}))
+	w.WriteJSON("tenants/acme/settings", MustNew(map[string]any{"theme": "dark"}))
+	w.WriteJSON("tenants/other/users", MustNew([]any{"eve"}))
+	private := w.Prepare(time.Now())
+
+	view := base.TenantView("tenants/acme", private)
+
+	if r := view.Resource("shared/roles"); r == nil || r.Kind() != JSON || r.JSON().String() != `["admin","viewer"]` {
+		t.Fatalf("unexpected shared resource %v", r)
+	}
+	if r := view.Resource("tenant/users"); r == nil || r.Name() != "tenant/users" || r.JSON().String() != `["bob"]` {
+		t.Fatalf("unexpected tenant resource %v", r)
+	}
+	for _, name := range []string{"tenants", "tenants/acme/users", "tenant/other", "other/users"} {
+		if r := view.Resource(name); r != nil {
+			t.Errorf("expected %q not reachable, got %v", name, r.Name())
+		}
+	}
+
+	expected := []string{"shared/limits/default", "shared/roles", "tenant/settings", "tenant/users"}
+	if collections := view.Collections(); !reflect.DeepEqual(collections, expected) {
+		t.Fatalf("expected %v, got %v", expected, collections)
+	}
+
+	var names []string
+	view.Walk(func(r Resource) bool {
+		names = append(names, r.Name())
+		return true
+	})
+	if len(names) != 8 {
+		t.Fatalf("unexpected walk %v", names)
+	}
+
+	// The view copies as the collections of the same resources, and diffs
+	// as them.
+	if collections := view.Writable().Prepare(time.Now()).Collections(); !reflect.DeepEqual(collections, expected) {
+		t.Fatalf("expected %v copied, got %v", expected, collections)
+	}
+	if _, _, empty, err := view.Diff(view); err != nil || !empty {
+		t.Fatalf("expected no difference, got %v", err)
+	}
+	if _, _, empty, err := base.Diff(view); err != nil || empty {
+		t.Fatalf("expected differences, got %v", err)
+	}
+
+	if _, err := view.PatchJSON("tenant/users", nil); err == nil {
+		t.Fatal("expected patch to fail")
+	}
+	if view.Remove("tenant/users") || view.WriteMeta("tenant/users", "k", "v") {
+		t.Fatal("expected writes to fail")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected write to panic")
+			}
+		}()
+		view.WriteJSON("tenant/users", NewNull())
+	}()
+
+	// A tenant without private resources sees the shared ones only.
+	if collections := base.TenantView("tenants/missing", private).Collections(); !reflect.DeepEqual(collections, expected[:2]) {
+		t.Fatalf("unexpected collections %v", collections)
+	}
+}
@@ -19,3 +19,85 @@ func UnionObjects(a, b Json) Json {
 
 	return b
 }
+
+// MergeObjects merges b over a like UnionObjects does, unless concatArrays
+// is true: then the arrays at the same paths of both are concatenated
+// instead, the elements of b after those of a. Objects are merged key by
+// key, recursively, the keys of either kept, and on any other conflict,
+// the value of b replaces the value of a.
+func MergeObjects(a, b Json, concatArrays bool) Json {
+	if !concatArrays {
+		return UnionObjects(a, b)
+	}
+
+	if x, ok := a.(Array); ok {
+		y, ok := b.(Array)
+		if !ok {
+			return b
+		}
+
+		elements := make([]File, 0, x.Len()+y.Len())
+		for i := range x.Len() {
+			elements = append(elements, x.Iterate(i))
+		}
+		for i := range y.Len() {
+			elements = append(elements, y.Iterate(i))
+		}
+		return NewArray(elements, len(elements))
+	}
+
+	if !isObject(a) || !isObject(b) {
+		return b
+	}
+
+	result := NewObject2(0)
+	iterObject(a, func(k, v Json) {
+		if v2, ok := getObject(b, k); ok {
+			v = MergeObjects(v, v2, true)
+		}
+		result = result.Insert(k, v)
+	})
+	iterObject(b, func(k, v Json) {
+		if _, ok := getObject(a, k); !ok {
+			result = result.Insert(k, v)
+		}
+	})
+
+	return result
+}
+
+func isObject(j Json) bool {
+	switch j.(type) {
+	case Object, Object2:
+		return true
+	}
+	return false
+}
+
+// iterObject calls f for the keys and values of the Object or Object2.
+func iterObject(j Json, f func(k, v Json)) {
+	switch o := j.(type) {
+	case Object:
+		for _, name := range o.Names() {
+			f(NewString(name), o.Value(name))
+		}
+	case Object2:
+		o.iter(func(_ uint64, k, v Json) {
+			f(k, v)
+		})
+	}
+}
+
+// getObject returns the value of the key of the Object or Object2.
+func getObject(j Json, k Json) (Json, bool) {
+	switch o := j.(type) {
+	case Object:
+		if s, ok := k.(*String); ok {
+			v := o.Value(s.Value())
+			return v, v != nil
+		}
+	case Object2:
+		return o.Get(k)
+	}
+	return nil, false
+}
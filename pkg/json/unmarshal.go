@@ -5,30 +5,480 @@ package json
 
 import (
 	"bytes"
+	"encoding"
+	"encoding/base64"
+	gojson "encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 
 	jsoniter "github.com/json-iterator/go"
+
+	internal "github.com/open-policy-agent/eopa/pkg/json/internal/json"
 )
 
-// Unmarshal stores the JSON data in the value pointed to by v.
+// Unmarshal stores the JSON data in the value pointed to by v, like the golang JSON package would decode the JSON data serialized: numbers stored in
+// interface values are stored as float64, and the gojson.Unmarshaler and encoding.TextUnmarshaler interfaces, and the struct tags, are honored. The
+// data is read directly, without serializing it.
 func Unmarshal(data Json, v any) error {
-	var buf bytes.Buffer
-	if _, err := data.WriteTo(&buf); err != nil {
-		return err
-	}
-
-	return jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(buf.Bytes(), v)
+	return unmarshalJSON(data, v, false)
 }
 
 // UnmarshalUseNumber stores the JSON data in the value pointed to by v. Numbers are stored as json.Number
 func UnmarshalUseNumber(data Json, v any) error {
-	var buf bytes.Buffer
-	if _, err := data.WriteTo(&buf); err != nil {
+	return unmarshalJSON(data, v, true)
+}
+
+func unmarshalJSON(data Json, v any, useNumber bool) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &gojson.InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+
+	d := structDecoder{useNumber: useNumber}
+	if err := d.value(data, rv); err != nil {
 		return err
 	}
 
-	dec := jsoniter.ConfigCompatibleWithStandardLibrary.NewDecoder(&buf)
-	dec.UseNumber()
-	return dec.Decode(v)
+	return d.savedError
+}
+
+var (
+	encodingTextUnmarshalerType  = reflect.TypeFor[encoding.TextUnmarshaler]()
+	errUnexportedEmbeddedPointer = errors.New("json: cannot set embedded pointer to unexported struct")
+)
+
+// structDecoder decodes the JSON data into golang values, the inverse of New. As the golang JSON package, it continues past the type mismatches, to
+// return the first of them once done.
+type structDecoder struct {
+	useNumber  bool
+	savedError error
+	structType reflect.Type // Struct of the field decoded, for the errors.
+	fields     []string     // Path of the field decoded, for the errors.
+}
+
+func (d *structDecoder) saveError(err error) {
+	if d.savedError == nil {
+		d.savedError = err
+	}
+}
+
+func (d *structDecoder) typeError(value string, t reflect.Type) {
+	err := &gojson.UnmarshalTypeError{Value: value, Type: t, Field: strings.Join(d.fields, ".")}
+	if d.structType != nil {
+		err.Struct = d.structType.Name()
+	}
+	d.saveError(err)
+}
+
+// field decodes the data into v, as the field of the path given.
+func (d *structDecoder) field(data Json, v reflect.Value, name string) error {
+	d.fields = append(d.fields, name)
+	err := d.value(data, v)
+	d.fields = d.fields[:len(d.fields)-1]
+	return err
+}
+
+func (d *structDecoder) value(data Json, v reflect.Value) error {
+	_, null := data.(Null)
+	u, tu, v := indirect(v, null)
+	if u != nil {
+		var buf bytes.Buffer
+		if _, err := data.WriteTo(&buf); err != nil {
+			return err
+		}
+		return u.UnmarshalJSON(buf.Bytes())
+	}
+
+	if tu != nil {
+		switch data := data.(type) {
+		case *String:
+			return tu.UnmarshalText([]byte(data.Value()))
+		case Null:
+		default:
+			d.typeError(jsonTypeName(data), reflect.TypeOf(tu))
+			return nil
+		}
+	}
+
+	switch data := data.(type) {
+	case Null:
+		switch v.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+			v.SetZero()
+		}
+
+	case Bool:
+		switch {
+		case v.Kind() == reflect.Bool:
+			v.SetBool(data.Value())
+		case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+			v.Set(reflect.ValueOf(data.Value()))
+		default:
+			d.typeError("bool", v.Type())
+		}
+
+	case Float:
+		return d.number(data.Value(), v)
+
+	case *String:
+		s := data.Value()
+		switch {
+		case v.Kind() == reflect.String && v.Type() == numberType:
+			if _, err := strconv.ParseFloat(s, 64); err != nil {
+				return fmt.Errorf("json: invalid number literal, trying to unmarshal %q into Number", s)
+			}
+			v.SetString(s)
+		case v.Kind() == reflect.String:
+			v.SetString(s)
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				d.saveError(err)
+				return nil
+			}
+			v.SetBytes(b)
+		case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+			v.Set(reflect.ValueOf(s))
+		default:
+			d.typeError("string", v.Type())
+		}
+
+	case Array:
+		return d.array(data, v)
+
+	case Object:
+		return d.object(data, v)
+
+	default:
+		// Other types, such as sets, are decoded from their serialization.
+		var buf bytes.Buffer
+		if _, err := data.WriteTo(&buf); err != nil {
+			return err
+		}
+
+		dec := jsoniter.ConfigCompatibleWithStandardLibrary.NewDecoder(&buf)
+		if d.useNumber {
+			dec.UseNumber()
+		}
+		return dec.Decode(v.Addr().Interface())
+	}
+
+	return nil
+}
+
+func (d *structDecoder) number(n gojson.Number, v reflect.Value) error {
+	s := string(n)
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			d.typeError("number", v.Type())
+			return nil
+		}
+
+		if d.useNumber {
+			v.Set(reflect.ValueOf(n))
+			return nil
+		}
+
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			d.typeError("number "+s, v.Type())
+			return nil
+		}
+		v.Set(reflect.ValueOf(f))
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v.OverflowInt(i) {
+			d.typeError("number "+s, v.Type())
+			return nil
+		}
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || v.OverflowUint(u) {
+			d.typeError("number "+s, v.Type())
+			return nil
+		}
+		v.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil || v.OverflowFloat(f) {
+			d.typeError("number "+s, v.Type())
+			return nil
+		}
+		v.SetFloat(f)
+
+	case reflect.String:
+		if v.Type() != numberType {
+			d.typeError("number", v.Type())
+			return nil
+		}
+		v.SetString(s)
+
+	default:
+		d.typeError("number", v.Type())
+	}
+
+	return nil
+}
+
+func (d *structDecoder) array(data Array, v reflect.Value) error {
+	n := data.Len()
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			d.typeError("array", v.Type())
+			return nil
+		}
+
+		a := make([]any, n)
+		for i := range n {
+			if err := d.field(data.Iterate(i), reflect.ValueOf(&a[i]).Elem(), strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(reflect.ValueOf(a))
+		return nil
+
+	case reflect.Slice:
+		if v.IsNil() || v.Cap() < n {
+			v.Set(reflect.MakeSlice(v.Type(), n, n))
+		} else {
+			v.SetLen(n)
+		}
+
+	case reflect.Array:
+		for i := n; i < v.Len(); i++ {
+			v.Index(i).SetZero()
+		}
+		n = min(n, v.Len())
+
+	default:
+		d.typeError("array", v.Type())
+		return nil
+	}
+
+	for i := range n {
+		if err := d.field(data.Iterate(i), v.Index(i), strconv.Itoa(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *structDecoder) object(data Object, v reflect.Value) error {
+	names := data.Names()
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			d.typeError("object", v.Type())
+			return nil
+		}
+
+		m := make(map[string]any, len(names))
+		for _, name := range names {
+			var value any
+			if err := d.field(data.Value(name), reflect.ValueOf(&value).Elem(), name); err != nil {
+				return err
+			}
+			m[name] = value
+		}
+		v.Set(reflect.ValueOf(m))
+
+	case reflect.Map:
+		t := v.Type()
+		switch kt := t.Key(); {
+		case reflect.PointerTo(kt).Implements(encodingTextUnmarshalerType):
+		case kt.Kind() == reflect.String, kt.Kind() >= reflect.Int && kt.Kind() <= reflect.Uintptr:
+		default:
+			d.typeError("object", t)
+			return nil
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, len(names)))
+		}
+
+		for _, name := range names {
+			value := reflect.New(t.Elem()).Elem()
+			if err := d.field(data.Value(name), value, name); err != nil {
+				return err
+			}
+
+			key, ok := d.mapKey(name, t.Key())
+			if !ok {
+				continue
+			}
+			v.SetMapIndex(key, value)
+		}
+
+	case reflect.Struct:
+		structType := d.structType
+		d.structType = v.Type()
+		defer func() { d.structType = structType }()
+
+		fields := internal.CachedTypeFields(v.Type())
+		for _, name := range names {
+			f := findField(fields, name)
+			if f == nil {
+				continue
+			}
+
+			fv, err := fieldForSet(v, f.Index)
+			if err != nil {
+				d.saveError(err)
+				continue
+			}
+
+			if err := d.field(data.Value(name), fv, f.Name); err != nil {
+				return err
+			}
+		}
+
+	default:
+		d.typeError("object", v.Type())
+	}
+
+	return nil
+}
+
+// mapKey converts the object key to the map key type, as the golang JSON package.
+func (d *structDecoder) mapKey(name string, kt reflect.Type) (reflect.Value, bool) {
+	if reflect.PointerTo(kt).Implements(encodingTextUnmarshalerType) {
+		key := reflect.New(kt)
+		if err := key.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(name)); err != nil {
+			d.saveError(err)
+			return reflect.Value{}, false
+		}
+		return key.Elem(), true
+	}
+
+	switch kt.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(name).Convert(kt), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(name, 10, 64)
+		if err != nil || reflect.Zero(kt).OverflowInt(i) {
+			d.typeError("number "+name, kt)
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(i).Convert(kt), true
+	default:
+		u, err := strconv.ParseUint(name, 10, 64)
+		if err != nil || reflect.Zero(kt).OverflowUint(u) {
+			d.typeError("number "+name, kt)
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(u).Convert(kt), true
+	}
+}
+
+// findField returns the field of the key, preferring an exact match over a case-insensitive one.
+func findField(fields []internal.Field, name string) *internal.Field {
+	var fold *internal.Field
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i]
+		}
+		if fold == nil && strings.EqualFold(fields[i].Name, name) {
+			fold = &fields[i]
+		}
+	}
+	return fold
+}
+
+// fieldForSet returns the field, allocating the embedded struct pointers on its way.
+func fieldForSet(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("%w: %v", errUnexportedEmbeddedPointer, v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// indirect walks down the pointers of v, allocating them as needed, until it reaches a non-pointer, or a gojson.Unmarshaler or an
+// encoding.TextUnmarshaler. For null, it stops at the last pointer, for it to be set to nil. This is as the golang JSON package.
+func indirect(v reflect.Value, null bool) (gojson.Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
+	// Start from the pointer to v, if addressable, for the methods with pointer receivers.
+	if v.Kind() != reflect.Pointer && v.Type().Name() != "" && v.CanAddr() {
+		v = v.Addr()
+	}
+
+	for {
+		// Decode into the value of an interface, if it holds a non-nil pointer.
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			if e := v.Elem(); e.Kind() == reflect.Pointer && !e.IsNil() && (!null || e.Elem().Kind() == reflect.Pointer) {
+				v = e
+				continue
+			}
+		}
+
+		if v.Kind() != reflect.Pointer {
+			break
+		}
+
+		if null && v.CanSet() {
+			break
+		}
+
+		// A pointer to itself would loop forever.
+		if v.Elem().Kind() == reflect.Interface && v.Elem().Elem().Equal(v) {
+			v = v.Elem()
+			break
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		if v.Type().NumMethod() > 0 && v.CanInterface() {
+			if u, ok := v.Interface().(gojson.Unmarshaler); ok {
+				return u, nil, reflect.Value{}
+			}
+			if !null {
+				if u, ok := v.Interface().(encoding.TextUnmarshaler); ok {
+					return nil, u, reflect.Value{}
+				}
+			}
+		}
+
+		v = v.Elem()
+	}
+
+	return nil, nil, v
+}
+
+func jsonTypeName(data Json) string {
+	switch data.(type) {
+	case Bool:
+		return "bool"
+	case Float:
+		return "number"
+	case Array:
+		return "array"
+	case Object:
+		return "object"
+	default:
+		return "value"
+	}
 }
 
 func (decoder *Decoder) UnmarshalString() (string, error) {
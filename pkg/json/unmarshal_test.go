@@ -5,6 +5,9 @@ package json
 
 import (
 	"bytes"
+	gojson "encoding/json"
+	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -56,3 +59,107 @@ func TestUnmarshalArray(t *testing.T) {
 		t.Error("parsing failure: not equal")
 	}
 }
+
+type unmarshalText string
+
+func (u *unmarshalText) UnmarshalText(text []byte) error {
+	*u = unmarshalText("text:" + string(text))
+	return nil
+}
+
+type unmarshalJSONValue string
+
+func (u *unmarshalJSONValue) UnmarshalJSON(data []byte) error {
+	*u = unmarshalJSONValue("json:" + string(data))
+	return nil
+}
+
+type unmarshalEmbedded struct {
+	E int `json:"e"`
+}
+
+type unmarshalStruct struct {
+	unmarshalEmbedded
+	A      string                   `json:"a"`
+	B      int                      `json:"b,omitempty"`
+	C      *float64                 `json:"c"`
+	D      []any                    `json:"d"`
+	F      map[string]unmarshalText `json:"f"`
+	G      unmarshalJSONValue       `json:"g"`
+	H      [2]bool                  `json:"h"`
+	I      []byte                   `json:"i"`
+	J      gojson.Number            `json:"j"`
+	K      map[int]string           `json:"k"`
+	L      any                      `json:"l"`
+	Mixed  string
+	Ignore string `json:"-"`
+}
+
+func TestUnmarshalGo(t *testing.T) {
+	doc := `{
+		"a": "x", "b": 1, "c": 1.5, "d": [1, "y", null, {"z": true}], "e": 7,
+		"f": {"k": "v"}, "g": {"nested":[1]}, "h": [true], "i": "Zm9v", "j": 12.50,
+		"k": {"1": "one"}, "l": {"m": [2]}, "mixed": "case", "Ignore": "no", "unknown": 1
+	}`
+
+	tests := []struct {
+		note string
+		doc  string
+		new  func() any
+	}{
+		{note: "struct", doc: doc, new: func() any { return new(unmarshalStruct) }},
+		{note: "struct pointer", doc: doc, new: func() any { return new(*unmarshalStruct) }},
+		{note: "interface", doc: doc, new: func() any { return new(any) }},
+		{note: "map", doc: doc, new: func() any { return new(map[string]any) }},
+		{note: "null", doc: `{"c": null, "d": null, "a": null}`, new: func() any {
+			c := 1.0
+			return &unmarshalStruct{A: "a", C: &c, D: []any{1}}
+		}},
+		{note: "array", doc: `[1, 2, 3]`, new: func() any { return new([2]int) }},
+		{note: "slice reuse", doc: `[1]`, new: func() any { s := []int{4, 5}; return &s }},
+		{note: "type mismatch", doc: `{"a": 1, "b": "x", "mixed": "y"}`, new: func() any { return new(unmarshalStruct) }},
+		{note: "overflow", doc: `[1, 300, 3]`, new: func() any { return new([]int8) }},
+		{note: "text unmarshaler", doc: `"x"`, new: func() any { return new(unmarshalText) }},
+		{note: "unexported embedded pointer", doc: `{"e": 1}`, new: func() any {
+			return new(struct{ *unmarshalEmbedded })
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			expected, actual := tc.new(), tc.new()
+			expectedErr := gojson.Unmarshal([]byte(tc.doc), expected)
+
+			j, err := NewDecoder(bytes.NewBufferString(tc.doc)).Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The error messages vary between the golang versions: compare the type errors by their fields.
+			err = Unmarshal(j, actual)
+			var expectedTypeErr, typeErr *gojson.UnmarshalTypeError
+			switch {
+			case (err == nil) != (expectedErr == nil):
+				t.Fatalf("expected error %v, got %v", expectedErr, err)
+			case tc.note == "unexported embedded pointer":
+			case errors.As(expectedErr, &expectedTypeErr) && (!errors.As(err, &typeErr) || typeErr.Field != expectedTypeErr.Field):
+				t.Fatalf("expected error %v, got %v", expectedErr, err)
+			}
+			if !reflect.DeepEqual(expected, actual) {
+				t.Fatalf("expected %#v, got %#v", expected, actual)
+			}
+		})
+	}
+
+	var n any
+	if err := UnmarshalUseNumber(MustNew(map[string]any{"n": gojson.Number("1.50")}), &n); err != nil {
+		t.Fatal(err)
+	}
+	if exp := map[string]any{"n": gojson.Number("1.50")}; !reflect.DeepEqual(n, exp) {
+		t.Fatalf("expected %v, got %v", exp, n)
+	}
+
+	if err := Unmarshal(MustNew(1), n); err == nil {
+		t.Fatal("expected non-pointer to fail")
+	}
+}
@@ -6,6 +6,8 @@ package utils
 import (
 	"errors"
 	"io"
+	"sync"
+	"sync/atomic"
 )
 
 // SafeRead reads len(data) bytes from reader to data. It also guarantees that if not all data was not read, there is an error, but masks the [io.EOF] error out if all the data requested was read.
@@ -65,6 +67,8 @@ type MultiReader struct {
 	bb   *BytesReader
 	am   *MultiReader
 	bm   *MultiReader
+
+	cache atomic.Pointer[sync.Map] // Allocated on first use.
 }
 
 // Creates a new MultiReader from an existing MultiReader.
@@ -87,6 +91,18 @@ func NewMultiReaderFromBytesReaders(a, b *BytesReader) *MultiReader {
 	return &MultiReader{ab: a, base: 0, n: int64(a.Len()), bb: b}
 }
 
+// Cache returns a map to cache data derived from the contents of the
+// reader. The contents are immutable, so the cached data remains valid
+// as long as the reader is in use.
+func (r *MultiReader) Cache() *sync.Map {
+	if c := r.cache.Load(); c != nil {
+		return c
+	}
+
+	r.cache.CompareAndSwap(nil, &sync.Map{})
+	return r.cache.Load()
+}
+
 // Is this MultiReader built from 1+ MultiReaders?
 func (r *MultiReader) HasMultiReaders() bool {
 	return r.am != nil
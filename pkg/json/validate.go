@@ -0,0 +1,236 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Constraint names a constraint of the limits a document violated.
+type Constraint string
+
+const (
+	ConstraintMaxDepth        Constraint = "max_depth"
+	ConstraintMaxStringLength Constraint = "max_string_length"
+	ConstraintMaxArrayLength  Constraint = "max_array_length"
+	ConstraintTypes           Constraint = "types"
+)
+
+// Limits constrain the documents ValidateStream accepts. Zero values are
+// not enforced.
+type Limits struct {
+	// MaxDepth bounds the nesting of arrays and objects: [[1]] is of
+	// depth 2, and scalars of depth 0.
+	MaxDepth int
+
+	// MaxStringLength bounds the length of the strings and the object
+	// keys, in bytes.
+	MaxStringLength int
+
+	// MaxArrayLength bounds the number of elements of the arrays.
+	MaxArrayLength int
+
+	// Types are the types allowed at the paths given as JSON pointers, by
+	// their names: "null", "boolean", "number", "string", "array" and
+	// "object". A "*" path segment matches any object key or array index.
+	Types map[string][]string
+}
+
+// LimitError is the error of a document violating a constraint of the
+// limits, at the path given as a JSON pointer.
+type LimitError struct {
+	Constraint Constraint
+	Path       string
+	Limit      int    // The limit exceeded, if any.
+	Type       string // The type not allowed, if any.
+}
+
+func (e *LimitError) Error() string {
+	if e.Constraint == ConstraintTypes {
+		return fmt.Sprintf("json: type %s not allowed at %q", e.Type, e.Path)
+	}
+	return fmt.Sprintf("json: %s %d exceeded at %q", e.Constraint, e.Limit, e.Path)
+}
+
+// ValidateStream checks the JSON document read is valid, and within the
+// limits, without constructing it. It stops reading at the first
+// violation, returning a *LimitError. Strings are read in full before
+// their lengths are checked.
+func ValidateStream(r io.Reader, limits Limits) error {
+	v := &validator{
+		iter:   jsoniter.Parse(config, r, 512),
+		limits: limits,
+	}
+
+	for ptr, types := range limits.Types {
+		path, err := ParsePointer(ptr)
+		if err != nil {
+			return fmt.Errorf("json: invalid types path %q: %w", ptr, err)
+		}
+		v.types = append(v.types, typeConstraint{path: path, types: types})
+	}
+
+	if err := v.value(0); err != nil {
+		return err
+	}
+
+	// Only whitespace may follow the document.
+	if v.iter.WhatIsNext(); !errors.Is(v.iter.Error, io.EOF) {
+		if err := v.error(); err != nil {
+			return err
+		}
+		return errors.New("json: unexpected data after document")
+	}
+
+	return nil
+}
+
+type typeConstraint struct {
+	path  []string
+	types []string
+}
+
+type validator struct {
+	iter   *jsoniter.Iterator
+	limits Limits
+	types  []typeConstraint
+	path   []string
+}
+
+func (v *validator) error() error {
+	if err := v.iter.Error; err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+func (v *validator) value(depth int) error {
+	valueType := v.iter.WhatIsNext()
+	if err := v.iter.Error; err != nil {
+		return err
+	}
+
+	if err := v.checkType(valueType); err != nil {
+		return err
+	}
+
+	switch valueType {
+	case jsoniter.StringValue:
+		if err := v.checkString(v.iter.ReadString()); err != nil {
+			return err
+		}
+
+	case jsoniter.NumberValue, jsoniter.NilValue, jsoniter.BoolValue:
+		v.iter.Skip()
+
+	case jsoniter.ArrayValue:
+		if err := v.checkDepth(depth + 1); err != nil {
+			return err
+		}
+
+		var n int
+		var err error
+		v.iter.ReadArrayCB(func(*jsoniter.Iterator) bool {
+			if n++; v.limits.MaxArrayLength > 0 && n > v.limits.MaxArrayLength {
+				err = v.limitError(ConstraintMaxArrayLength, v.limits.MaxArrayLength)
+				return false
+			}
+
+			v.path = append(v.path, strconv.Itoa(n-1))
+			err = v.value(depth + 1)
+			v.path = v.path[:len(v.path)-1]
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+
+	case jsoniter.ObjectValue:
+		if err := v.checkDepth(depth + 1); err != nil {
+			return err
+		}
+
+		var err error
+		v.iter.ReadMapCB(func(_ *jsoniter.Iterator, field string) bool {
+			v.path = append(v.path, field)
+			if err = v.checkString(field); err == nil {
+				err = v.value(depth + 1)
+			}
+			v.path = v.path[:len(v.path)-1]
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+
+	default:
+		v.iter.Skip() // Let the iterator report the invalid value.
+	}
+
+	return v.error()
+}
+
+func (v *validator) checkDepth(depth int) error {
+	if v.limits.MaxDepth > 0 && depth > v.limits.MaxDepth {
+		return v.limitError(ConstraintMaxDepth, v.limits.MaxDepth)
+	}
+	return nil
+}
+
+func (v *validator) checkString(s string) error {
+	if v.limits.MaxStringLength > 0 && len(s) > v.limits.MaxStringLength {
+		return v.limitError(ConstraintMaxStringLength, v.limits.MaxStringLength)
+	}
+	return nil
+}
+
+func (v *validator) checkType(valueType jsoniter.ValueType) error {
+	var name string
+	switch valueType {
+	case jsoniter.StringValue:
+		name = "string"
+	case jsoniter.NumberValue:
+		name = "number"
+	case jsoniter.NilValue:
+		name = "null"
+	case jsoniter.BoolValue:
+		name = "boolean"
+	case jsoniter.ArrayValue:
+		name = "array"
+	case jsoniter.ObjectValue:
+		name = "object"
+	default:
+		return nil
+	}
+
+	for _, c := range v.types {
+		if matchPath(c.path, v.path) && !slices.Contains(c.types, name) {
+			return &LimitError{Constraint: ConstraintTypes, Path: NewPointer(v.path), Type: name}
+		}
+	}
+	return nil
+}
+
+func (v *validator) limitError(c Constraint, limit int) error {
+	return &LimitError{Constraint: c, Path: NewPointer(v.path), Limit: limit}
+}
+
+func matchPath(pattern, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
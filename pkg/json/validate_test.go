@@ -0,0 +1,113 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateStream(t *testing.T) {
+	tests := []struct {
+		note    string
+		doc     string
+		limits  Limits
+		err     *LimitError
+		invalid bool
+	}{
+		{
+			note:   "within limits",
+			doc:    `{"a": [1, "bc", {"d": null}], "e": true}`,
+			limits: Limits{MaxDepth: 3, MaxStringLength: 2, MaxArrayLength: 3},
+		},
+		{
+			note: "no limits",
+			doc:  `[[[[[[["` + strings.Repeat("x", 1000) + `"]]]]]]]`,
+		},
+		{
+			note:   "max depth",
+			doc:    `{"a": [{"b": [1]}]}`,
+			limits: Limits{MaxDepth: 3},
+			err:    &LimitError{Constraint: ConstraintMaxDepth, Path: "/a/0/b", Limit: 3},
+		},
+		{
+			note:   "max string length",
+			doc:    `{"a": ["ok", "too long"]}`,
+			limits: Limits{MaxStringLength: 4},
+			err:    &LimitError{Constraint: ConstraintMaxStringLength, Path: "/a/1", Limit: 4},
+		},
+		{
+			note:   "max key length",
+			doc:    `{"a/b": {"long key": 1}}`,
+			limits: Limits{MaxStringLength: 4},
+			err:    &LimitError{Constraint: ConstraintMaxStringLength, Path: "/a~1b/long key", Limit: 4},
+		},
+		{
+			note:   "max array length",
+			doc:    `{"a": [1, 2, 3]}`,
+			limits: Limits{MaxArrayLength: 2},
+			err:    &LimitError{Constraint: ConstraintMaxArrayLength, Path: "/a", Limit: 2},
+		},
+		{
+			note:   "types",
+			doc:    `{"users": [{"name": "a"}, {"name": 1}]}`,
+			limits: Limits{Types: map[string][]string{"": {"object"}, "/users/*/name": {"string"}}},
+			err:    &LimitError{Constraint: ConstraintTypes, Path: "/users/1/name", Type: "number"},
+		},
+		{
+			note:   "root type",
+			doc:    `[]`,
+			limits: Limits{Types: map[string][]string{"": {"object", "null"}}},
+			err:    &LimitError{Constraint: ConstraintTypes, Path: "", Type: "array"},
+		},
+		{
+			note:   "violation before invalid JSON",
+			doc:    `["too long", {`,
+			limits: Limits{MaxStringLength: 4},
+			err:    &LimitError{Constraint: ConstraintMaxStringLength, Path: "/0", Limit: 4},
+		},
+		{
+			note:    "invalid JSON",
+			doc:     `{"a": [1, }`,
+			invalid: true,
+		},
+		{
+			note:    "trailing data",
+			doc:     `{} x`,
+			invalid: true,
+		},
+		{
+			note:    "empty",
+			doc:     ``,
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			err := ValidateStream(strings.NewReader(tc.doc), tc.limits)
+
+			var lerr *LimitError
+			switch {
+			case tc.err != nil:
+				if !errors.As(err, &lerr) || *lerr != *tc.err {
+					t.Fatalf("expected %v, got %v", tc.err, err)
+				}
+			case tc.invalid:
+				if err == nil || errors.As(err, &lerr) {
+					t.Fatalf("expected invalid JSON, got %v", err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestValidateStreamInvalidTypesPath(t *testing.T) {
+	if err := ValidateStream(strings.NewReader(`{}`), Limits{Types: map[string][]string{"a": {"object"}}}); err == nil {
+		t.Fatal("expected invalid pointer error")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package json
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/open-policy-agent/eopa/pkg/json/utils"
+)
+
+var errReadOnlyView = errors.New("json: view is read-only")
+
+// readOnlyView implements the Collections interface over a root directory
+// assembled from the resources of other collections, such as the tenant
+// and principal views, sharing the resources instead of copying them.
+// Writing to it fails. The binary level representation, only needed for
+// diffing and persisting, is serialized on first use.
+type readOnlyView struct {
+	root Object
+
+	once     sync.Once
+	snapshot *snapshot
+}
+
+func (v *readOnlyView) Resource(name string) Resource {
+	return findImpl2(v.root, PathSegments(name), 0)
+}
+
+func (v *readOnlyView) Collections() []string {
+	collections := make([]string, 0)
+
+	v.Walk(func(resource Resource) bool {
+		if resource.Kind() == JSON {
+			collections = append(collections, resource.Name())
+		}
+
+		return true
+	})
+
+	sort.Strings(collections)
+	return collections
+}
+
+func (v *readOnlyView) Walk(callback func(resource Resource) bool) {
+	findImpl(v.root, "").Walk(callback)
+}
+
+func (v *readOnlyView) Diff(other Collections) (*utils.BytesReader, int64, bool, error) {
+	return v.serialized().Diff(other)
+}
+
+func (v *readOnlyView) Writable() WritableCollections {
+	return &writableSnapshot{data: v.root.Clone(true).(Object)}
+}
+
+func (v *readOnlyView) Len() int64 {
+	return v.serialized().Len()
+}
+
+func (v *readOnlyView) Reader() *utils.MultiReader {
+	return v.serialized().Reader()
+}
+
+func (v *readOnlyView) DeltaReader() *utils.MultiReader {
+	panic("not reached")
+}
+
+func (v *readOnlyView) DeltaBytes() ([]byte, bool) {
+	return nil, false
+}
+
+func (v *readOnlyView) WriteTo(w io.Writer) (int64, error) {
+	return v.serialized().WriteTo(w)
+}
+
+func (v *readOnlyView) ToBundle(roots []string) ([]byte, error) {
+	return toBundle(findImpl(v.root, ""), roots)
+}
+
+func (v *readOnlyView) MerkleHash() map[string][32]byte {
+	hashes := make(map[string][32]byte)
+	merkleHash(findImpl(v.root, ""), hashes)
+	return hashes
+}
+
+func (v *readOnlyView) TenantView(tenantPrefix string, private Collections) Collections {
+	return newTenantView(v, tenantPrefix, private)
+}
+
+func (v *readOnlyView) WithPrincipal(principal string) Collections {
+	return newPrincipalView(v.root, principal)
+}
+
+func (v *readOnlyView) Objects() []any {
+	return nil
+}
+
+func (v *readOnlyView) WriteBlob(string, Blob) {
+	panic(errReadOnlyView)
+}
+
+func (v *readOnlyView) WriteJSON(string, Json) {
+	panic(errReadOnlyView)
+}
+
+func (v *readOnlyView) PatchJSON(string, Patch) (bool, error) {
+	return false, errReadOnlyView
+}
+
+func (v *readOnlyView) WriteDirectory(string) {
+	panic(errReadOnlyView)
+}
+
+func (v *readOnlyView) Remove(string) bool {
+	return false
+}
+
+func (v *readOnlyView) WriteMeta(string, string, string) bool {
+	return false
+}
+
+// serialized returns the view serialized as a snapshot.
+func (v *readOnlyView) serialized() *snapshot {
+	v.once.Do(func() {
+		content, slen, err := translate(v.root)
+		if err != nil {
+			corrupted(err)
+		}
+
+		v.snapshot = &snapshot{ObjectBinary: newObject(content, 0), blen: slen, slen: slen}
+	})
+
+	return v.snapshot
+}
@@ -14,9 +14,14 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
 
 	"github.com/open-policy-agent/opa/v1/ast"
 	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/logging"
 	"github.com/open-policy-agent/opa/v1/metrics"
 	"github.com/open-policy-agent/opa/v1/storage"
 	"github.com/open-policy-agent/opa/v1/util"
@@ -316,20 +321,64 @@ func readEtagFromStore(ctx context.Context, store storage.Store, txn storage.Tra
 		return "", err
 	}
 
-	str, ok := value.(*bjson.String)
-	if !ok {
-		return "", fmt.Errorf("corrupt bundle etag")
+	switch str := value.(type) {
+	case string:
+		return str, nil
+	case *bjson.String:
+		return str.Value(), nil
 	}
 
-	return str.Value(), nil
+	return "", fmt.Errorf("corrupt bundle etag")
+}
+
+type CustomActivator struct {
+	logger      logging.Logger
+	retain      atomic.Int64
+	retained    retainedSnapshots
+	concurrency atomic.Int64
+
+	mtx    sync.Mutex
+	mapped map[string]MappedBundleConfig
 }
 
-type CustomActivator struct{}
+// SetLogger sets the logger used to warn about manifest roots left
+// without data and policies by an activation. Without a logger, no
+// such check is done.
+func (a *CustomActivator) SetLogger(l logging.Logger) {
+	a.logger = l
+}
+
+// SetConcurrency sets the number of bundles whose data is converted and
+// validated concurrently when activating several bundles at once. Below
+// two, the default, bundles are prepared one after the other. Either way,
+// the policies are compiled together and everything is written in the
+// activation transaction.
+func (a *CustomActivator) SetConcurrency(n int) {
+	a.concurrency.Store(int64(n))
+}
 
 // Activate the bundle(s) by loading into the given Store. This will load policies, data, and record
 // the manifest in storage. The compiler provided will have had the polices compiled on it.
-func (*CustomActivator) Activate(opts *bundleApi.ActivateOpts) error {
-	return activateBundles(opts)
+func (a *CustomActivator) Activate(opts *bundleApi.ActivateOpts) error {
+	retain := int(a.retain.Load())
+	snapshots, err := a.captureSnapshots(opts, retain)
+	if err != nil {
+		return err
+	}
+
+	used, err := activateBundles(opts, int(a.concurrency.Load()))
+	if err != nil {
+		return err
+	}
+
+	for name, s := range snapshots {
+		a.retainSnapshot(opts.Store, name, s, retain)
+	}
+
+	if a.logger != nil {
+		warnUnusedRoots(a.logger, opts.Bundles, used)
+	}
+	return nil
 }
 
 // Note(philip): Originally, this function would convert the bundle in-place to
@@ -337,7 +386,10 @@ func (*CustomActivator) Activate(opts *bundleApi.ActivateOpts) error {
 // meaning the (*inmem.store).Truncate() call later would have to redo all the
 // conversion work again. For larger (>1 GB) OPA bundles, this resulted in
 // prohibitive slowdowns.
-func activateBundles(opts *bundleApi.ActivateOpts) error {
+//
+// It returns the manifest roots of each snapshot bundle that the
+// activation wrote data or policies under.
+func activateBundles(opts *bundleApi.ActivateOpts, concurrency int) (map[string]map[string]struct{}, error) {
 	// Build collections of bundle names, modules, and roots to erase
 	erase := map[string]struct{}{}
 	names := map[string]struct{}{}
@@ -353,7 +405,7 @@ func activateBundles(opts *bundleApi.ActivateOpts) error {
 
 			roots, err := ReadBundleRootsFromStore(opts.Ctx, opts.Store, opts.Txn, name)
 			if suppressNotFound(err) != nil {
-				return err
+				return nil, err
 			}
 			for _, root := range roots {
 				erase[root] = struct{}{}
@@ -366,17 +418,22 @@ func activateBundles(opts *bundleApi.ActivateOpts) error {
 		}
 	}
 
-	// Before changing anything make sure the roots don't collide with any
-	// other bundles that already are activated or other bundles being activated.
+	// Before changing anything make sure the runtime supports the rego
+	// versions of the modules, and the roots don't collide with any other
+	// bundles that already are activated or other bundles being activated.
+	if err := checkRegoVersions(snapshotBundles); err != nil {
+		return nil, err
+	}
+
 	err := hasRootsOverlap(opts.Ctx, opts.Store, opts.Txn, opts.Bundles)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(deltaBundles) != 0 {
 		err := activateDeltaBundles(opts, deltaBundles)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -384,77 +441,14 @@ func activateBundles(opts *bundleApi.ActivateOpts) error {
 	// manifests before activating a new snapshot bundle.
 	remaining, err := eraseBundles(opts.Ctx, opts.Store, opts.Txn, opts.ParserOptions, names, erase)
 	if err != nil {
-		return err
-	}
-
-	// Note(philip): This block does in in-place replacement of the JSON
-	// bundleApi.Raw content for OPA bundles with their EOPA BJSON equivalents.
-	// This saves re-processing the data multiple times down the line,
-	// noticeably in (*inmem.store).Truncate.
-	for _, b := range snapshotBundles {
-		for idx, item := range b.Raw {
-			path := filepath.ToSlash(item.Path)
-			if filepath.Base(path) == "data.json" {
-				// Skip if already in BJSON format
-				if bjson.IsBJson(item.Value) {
-					continue
-				}
-
-				// Convert JSON to BJSON
-				val, err := bjson.NewDecoder(bytes.NewReader(item.Value)).Decode()
-				if err != nil {
-					return err
-				}
-
-				bs, err := bjson.Marshal(val)
-				if err != nil {
-					return err
-				}
-				b.Raw[idx] = bundleApi.Raw{Path: item.Path, Value: bs}
-			}
-		}
+		return nil, err
 	}
 
-	// Validate data in bundle does not contain paths outside the bundle's roots.
-	for _, b := range snapshotBundles {
-		for _, item := range b.Raw {
-			path := filepath.ToSlash(item.Path)
-
-			if filepath.Base(path) == "data.json" {
-				val, err := BjsonFromBinary(item.Value)
-				if err != nil {
-					return err
-				}
-
-				valObj, ok := val.(bjson.Object)
-				if ok {
-					err := doDFS(valObj, filepath.Dir(strings.Trim(path, "/")), *b.Manifest.Roots)
-					if err != nil {
-						return err
-					}
-				} else {
-					// Build an object for the value
-					p := getNormalizedPath(path)
-
-					if len(p) == 0 {
-						return fmt.Errorf("root value must be object")
-					}
-
-					dir := bjson.NewObject(nil)
-					for i := len(p) - 1; i > 0; i-- {
-						dir, _ = dir.Set(p[i], val)
-						val = dir
-						dir = bjson.NewObject(nil)
-					}
-					dir, _ = dir.Set(p[0], val)
-
-					err = doDFS(dir, filepath.Dir(strings.Trim(path, "/")), *b.Manifest.Roots)
-					if err != nil {
-						return err
-					}
-				}
-			}
-		}
+	// Convert and validate the data of the bundles, independently of each
+	// other, as their roots are disjoint.
+	used, err := prepareBundlesData(snapshotBundles, concurrency)
+	if err != nil {
+		return nil, err
 	}
 
 	// Compile the modules all at once to avoid having to re-do work.
@@ -464,36 +458,154 @@ func activateBundles(opts *bundleApi.ActivateOpts) error {
 
 	err = compileModules(opts.Compiler, opts.Metrics, snapshotBundles, remainingAndExtra, false)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := writeDataAndModules(opts.Ctx, opts.Store, opts.Txn, opts.TxnCtx, snapshotBundles, false, opts.ParserOptions.RegoVersion); err != nil {
-		return err
+	if err := writeDataAndModules(opts.Ctx, opts.Store, opts.Txn, opts.TxnCtx, snapshotBundles, false, opts.ParserOptions.RegoVersion, used); err != nil {
+		return nil, err
 	}
 
 	if err := ast.CheckPathConflicts(opts.Compiler, storage.NonEmpty(opts.Ctx, opts.Store, opts.Txn)); len(err) > 0 {
-		return err
+		return nil, err
 	}
 
 	for name, b := range snapshotBundles {
 		if err := writeManifestToStore(opts, name, b.Manifest); err != nil {
-			return err
+			return nil, err
 		}
 
 		if err := writeEtagToStore(opts, name, b.Etag); err != nil {
-			return err
+			return nil, err
 		}
 
 		if err := writeWasmModulesToStore(opts.Ctx, opts.Store, opts.Txn, name, b); err != nil {
-			return err
+			return nil, err
+		}
+	}
+
+	return used, nil
+}
+
+// prepareBundlesData prepares the data of the bundles for writing,
+// preparing up to concurrency bundles at once. It returns the manifest
+// roots of each bundle the data is written under.
+func prepareBundlesData(bundles map[string]*bundleApi.Bundle, concurrency int) (map[string]map[string]struct{}, error) {
+	used := make(map[string]map[string]struct{}, len(bundles))
+	for name := range bundles {
+		used[name] = map[string]struct{}{}
+	}
+
+	if concurrency < 2 || len(bundles) < 2 {
+		for name, b := range bundles {
+			if err := prepareBundleData(b, used[name]); err != nil {
+				return nil, err
+			}
+		}
+		return used, nil
+	}
+
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for name, b := range bundles {
+		u := used[name]
+		g.Go(func() error {
+			return prepareBundleData(b, u)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return used, nil
+}
+
+// prepareBundleData converts the data of the bundle to BJSON, and checks
+// it is within the bundle roots, recording the roots it is under in used.
+func prepareBundleData(b *bundleApi.Bundle, used map[string]struct{}) error {
+	// Note(philip): This block does in in-place replacement of the JSON
+	// bundleApi.Raw content for OPA bundles with their EOPA BJSON equivalents.
+	// This saves re-processing the data multiple times down the line,
+	// noticeably in (*inmem.store).Truncate.
+	for idx, item := range b.Raw {
+		path := filepath.ToSlash(item.Path)
+		if filepath.Base(path) == "data.json" {
+			// Skip if already in BJSON format
+			if bjson.IsBJson(item.Value) {
+				continue
+			}
+
+			// Convert JSON to BJSON
+			val, err := bjson.NewDecoder(bytes.NewReader(item.Value)).Decode()
+			if err != nil {
+				return err
+			}
+
+			bs, err := bjson.Marshal(val)
+			if err != nil {
+				return err
+			}
+			b.Raw[idx] = bundleApi.Raw{Path: item.Path, Value: bs}
+		}
+	}
+
+	// Validate data in bundle does not contain paths outside the bundle's roots.
+	for _, item := range b.Raw {
+		path := filepath.ToSlash(item.Path)
+
+		switch filepath.Base(path) {
+		case "data.json":
+			val, err := BjsonFromBinary(item.Value)
+			if err != nil {
+				return err
+			}
+
+			valObj, ok := val.(bjson.Object)
+			if ok {
+				err := doDFS(valObj, filepath.Dir(strings.Trim(path, "/")), *b.Manifest.Roots, used)
+				if err != nil {
+					return err
+				}
+			} else {
+				// Build an object for the value
+				p := getNormalizedPath(path)
+
+				if len(p) == 0 {
+					return fmt.Errorf("root value must be object")
+				}
+
+				dir := bjson.NewObject(nil)
+				for i := len(p) - 1; i > 0; i-- {
+					dir, _ = dir.Set(p[i], val)
+					val = dir
+					dir = bjson.NewObject(nil)
+				}
+				dir, _ = dir.Set(p[0], val)
+
+				err = doDFS(dir, filepath.Dir(strings.Trim(path, "/")), *b.Manifest.Roots, used)
+				if err != nil {
+					return err
+				}
+			}
+
+		case "data.yaml", "data.yml":
+			// The store decodes the YAML data: without decoding it here
+			// too, count the roots it may be written under as used.
+			dir := strings.TrimLeft(filepath.ToSlash(filepath.Dir(strings.Trim(path, "/"))), "/.")
+			for _, root := range *b.Manifest.Roots {
+				if bundleApi.RootPathsContain([]string{root}, dir) || bundleApi.RootPathsContain([]string{dir}, root) {
+					used[root] = struct{}{}
+				}
+			}
 		}
 	}
 
 	return nil
 }
 
-func doDFS(obj bjson.Object, path string, roots []string) error {
+func doDFS(obj bjson.Object, path string, roots []string, used map[string]struct{}) error {
 	if len(roots) == 1 && roots[0] == "" {
+		if obj.Len() > 0 {
+			used[""] = struct{}{}
+		}
 		return nil
 	}
 
@@ -523,6 +635,7 @@ func doDFS(obj bjson.Object, path string, roots []string) error {
 		}
 
 		if contains {
+			usedRoot(newPath, roots, used)
 			continue
 		}
 
@@ -531,13 +644,64 @@ func doDFS(obj bjson.Object, path string, roots []string) error {
 			return fmt.Errorf("manifest roots %v do not permit data at path '/%s' (hint: check bundle directory structure)", roots, newPath)
 		}
 
-		if err := doDFS(next, newPath, roots); err != nil {
+		if err := doDFS(next, newPath, roots, used); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// warnUnusedRoots logs the manifest roots of the snapshot bundles that
+// the activation wrote no data and no policies under. These usually
+// signal a misconfigured bundle, but are otherwise harmless.
+func warnUnusedRoots(logger logging.Logger, bundles map[string]*bundleApi.Bundle, used map[string]map[string]struct{}) {
+	for name, b := range bundles {
+		if b.Type() == bundleApi.DeltaBundleType {
+			continue
+		}
+
+		if unused := unusedRoots(*b.Manifest.Roots, used[name]); len(unused) > 0 {
+			logger.Warn("Bundle %q has manifest roots without data or policies: %v", name, unused)
+		}
+	}
+}
+
+// unusedRoots returns the roots missing from used, in order.
+func unusedRoots(roots []string, used map[string]struct{}) []string {
+	var unused []string
+	for _, root := range roots {
+		if _, ok := used[root]; !ok {
+			unused = append(unused, root)
+		}
+	}
+	return unused
+}
+
+// usedModuleRoots records the roots containing the packages of the
+// modules.
+func usedModuleRoots(modules map[string]*ast.Module, roots []string, used map[string]struct{}) {
+	for _, module := range modules {
+		path := make([]string, 0, len(module.Package.Path)-1)
+		for _, term := range module.Package.Path[1:] {
+			s, ok := term.Value.(ast.String)
+			if !ok {
+				break
+			}
+			path = append(path, string(s))
+		}
+		usedRoot(strings.Join(path, "/"), roots, used)
+	}
+}
+
+// usedRoot records the roots containing the path.
+func usedRoot(path string, roots []string, used map[string]struct{}) {
+	for _, root := range roots {
+		if bundleApi.RootPathsContain([]string{root}, path) {
+			used[root] = struct{}{}
+		}
+	}
+}
+
 func activateDeltaBundles(opts *bundleApi.ActivateOpts, bundles map[string]*bundleApi.Bundle) error {
 	// Check that the manifest roots and wasm resolvers in the delta bundle
 	// match with those currently in the store
@@ -784,11 +948,16 @@ func writeModuleRegoVersionToStore(ctx context.Context, store storage.Store, txn
 	return nil
 }
 
-func writeDataAndModules(ctx context.Context, store storage.Store, txn storage.Transaction, txnCtx *storage.Context, bundles map[string]*bundleApi.Bundle, legacy bool, runtimeRegoVersion ast.RegoVersion) error {
+// writeDataAndModules writes the data and the policies of the bundles to
+// the store, recording the roots of each bundle they are under in used.
+// The data of the raw bundles has been recorded by prepareBundleData.
+func writeDataAndModules(ctx context.Context, store storage.Store, txn storage.Transaction, txnCtx *storage.Context, bundles map[string]*bundleApi.Bundle, legacy bool, runtimeRegoVersion ast.RegoVersion, used map[string]map[string]struct{}) error {
 	params := storage.WriteParams
 	params.Context = txnCtx
 
 	for name, b := range bundles {
+		usedModuleRoots(b.ParsedModules(name), *b.Manifest.Roots, used[name])
+
 		if len(b.Raw) == 0 {
 			// Write data from each new bundle into the store. Only write under the
 			// roots contained in their manifest.
@@ -797,7 +966,7 @@ func writeDataAndModules(ctx context.Context, store storage.Store, txn storage.T
 				return fmt.Errorf("corrupt bundle data")
 			}
 
-			if err := writeData(ctx, store, txn, *b.Manifest.Roots, data); err != nil {
+			if err := writeData(ctx, store, txn, *b.Manifest.Roots, data, used[name]); err != nil {
 				return err
 			}
 
@@ -875,13 +1044,16 @@ func normalizePath(p string) string {
 	return filepath.ToSlash(p)
 }
 
-func writeData(ctx context.Context, store storage.Store, txn storage.Transaction, roots []string, data bjson.Object) error {
+func writeData(ctx context.Context, store storage.Store, txn storage.Transaction, roots []string, data bjson.Object, used map[string]struct{}) error {
 	for _, root := range roots {
 		path, ok := storage.ParsePathEscaped("/" + root)
 		if !ok {
 			return fmt.Errorf("manifest root path invalid: %v", root)
 		}
 		if value, ok := lookup(path, data); ok {
+			if len(path) > 0 || data.Len() > 0 {
+				used[root] = struct{}{}
+			}
 			if len(path) > 0 {
 				if err := storage.MakeDir(ctx, store, txn, path[:len(path)-1]); err != nil {
 					return err
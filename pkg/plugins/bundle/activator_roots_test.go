@@ -0,0 +1,126 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/logging"
+	"github.com/open-policy-agent/opa/v1/logging/test"
+	"github.com/open-policy-agent/opa/v1/metrics"
+	"github.com/open-policy-agent/opa/v1/storage"
+
+	"github.com/open-policy-agent/eopa/pkg/plugins/bundle"
+	"github.com/open-policy-agent/eopa/pkg/storage/inmem"
+)
+
+func TestActivateWarnsUnusedRoots(t *testing.T) {
+	module := "package a.policy\n\nallow := true"
+
+	tests := []struct {
+		note    string
+		roots   []string
+		data    map[string]any
+		raw     []bundleApi.Raw
+		modules bool
+		unused  []string
+	}{
+		{
+			note:    "all roots used",
+			roots:   []string{"a", "b/c"},
+			data:    map[string]any{"b": map[string]any{"c": map[string]any{"d": 1}}},
+			modules: true,
+		},
+		{
+			note:    "root without data or policies",
+			roots:   []string{"a", "b/c", "x"},
+			data:    map[string]any{"b": map[string]any{"c": 1}},
+			modules: true,
+			unused:  []string{"x"},
+		},
+		{
+			note:   "data outside a deeper root",
+			roots:  []string{"b/c"},
+			data:   map[string]any{"b": map[string]any{"d": 1}},
+			unused: []string{"b/c"},
+		},
+		{
+			note:  "raw data",
+			roots: []string{"b/c", "x/y"},
+			raw: []bundleApi.Raw{
+				{Path: "/b/data.json", Value: []byte(`{"c": {"d": 1}}`)},
+				{Path: "/x/y/data.json", Value: []byte(`[1]`)},
+			},
+		},
+		{
+			note:  "raw yaml data",
+			roots: []string{"b/c", "x"},
+			raw: []bundleApi.Raw{
+				{Path: "/b/c/data.yaml", Value: []byte("d: 1\n")},
+			},
+			unused: []string{"x"},
+		},
+		{
+			note:   "empty root",
+			roots:  []string{""},
+			data:   map[string]any{},
+			unused: []string{""},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			ctx := context.Background()
+			store := inmem.New()
+			logger := test.New()
+
+			activator := &bundle.CustomActivator{}
+			activator.SetLogger(logger)
+
+			b := &bundleApi.Bundle{
+				Manifest: bundleApi.Manifest{Roots: &tc.roots},
+				Data:     tc.data,
+				Raw:      tc.raw,
+			}
+			if tc.modules {
+				b.Modules = []bundleApi.ModuleFile{
+					{Path: "/a/policy.rego", Raw: []byte(module), Parsed: ast.MustParseModule(module)},
+				}
+			}
+
+			txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+			defer store.Abort(ctx, txn)
+
+			if err := activator.Activate(&bundleApi.ActivateOpts{
+				Ctx:      ctx,
+				Store:    store,
+				Txn:      txn,
+				Compiler: ast.NewCompiler(),
+				Metrics:  metrics.New(),
+				Bundles:  map[string]*bundleApi.Bundle{"test": b},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			var warnings []string
+			for _, e := range logger.Entries() {
+				if e.Level == logging.Warn {
+					warnings = append(warnings, e.Message)
+				}
+			}
+
+			var expected []string
+			if len(tc.unused) > 0 {
+				expected = []string{fmt.Sprintf("Bundle %q has manifest roots without data or policies: %v", "test", tc.unused)}
+			}
+			if fmt.Sprint(warnings) != fmt.Sprint(expected) {
+				t.Fatalf("expected warnings %q, got %q", expected, warnings)
+			}
+		})
+	}
+}
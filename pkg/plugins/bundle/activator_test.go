@@ -0,0 +1,79 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
+
+	bjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+func TestPrepareBundlesDataConcurrently(t *testing.T) {
+	for _, concurrency := range []int{0, 4} {
+		t.Run(fmt.Sprintf("concurrency=%d", concurrency), func(t *testing.T) {
+			bundles := disjointBundles(16)
+			if _, err := prepareBundlesData(bundles, concurrency); err != nil {
+				t.Fatal(err)
+			}
+			for name, b := range bundles {
+				if !bjson.IsBJson(b.Raw[0].Value) {
+					t.Fatalf("bundle %s data not converted", name)
+				}
+			}
+
+			bundles = disjointBundles(16)
+			bundles["outside"] = &bundleApi.Bundle{
+				Manifest: bundleApi.Manifest{Roots: &[]string{"x"}},
+				Raw:      []bundleApi.Raw{{Path: "/y/data.json", Value: []byte(`{"z": 1}`)}},
+			}
+			if _, err := prepareBundlesData(bundles, concurrency); err == nil {
+				t.Fatal("expected data outside the roots to fail")
+			}
+		})
+	}
+}
+
+func BenchmarkPrepareBundlesData(b *testing.B) {
+	for _, concurrency := range []int{1, 8} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			for range b.N {
+				b.StopTimer()
+				bundles := disjointBundles(48)
+				b.StartTimer()
+
+				if _, err := prepareBundlesData(bundles, concurrency); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// disjointBundles returns n bundles with disjoint roots, each with some
+// JSON data to convert.
+func disjointBundles(n int) map[string]*bundleApi.Bundle {
+	var data strings.Builder
+	data.WriteString(`{"items": [`)
+	for i := range 1000 {
+		if i > 0 {
+			data.WriteString(",")
+		}
+		fmt.Fprintf(&data, `{"id": %d, "name": "item-%d", "tags": ["a", "b"]}`, i, i)
+	}
+	data.WriteString(`]}`)
+
+	bundles := make(map[string]*bundleApi.Bundle, n)
+	for i := range n {
+		root := fmt.Sprintf("b%d", i)
+		bundles[root] = &bundleApi.Bundle{
+			Manifest: bundleApi.Manifest{Roots: &[]string{root}},
+			Raw:      []bundleApi.Raw{{Path: "/" + root + "/data.json", Value: []byte(data.String())}},
+		}
+	}
+	return bundles
+}
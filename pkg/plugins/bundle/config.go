@@ -0,0 +1,77 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/config"
+)
+
+// ActivatorConfig is under "extra/bundle_activation" in OPA config.
+type ActivatorConfig struct {
+	// RetainedSnapshots is the number of prior states of each bundle
+	// retained for RollbackBundle. See SetRetainedSnapshots.
+	RetainedSnapshots int `json:"retained_snapshots"`
+
+	// Concurrency is the number of bundles whose data is prepared at
+	// once by an activation. See SetConcurrency.
+	Concurrency int `json:"concurrency"`
+
+	// MappedBundles are the data files activated as memory mapped
+	// bundles, by bundle name. See MappedBundlesPlugin.
+	MappedBundles map[string]MappedBundleConfig `json:"mapped_bundles"`
+}
+
+// MappedBundleConfig is a data file in EOPA binary form, and the roots
+// and revision of the manifest of its bundle.
+type MappedBundleConfig struct {
+	FilePath string   `json:"file_path"`
+	Roots    []string `json:"roots"`
+	Revision string   `json:"revision"`
+}
+
+// OnConfig and OnConfigDiscovery make the activator a config hook,
+// configuring it from the "extra/bundle_activation" section. Without the
+// section, the defaults apply.
+func (a *CustomActivator) OnConfig(ctx context.Context, conf *config.Config) (*config.Config, error) {
+	return a.onConfig(ctx, conf)
+}
+
+func (a *CustomActivator) OnConfigDiscovery(ctx context.Context, conf *config.Config) (*config.Config, error) {
+	return a.onConfig(ctx, conf)
+}
+
+func (a *CustomActivator) onConfig(_ context.Context, conf *config.Config) (*config.Config, error) {
+	var c ActivatorConfig
+	if raw := conf.Extra["bundle_activation"]; raw != nil {
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return conf, err
+		}
+	}
+
+	if c.RetainedSnapshots < 0 {
+		return conf, fmt.Errorf("invalid bundle_activation.retained_snapshots: %d", c.RetainedSnapshots)
+	}
+
+	if c.Concurrency < 0 {
+		return conf, fmt.Errorf("invalid bundle_activation.concurrency: %d", c.Concurrency)
+	}
+
+	for name, m := range c.MappedBundles {
+		if m.FilePath == "" {
+			return conf, fmt.Errorf("bundle_activation.mapped_bundles.%s: file_path required", name)
+		}
+	}
+
+	a.SetRetainedSnapshots(c.RetainedSnapshots)
+	a.SetConcurrency(c.Concurrency)
+
+	a.mtx.Lock()
+	a.mapped = c.MappedBundles
+	a.mtx.Unlock()
+	return conf, nil
+}
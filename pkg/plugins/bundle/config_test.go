@@ -0,0 +1,61 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/config"
+)
+
+func TestActivatorConfig(t *testing.T) {
+	ctx := context.Background()
+	a := &CustomActivator{}
+
+	conf, err := config.ParseConfig([]byte(`{"bundle_activation": {"retained_snapshots": 3, "concurrency": 4}}`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.OnConfig(ctx, conf); err != nil {
+		t.Fatal(err)
+	}
+	if n := a.retain.Load(); n != 3 {
+		t.Fatalf("expected 3 retained snapshots, got %d", n)
+	}
+	if n := a.concurrency.Load(); n != 4 {
+		t.Fatalf("expected concurrency 4, got %d", n)
+	}
+
+	// Without the section, the defaults apply again.
+	conf, err = config.ParseConfig([]byte(`{}`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.OnConfigDiscovery(ctx, conf); err != nil {
+		t.Fatal(err)
+	}
+	if n := a.retain.Load(); n != 0 {
+		t.Fatalf("expected no retained snapshots, got %d", n)
+	}
+	if n := a.concurrency.Load(); n != 0 {
+		t.Fatalf("expected no concurrency, got %d", n)
+	}
+
+	conf, err = config.ParseConfig([]byte(`{"bundle_activation": {"retained_snapshots": -1}}`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.OnConfig(ctx, conf); err == nil {
+		t.Fatal("expected error")
+	}
+
+	conf, err = config.ParseConfig([]byte(`{"bundle_activation": {"concurrency": -1}}`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.OnConfig(ctx, conf); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"cmp"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/storage"
+
+	bjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+// ConflictKind is the kind of a path conflict.
+type ConflictKind string
+
+const (
+	// ConflictRoots is a manifest root overlapping with the root of
+	// another bundle.
+	ConflictRoots ConflictKind = "roots"
+
+	// ConflictData is a rule defined at, or under, a path with data.
+	ConflictData ConflictKind = "data"
+)
+
+// ConflictInfo describes a conflict between two paths, as slash
+// separated paths relative to data, and their sources. For root
+// conflicts, the sources are the bundle names; for data conflicts, the
+// path is the rule path and its source the module, and the conflicting
+// path is the data path and its source the data file, or for the data in
+// the store, the name of the bundle owning it, if any.
+type ConflictInfo struct {
+	Kind              ConflictKind `json:"kind"`
+	Path              string       `json:"path"`
+	Source            string       `json:"source"`
+	ConflictingPath   string       `json:"conflicting_path"`
+	ConflictingSource string       `json:"conflicting_source"`
+}
+
+// DetectAllConflicts reports every conflict the activation of the
+// bundles would run into, instead of failing on the first: the roots
+// overlapping with the roots of the other bundles, activated or
+// already active, and the rules of the bundles, the extra modules and
+// the policies in the store conflicting with the data of the bundles
+// or the data in the store. Like activation, it leaves out the data and
+// policies in the store under the roots of the bundles activated, as
+// they are replaced. Unlike activation, it does not modify the store.
+// Data and policies that fail to decode are skipped, for the
+// activation to report.
+func DetectAllConflicts(opts *bundleApi.ActivateOpts) []ConflictInfo {
+	conflicts := rootConflicts(opts)
+
+	var data []bundleData
+	var erase []string
+	for name, b := range opts.Bundles {
+		if b.Type() != bundleApi.DeltaBundleType {
+			data = append(data, readBundleData(name, b)...)
+
+			if opts.Store != nil {
+				roots, _ := ReadBundleRootsFromStore(opts.Ctx, opts.Store, opts.Txn, name)
+				erase = append(erase, roots...)
+			}
+			erase = append(erase, *b.Manifest.Roots...)
+		}
+	}
+
+	var stored *storeData
+	modules := map[string]*ast.Module{}
+	if opts.Store != nil {
+		stored = newStoreData(opts, erase)
+		maps.Copy(modules, readStoreModules(opts, erase))
+	}
+	maps.Copy(modules, opts.ExtraModules)
+	for name, b := range opts.Bundles {
+		if b.Type() != bundleApi.DeltaBundleType {
+			maps.Copy(modules, b.ParsedModules(name))
+		}
+	}
+
+	for source, module := range modules {
+		seen := make(map[string]struct{}, len(module.Rules))
+		for _, rule := range module.Rules {
+			path := rulePath(rule)
+			key := strings.Join(path, "/")
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			for _, d := range data {
+				if p, ok := d.conflict(path); ok {
+					conflicts = append(conflicts, ConflictInfo{
+						Kind:              ConflictData,
+						Path:              key,
+						Source:            source,
+						ConflictingPath:   p,
+						ConflictingSource: d.source,
+					})
+				}
+			}
+
+			if stored != nil {
+				if p, ok := stored.conflict(path); ok {
+					conflicts = append(conflicts, ConflictInfo{
+						Kind:              ConflictData,
+						Path:              key,
+						Source:            source,
+						ConflictingPath:   p,
+						ConflictingSource: stored.owner(p),
+					})
+				}
+			}
+		}
+	}
+
+	slices.SortFunc(conflicts, func(a, b ConflictInfo) int {
+		return cmp.Or(
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Path, b.Path),
+			cmp.Compare(a.Source, b.Source),
+			cmp.Compare(a.ConflictingPath, b.ConflictingPath),
+			cmp.Compare(a.ConflictingSource, b.ConflictingSource),
+		)
+	})
+	return conflicts
+}
+
+// rootConflicts reports the roots of the bundles activated overlapping
+// with the roots of the other bundles, like hasRootsOverlap. The
+// conflicts between two bundles activated are reported once.
+func rootConflicts(opts *bundleApi.ActivateOpts) []ConflictInfo {
+	allRoots := map[string][]string{}
+	if opts.Store != nil {
+		names, _ := ReadBundleNamesFromStore(opts.Ctx, opts.Store, opts.Txn)
+		for _, name := range names {
+			roots, _ := ReadBundleRootsFromStore(opts.Ctx, opts.Store, opts.Txn, name)
+			allRoots[name] = roots
+		}
+	}
+
+	for name, b := range opts.Bundles {
+		allRoots[name] = *b.Manifest.Roots
+	}
+
+	var conflicts []ConflictInfo
+	for name, b := range opts.Bundles {
+		for other, otherRoots := range allRoots {
+			if _, ok := opts.Bundles[other]; name == other || ok && other < name {
+				continue
+			}
+
+			for _, root := range *b.Manifest.Roots {
+				for _, otherRoot := range otherRoots {
+					if bundleApi.RootPathsOverlap(root, otherRoot) {
+						conflicts = append(conflicts, ConflictInfo{
+							Kind:              ConflictRoots,
+							Path:              root,
+							Source:            name,
+							ConflictingPath:   otherRoot,
+							ConflictingSource: other,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// readStoreModules parses the policies in the store the activation keeps,
+// those outside the roots erased, by their IDs.
+func readStoreModules(opts *bundleApi.ActivateOpts, erase []string) map[string]*ast.Module {
+	ids, err := opts.Store.ListPolicies(opts.Ctx, opts.Txn)
+	if err != nil {
+		return nil
+	}
+
+	modulesInfo, _ := readModuleInfoFromStore(opts.Ctx, opts.Store, opts.Txn)
+
+	modules := make(map[string]*ast.Module, len(ids))
+	for _, id := range ids {
+		bs, err := opts.Store.GetPolicy(opts.Ctx, opts.Txn, id)
+		if err != nil {
+			continue
+		}
+
+		parserOpts := opts.ParserOptions
+		if info, ok := modulesInfo[id]; ok {
+			parserOpts.RegoVersion = info.RegoVersion
+		}
+
+		module, err := ast.ParseModuleWithOpts(id, string(bs), parserOpts)
+		if err != nil {
+			continue
+		}
+
+		if path, err := module.Package.Path.Ptr(); err != nil || bundleApi.RootPathsContain(erase, path) {
+			continue
+		}
+		modules[id] = module
+	}
+	return modules
+}
+
+// storeData is the data in the store the activation keeps, outside the
+// roots erased.
+type storeData struct {
+	opts  *bundleApi.ActivateOpts
+	erase []string
+	roots map[string][]string // The roots of the bundles active, by name.
+}
+
+func newStoreData(opts *bundleApi.ActivateOpts, erase []string) *storeData {
+	d := &storeData{opts: opts, erase: erase, roots: map[string][]string{}}
+	names, _ := ReadBundleNamesFromStore(opts.Ctx, opts.Store, opts.Txn)
+	for _, name := range names {
+		if _, ok := opts.Bundles[name]; !ok {
+			d.roots[name], _ = ReadBundleRootsFromStore(opts.Ctx, opts.Store, opts.Txn, name)
+		}
+	}
+	return d
+}
+
+// conflict is like bundleData.conflict, for the data in the store.
+func (d *storeData) conflict(path []string) (string, bool) {
+	if bundleApi.RootPathsContain(d.erase, strings.Join(path, "/")) {
+		return "", false
+	}
+
+	for i := 1; i < len(path); i++ {
+		value, err := d.opts.Store.Read(d.opts.Ctx, d.opts.Txn, storage.Path(path[:i]))
+		if err != nil {
+			return "", false
+		}
+
+		switch value.(type) {
+		case map[string]any, bjson.Object:
+		default:
+			return strings.Join(path[:i], "/"), true
+		}
+	}
+
+	value, err := readBJSON(d.opts.Ctx, d.opts.Store, d.opts.Txn, storage.Path(path))
+	if err != nil {
+		return "", false
+	}
+
+	return strings.Join(path, "/"), d.nonEmpty(path, value)
+}
+
+// nonEmpty is like the nonEmpty function, leaving out the values under
+// the roots erased.
+func (d *storeData) nonEmpty(path []string, value bjson.Json) bool {
+	obj, ok := value.(bjson.Object)
+	if !ok {
+		return true
+	}
+
+	for name, v := range obj.IterEntries() {
+		child := strings.Join(append(slices.Clip(path), name), "/")
+		if bundleApi.RootPathsContain(d.erase, child) {
+			continue
+		}
+
+		if !slices.ContainsFunc(d.erase, func(root string) bool { return bundleApi.RootPathsOverlap(root, child) }) {
+			return true
+		}
+
+		if d.nonEmpty(append(slices.Clip(path), name), v) {
+			return true
+		}
+	}
+	return false
+}
+
+// owner returns the name of the active bundle with the data at the path,
+// or an empty string if the data belongs to no bundle.
+func (d *storeData) owner(path string) string {
+	for name, roots := range d.roots {
+		if bundleApi.RootPathsContain(roots, path) {
+			return name
+		}
+	}
+	return ""
+}
+
+// bundleData is a data document of a bundle, at its directory.
+type bundleData struct {
+	source string
+	dir    []string
+	value  bjson.Json
+}
+
+func readBundleData(name string, b *bundleApi.Bundle) []bundleData {
+	if len(b.Raw) == 0 {
+		if len(b.Data) == 0 {
+			return nil
+		}
+
+		value, err := bjson.New(b.Data)
+		if err != nil {
+			return nil
+		}
+		return []bundleData{{source: name, value: value}}
+	}
+
+	var data []bundleData
+	for _, item := range b.Raw {
+		path := filepath.ToSlash(item.Path)
+		if filepath.Base(path) != "data.json" {
+			continue
+		}
+
+		value, err := BjsonFromBinary(item.Value)
+		if err != nil {
+			continue
+		}
+
+		var dir []string
+		if d := strings.TrimLeft(filepath.Dir(strings.Trim(path, "/")), "/."); d != "" {
+			dir = strings.Split(d, "/")
+		}
+		data = append(data, bundleData{source: modulePathWithPrefix(name, path), dir: dir, value: value})
+	}
+	return data
+}
+
+// conflict returns the path of the data conflicting with a rule at the
+// path: non-empty data at the path or under it, or a value other than
+// an object above it.
+func (d bundleData) conflict(path []string) (string, bool) {
+	n := min(len(path), len(d.dir))
+	if !slices.Equal(path[:n], d.dir[:n]) {
+		return "", false
+	}
+
+	if len(path) <= len(d.dir) {
+		return strings.Join(d.dir, "/"), nonEmpty(d.value)
+	}
+
+	value := d.value
+	for i := len(d.dir); i < len(path); i++ {
+		obj, ok := value.(bjson.Object)
+		if !ok {
+			return strings.Join(path[:i], "/"), true
+		}
+
+		if value = obj.Value(path[i]); value == nil {
+			return "", false
+		}
+	}
+
+	return strings.Join(path, "/"), nonEmpty(value)
+}
+
+// nonEmpty is like storage.NonEmpty: empty objects are not data.
+func nonEmpty(value bjson.Json) bool {
+	obj, ok := value.(bjson.Object)
+	return !ok || obj.Len() > 0
+}
+
+// rulePath returns the ground prefix of the rule path, without data.
+func rulePath(rule *ast.Rule) []string {
+	ref := rule.Path().GroundPrefix()
+	path := make([]string, 0, len(ref))
+	for _, term := range ref[1:] {
+		s, ok := term.Value.(ast.String)
+		if !ok {
+			break
+		}
+		path = append(path, string(s))
+	}
+	return path
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+func TestDetectAllConflicts(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.NewFromObject(map[string]any{
+		"system": map[string]any{
+			"bundles": map[string]any{
+				"active": map[string]any{"manifest": map[string]any{"roots": []any{"x/y", "e"}}},
+			},
+		},
+		"a": map[string]any{"empty": map[string]any{"v": 1}}, // Erased by the activation of a.
+		"d": map[string]any{"q": 1},
+		"e": map[string]any{"f": 1},
+	})
+
+	// The policies in the store conflict with the data of the bundles,
+	// unless the activation erases them.
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	for id, policy := range map[string]string{
+		"manual.rego":       "package z\nw.v := 1",
+		"a/old.rego":        "package a\nold := 1",
+		"active/x/y/z.rego": "package x.y\nz := 1",
+	} {
+		if err := store.UpsertPolicy(ctx, txn, id, []byte(policy)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	policy := `package a
+allow := true
+deny contains "x"
+deny contains "y"
+leaf.rule := 1
+empty := 1`
+
+	aRoots, bRoots := []string{"a", "x"}, []string{"a/b", "c", "z/w"}
+	bundles := map[string]*bundleApi.Bundle{
+		"a": {
+			Manifest: bundleApi.Manifest{Roots: &aRoots},
+			Modules: []bundleApi.ModuleFile{
+				{Path: "/a/policy.rego", Raw: []byte(policy), Parsed: ast.MustParseModule(policy)},
+			},
+			Raw: []bundleApi.Raw{
+				{Path: "/a/data.json", Value: []byte(`{"allow": false, "leaf": 1, "empty": {}, "old": 1}`)},
+				{Path: "/a/deny/data.json", Value: []byte(`{"z": 1}`)},
+			},
+		},
+		"b": {
+			Manifest: bundleApi.Manifest{Roots: &bRoots},
+			Data:     map[string]any{"c": 1, "z": map[string]any{"w": map[string]any{"v": 1}}},
+		},
+	}
+
+	conflicts := DetectAllConflicts(&bundleApi.ActivateOpts{
+		Ctx:     ctx,
+		Store:   store,
+		Txn:     txn,
+		Bundles: bundles,
+		ExtraModules: map[string]*ast.Module{
+			"extra.rego":   ast.MustParseModule("package c\np := 1"),
+			"extra/d.rego": ast.MustParseModule("package d\nq := 1"),
+			"extra/e.rego": ast.MustParseModule("package e\nf := 1"),
+		},
+	})
+
+	expected := []ConflictInfo{
+		{Kind: ConflictData, Path: "a/allow", Source: "a/a/policy.rego", ConflictingPath: "a/allow", ConflictingSource: "a/a/data.json"},
+		{Kind: ConflictData, Path: "a/deny", Source: "a/a/policy.rego", ConflictingPath: "a/deny", ConflictingSource: "a/a/deny/data.json"},
+		{Kind: ConflictData, Path: "a/leaf/rule", Source: "a/a/policy.rego", ConflictingPath: "a/leaf", ConflictingSource: "a/a/data.json"},
+		{Kind: ConflictData, Path: "c/p", Source: "extra.rego", ConflictingPath: "c", ConflictingSource: "b"},
+		{Kind: ConflictData, Path: "d/q", Source: "extra/d.rego", ConflictingPath: "d/q", ConflictingSource: ""},
+		{Kind: ConflictData, Path: "e/f", Source: "extra/e.rego", ConflictingPath: "e/f", ConflictingSource: "active"},
+		{Kind: ConflictData, Path: "z/w/v", Source: "manual.rego", ConflictingPath: "z/w/v", ConflictingSource: "b"},
+		{Kind: ConflictRoots, Path: "a", Source: "a", ConflictingPath: "a/b", ConflictingSource: "b"},
+		{Kind: ConflictRoots, Path: "x", Source: "a", ConflictingPath: "x/y", ConflictingSource: "active"},
+	}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Fatalf("expected %v, got %v", expected, conflicts)
+	}
+}
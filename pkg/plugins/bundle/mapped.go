@@ -0,0 +1,157 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/metrics"
+	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/storage"
+
+	bjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+// MappedBundlesPluginName is the name MappedBundlesPlugin is registered
+// under with the plugin manager.
+const MappedBundlesPluginName = "eopa_mapped_bundles"
+
+// NewMappedDataBundle returns a bundle of the data file at the path, in
+// EOPA binary form, memory mapped read-only instead of read. Once the
+// bundle is activated, the store reads the data out of the mapping
+// lazily, leaving the OS page cache to hold the pages evaluations touch,
+// which suits read-mostly data sets larger than memory. The manifest
+// roots the data, like the manifest of any bundle.
+//
+// Closing the closer returned unmaps the data: the store, its open
+// transactions and any snapshot retained by the activator must not hold
+// it anymore, which in practice means the store is closed. See
+// MappedBundlesPlugin. On platforms without memory mapping, the file is
+// read instead.
+func NewMappedDataBundle(path string, manifest bundleApi.Manifest) (*bundleApi.Bundle, io.Closer, error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !bjson.IsBJson(data) {
+		_ = unmap()
+		return nil, nil, fmt.Errorf("%s: data not in EOPA binary form", path)
+	}
+
+	manifest.Init()
+
+	return &bundleApi.Bundle{
+		Manifest: manifest,
+		Raw:      []bundleApi.Raw{{Path: "/data.json", Value: data}},
+	}, closerFunc(unmap), nil
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+// MappedBundlesPlugin returns the plugin activating the bundles under
+// "bundle_activation/mapped_bundles" in the store of the manager when it
+// starts. The configuration is read then: later changes to the mapped
+// bundles only apply on restart.
+//
+// The data stays mapped until the plugin stops. The manager stops its
+// plugins in the order they were registered, and closes the store right
+// after: registered after the others, the plugin is stopped last, when
+// nothing evaluates against the store anymore.
+func (a *CustomActivator) MappedBundlesPlugin(m *plugins.Manager) plugins.Plugin {
+	return &mappedBundles{activator: a, manager: m}
+}
+
+type mappedBundles struct {
+	activator *CustomActivator
+	manager   *plugins.Manager
+
+	mtx     sync.Mutex
+	closers []io.Closer
+}
+
+func (p *mappedBundles) Start(ctx context.Context) error {
+	p.activator.mtx.Lock()
+	configs := p.activator.mapped
+	p.activator.mtx.Unlock()
+
+	if len(configs) == 0 {
+		p.manager.UpdatePluginStatus(MappedBundlesPluginName, &plugins.Status{State: plugins.StateOK})
+		return nil
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	bundles := make(map[string]*bundleApi.Bundle, len(configs))
+	for _, name := range slices.Sorted(maps.Keys(configs)) {
+		c := configs[name]
+		b, closer, err := NewMappedDataBundle(c.FilePath, bundleApi.Manifest{Revision: c.Revision, Roots: &c.Roots})
+		if err != nil {
+			p.close()
+			return fmt.Errorf("mapped bundle %s: %w", name, err)
+		}
+		bundles[name] = b
+		p.closers = append(p.closers, closer)
+	}
+
+	params := storage.WriteParams
+	params.Context = storage.NewContext()
+
+	if err := storage.Txn(ctx, p.manager.Store, params, func(txn storage.Transaction) error {
+		compiler := ast.NewCompiler().
+			WithPathConflictsCheck(storage.NonEmpty(ctx, p.manager.Store, txn)).
+			WithEnablePrintStatements(p.manager.EnablePrintStatements())
+
+		err := p.activator.Activate(&bundleApi.ActivateOpts{
+			Ctx:           ctx,
+			Store:         p.manager.Store,
+			Txn:           txn,
+			TxnCtx:        params.Context,
+			Compiler:      compiler,
+			Metrics:       metrics.New(),
+			Bundles:       bundles,
+			ParserOptions: p.manager.ParserOptions(),
+		})
+
+		plugins.SetCompilerOnContext(params.Context, compiler)
+		return err
+	}); err != nil {
+		p.close()
+		return err
+	}
+
+	p.manager.UpdatePluginStatus(MappedBundlesPluginName, &plugins.Status{State: plugins.StateOK})
+	return nil
+}
+
+func (p *mappedBundles) Stop(context.Context) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.close()
+	p.manager.UpdatePluginStatus(MappedBundlesPluginName, &plugins.Status{State: plugins.StateNotReady})
+}
+
+// Reconfigure does nothing: the data being possibly in use, it is only
+// unmapped by Stop.
+func (*mappedBundles) Reconfigure(context.Context, any) {}
+
+func (p *mappedBundles) close() {
+	for _, c := range p.closers {
+		_ = c.Close()
+	}
+	p.closers = nil
+}
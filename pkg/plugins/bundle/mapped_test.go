@@ -0,0 +1,81 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/metrics"
+	"github.com/open-policy-agent/opa/v1/storage"
+
+	bjson "github.com/open-policy-agent/eopa/pkg/json"
+	"github.com/open-policy-agent/eopa/pkg/plugins/bundle"
+	"github.com/open-policy-agent/eopa/pkg/storage/inmem"
+)
+
+func TestMappedDataBundle(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	bs, err := bjson.Marshal(bjson.MustNew(map[string]any{
+		"ref": map[string]any{"users": []any{map[string]any{"name": "a"}, map[string]any{"name": "b"}}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "data.bjson")
+	if err := os.WriteFile(path, bs, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b, closer, err := bundle.NewMappedDataBundle(path, bundleApi.Manifest{Revision: "r", Roots: &[]string{"ref"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+
+	store := inmem.New()
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	if err := (&bundle.CustomActivator{}).Activate(&bundleApi.ActivateOpts{
+		Ctx:      ctx,
+		Store:    store,
+		Txn:      txn,
+		Compiler: ast.NewCompiler(),
+		Metrics:  metrics.New(),
+		Bundles:  map[string]*bundleApi.Bundle{"mapped": b},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	value, err := store.Read(ctx, txn, storage.MustParsePath("/ref/users/1/name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "b" {
+		t.Fatalf("expected b, got %v", value)
+	}
+
+	if _, _, err := bundle.NewMappedDataBundle(filepath.Join(dir, "missing"), bundleApi.Manifest{}); err == nil {
+		t.Fatal("expected missing file error")
+	}
+
+	path = filepath.Join(dir, "data.json")
+	if err := os.WriteFile(path, []byte(`{"ref": {}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := bundle.NewMappedDataBundle(path, bundleApi.Manifest{}); err == nil {
+		t.Fatal("expected JSON data to be rejected")
+	}
+}
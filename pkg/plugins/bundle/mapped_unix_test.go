@@ -0,0 +1,98 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unix
+
+package bundle_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/storage"
+
+	bjson "github.com/open-policy-agent/eopa/pkg/json"
+	"github.com/open-policy-agent/eopa/pkg/plugins/bundle"
+	"github.com/open-policy-agent/eopa/pkg/storage/inmem"
+)
+
+func TestMappedBundlesPlugin(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// The heap must not grow with the size of the mapped data: only the
+	// pages read are loaded, and they are not on the heap.
+	var growth []uint64
+	for _, n := range []int{1_000, 100_000} {
+		path := filepath.Join(dir, fmt.Sprintf("data-%d.bjson", n))
+		writeMappedData(t, path, n)
+
+		store := inmem.New()
+		m, err := plugins.New([]byte(fmt.Sprintf(`{"bundle_activation": {"mapped_bundles": {"ref": {"file_path": %q, "roots": ["ref"], "revision": "r"}}}}`, path)), "test", store)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		activator := &bundle.CustomActivator{}
+		if _, err := activator.OnConfig(ctx, m.Config); err != nil {
+			t.Fatal(err)
+		}
+		p := activator.MappedBundlesPlugin(m)
+
+		before := heapAlloc()
+		if err := p.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		after := heapAlloc()
+		growth = append(growth, max(after, before)-before)
+
+		txn := storage.NewTransactionOrDie(ctx, store)
+		value, err := store.Read(ctx, txn, storage.MustParsePath(fmt.Sprintf("/ref/users/%d/name", n-1)))
+		store.Abort(ctx, txn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != fmt.Sprintf("user%d", n-1) {
+			t.Fatalf("unexpected value %v", value)
+		}
+
+		if status := m.PluginStatus()[bundle.MappedBundlesPluginName]; status == nil || status.State != plugins.StateOK {
+			t.Fatalf("unexpected status %v", status)
+		}
+
+		p.Stop(ctx)
+	}
+
+	const slack = 1 << 20
+	if growth[1] > growth[0]+slack {
+		t.Fatalf("heap grew with the data size: %d bytes for 1k users, %d bytes for 100k users", growth[0], growth[1])
+	}
+}
+
+func writeMappedData(t *testing.T, path string, n int) {
+	t.Helper()
+
+	users := make([]any, n)
+	for i := range users {
+		users[i] = map[string]any{"name": fmt.Sprintf("user%d", i), "roles": []any{"reader", "writer"}}
+	}
+	bs, err := bjson.Marshal(bjson.MustNew(map[string]any{"ref": map[string]any{"users": users}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, bs, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func heapAlloc() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
@@ -0,0 +1,24 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !unix
+
+package bundle
+
+import (
+	"fmt"
+	"os"
+)
+
+// mapFile reads the file, as memory mapping is not supported.
+func mapFile(path string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("%s: empty file", path)
+	}
+
+	return data, func() error { return nil }, nil
+}
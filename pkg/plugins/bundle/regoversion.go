@@ -0,0 +1,92 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/gobwas/glob"
+
+	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
+)
+
+// MinRegoVersion and MaxRegoVersion bound the rego versions, as numbered
+// in the bundle manifests, of the modules the runtime can activate.
+const (
+	MinRegoVersion = 0
+	MaxRegoVersion = 1
+)
+
+// RegoVersionError is the error of activating a bundle with a module
+// declared of a rego version out of the supported range.
+type RegoVersionError struct {
+	Bundle  string
+	Module  string
+	Version int // The version the manifest declares for the module.
+	Min     int
+	Max     int
+}
+
+func (e *RegoVersionError) Error() string {
+	return fmt.Sprintf("bundle '%s': module '%s' declares rego version %d, supported rego versions are %d to %d",
+		e.Bundle, e.Module, e.Version, e.Min, e.Max)
+}
+
+// checkRegoVersions returns a *RegoVersionError for the first module of
+// the bundles, in order of their names and paths, declared of a rego
+// version the runtime does not support. The versions of the modules
+// without a declared version are inferred at parsing, by the runtime,
+// and always supported.
+func checkRegoVersions(bundles map[string]*bundleApi.Bundle) error {
+	for _, name := range slices.Sorted(maps.Keys(bundles)) {
+		b := bundles[name]
+		if b.Manifest.RegoVersion == nil && len(b.Manifest.FileRegoVersions) == 0 {
+			continue
+		}
+
+		modules := slices.Clone(b.Modules)
+		slices.SortFunc(modules, func(a, b bundleApi.ModuleFile) int {
+			return cmp.Compare(a.Path, b.Path)
+		})
+
+		for _, mf := range modules {
+			version, err := declaredRegoVersion(b.Manifest, mf.Path)
+			if err != nil {
+				return fmt.Errorf("failed to get rego version for module '%s' in bundle '%s': %w", mf.Path, name, err)
+			}
+
+			if version != nil && (*version < MinRegoVersion || *version > MaxRegoVersion) {
+				return &RegoVersionError{
+					Bundle:  name,
+					Module:  mf.Path,
+					Version: *version,
+					Min:     MinRegoVersion,
+					Max:     MaxRegoVersion,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// declaredRegoVersion returns the rego version the manifest declares for
+// the module file, if any, as numbered in the manifest: the manifest
+// accessors map the versions they don't know to the supported ones.
+func declaredRegoVersion(m bundleApi.Manifest, path string) (*int, error) {
+	for pattern, v := range m.FileRegoVersions {
+		g, err := glob.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile glob pattern %s: %w", pattern, err)
+		}
+		if g.Match(path) {
+			return &v, nil
+		}
+	}
+
+	return m.RegoVersion, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/metrics"
+	"github.com/open-policy-agent/opa/v1/storage"
+
+	"github.com/open-policy-agent/eopa/pkg/plugins/bundle"
+	"github.com/open-policy-agent/eopa/pkg/storage/inmem"
+)
+
+func TestActivateRegoVersion(t *testing.T) {
+	v := func(i int) *int { return &i }
+
+	tests := []struct {
+		note     string
+		version  *int
+		files    map[string]int
+		expected *bundle.RegoVersionError
+	}{
+		{
+			note: "undeclared",
+		},
+		{
+			note:    "supported",
+			version: v(1),
+			files:   map[string]int{"/a/*.rego": 0},
+		},
+		{
+			note:     "unsupported bundle version",
+			version:  v(2),
+			expected: &bundle.RegoVersionError{Bundle: "b", Module: "/a/a.rego", Version: 2, Min: 0, Max: 1},
+		},
+		{
+			note:     "unsupported file version",
+			version:  v(1),
+			files:    map[string]int{"/b/*.rego": 3},
+			expected: &bundle.RegoVersionError{Bundle: "b", Module: "/b/b.rego", Version: 3, Min: 0, Max: 1},
+		},
+		{
+			note:    "unsupported bundle version overridden",
+			version: v(2),
+			files:   map[string]int{"/**.rego": 1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			ctx := context.Background()
+
+			var modules []bundleApi.ModuleFile
+			for _, path := range []string{"/a/a.rego", "/b/b.rego"} {
+				raw := "package " + path[1:2] + "\n\np := true"
+				modules = append(modules, bundleApi.ModuleFile{
+					Path:   path,
+					Raw:    []byte(raw),
+					Parsed: ast.MustParseModule(raw),
+				})
+			}
+
+			b := &bundleApi.Bundle{
+				Manifest: bundleApi.Manifest{
+					Roots:            &[]string{"a", "b"},
+					RegoVersion:      tc.version,
+					FileRegoVersions: tc.files,
+				},
+				Data:    map[string]any{},
+				Modules: modules,
+			}
+
+			store := inmem.New()
+			txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+			defer store.Abort(ctx, txn)
+
+			err := (&bundle.CustomActivator{}).Activate(&bundleApi.ActivateOpts{
+				Ctx:      ctx,
+				Store:    store,
+				Txn:      txn,
+				Compiler: ast.NewCompiler(),
+				Metrics:  metrics.New(),
+				Bundles:  map[string]*bundleApi.Bundle{"b": b},
+			})
+
+			if tc.expected == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var versionErr *bundle.RegoVersionError
+			if !errors.As(err, &versionErr) {
+				t.Fatalf("expected rego version error, got %v", err)
+			}
+			if *versionErr != *tc.expected {
+				t.Fatalf("expected %+v, got %+v", tc.expected, versionErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/storage"
+
+	bjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+// ErrSnapshotNotFound is returned by RollbackBundle if no snapshot of the
+// bundle revision is retained.
+var ErrSnapshotNotFound = errors.New("bundle snapshot not found")
+
+// Resource names and meta keys of the retained snapshots. The data and the
+// policies are stored by index, their roots and ids being meta values.
+const (
+	snapshotManifest    = "manifest"
+	snapshotEtag        = "etag"
+	snapshotData        = "data"
+	snapshotPolicies    = "policies"
+	snapshotMetaRoot    = "root"
+	snapshotMetaID      = "id"
+	snapshotMetaVersion = "rego_version"
+)
+
+// retainedSnapshot is the state of a bundle, as it was before being
+// replaced by an activation.
+type retainedSnapshot struct {
+	revision string
+	snapshot bjson.Collections
+}
+
+// retainedSnapshots are the snapshots of the bundles activated in a
+// store, by bundle name.
+type retainedSnapshots struct {
+	mtx      sync.Mutex
+	store    storage.Store
+	byBundle map[string][]retainedSnapshot
+}
+
+// SetRetainedSnapshots sets the number of prior states of each bundle to
+// retain in memory when activating its new revisions, for RollbackBundle
+// to restore. Without retained snapshots, the default, none are captured,
+// and those retained already are dropped on the next activation.
+func (a *CustomActivator) SetRetainedSnapshots(n int) {
+	a.retain.Store(int64(n))
+}
+
+// RetainedRevisions returns the revisions of the bundle retained for
+// rollback, from the oldest to the latest.
+func (a *CustomActivator) RetainedRevisions(name string) []string {
+	a.retained.mtx.Lock()
+	defer a.retained.mtx.Unlock()
+
+	var revisions []string
+	for _, s := range a.retained.byBundle[name] {
+		revisions = append(revisions, s.revision)
+	}
+	return revisions
+}
+
+// captureSnapshots captures the state of the snapshot bundles activated
+// already, before their activation replaces it.
+func (a *CustomActivator) captureSnapshots(opts *bundleApi.ActivateOpts, retain int) (map[string]retainedSnapshot, error) {
+	if retain < 1 {
+		a.retained.mtx.Lock()
+		a.retained.byBundle = nil
+		a.retained.mtx.Unlock()
+		return nil, nil
+	}
+
+	snapshots := map[string]retainedSnapshot{}
+	for name, b := range opts.Bundles {
+		if b.Type() == bundleApi.DeltaBundleType {
+			continue
+		}
+
+		s, ok, err := captureSnapshot(opts.Ctx, opts.Store, opts.Txn, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture bundle %s snapshot: %w", name, err)
+		}
+		if ok {
+			snapshots[name] = s
+		}
+	}
+	return snapshots, nil
+}
+
+// retainSnapshot records the snapshot of the bundle, keeping the last n
+// snapshots. A snapshot of the same revision is replaced. The snapshots
+// retained from another store are dropped.
+func (a *CustomActivator) retainSnapshot(store storage.Store, name string, s retainedSnapshot, n int) {
+	a.retained.mtx.Lock()
+	defer a.retained.mtx.Unlock()
+
+	if a.retained.store != store || a.retained.byBundle == nil {
+		a.retained.store = store
+		a.retained.byBundle = map[string][]retainedSnapshot{}
+	}
+
+	snapshots := slices.DeleteFunc(a.retained.byBundle[name], func(r retainedSnapshot) bool {
+		return r.revision == s.revision
+	})
+	snapshots = append(snapshots, s)
+	if len(snapshots) > n {
+		snapshots = slices.Delete(snapshots, 0, len(snapshots)-n)
+	}
+	a.retained.byBundle[name] = snapshots
+}
+
+func (a *CustomActivator) findSnapshot(store storage.Store, name, revision string) (bjson.Collections, bool) {
+	a.retained.mtx.Lock()
+	defer a.retained.mtx.Unlock()
+
+	if a.retained.store != store {
+		return nil, false
+	}
+	for _, s := range a.retained.byBundle[name] {
+		if s.revision == revision {
+			return s.snapshot, true
+		}
+	}
+	return nil, false
+}
+
+// captureSnapshot captures the activated state of the bundle: its
+// manifest, etag, the data under its roots and the policies, with their
+// Rego versions. It returns false if the bundle is not activated.
+func captureSnapshot(ctx context.Context, store storage.Store, txn storage.Transaction, name string) (retainedSnapshot, bool, error) {
+	manifest, err := store.Read(ctx, txn, ManifestStoragePath(name))
+	if storage.IsNotFound(err) {
+		return retainedSnapshot{}, false, nil
+	} else if err != nil {
+		return retainedSnapshot{}, false, err
+	}
+
+	revision, err := ReadBundleRevisionFromStore(ctx, store, txn, name)
+	if suppressNotFound(err) != nil {
+		return retainedSnapshot{}, false, err
+	}
+
+	roots, err := ReadBundleRootsFromStore(ctx, store, txn, name)
+	if suppressNotFound(err) != nil {
+		return retainedSnapshot{}, false, err
+	}
+
+	w := bjson.NewCollections()
+
+	m, err := bjson.New(manifest)
+	if err != nil {
+		return retainedSnapshot{}, false, err
+	}
+	w.WriteJSON(snapshotManifest, m)
+
+	if etag, err := ReadBundleEtagFromStore(ctx, store, txn, name); err == nil {
+		w.WriteJSON(snapshotEtag, bjson.NewString(etag))
+	} else if suppressNotFound(err) != nil {
+		return retainedSnapshot{}, false, err
+	}
+
+	for i, root := range roots {
+		path, ok := storage.ParsePathEscaped("/" + root)
+		if !ok {
+			return retainedSnapshot{}, false, fmt.Errorf("manifest root path invalid: %v", root)
+		}
+		if len(path) == 0 {
+			continue // Like eraseData, the data root is not replaced.
+		}
+
+		value, err := readBJSON(ctx, store, txn, path)
+		if storage.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return retainedSnapshot{}, false, err
+		}
+
+		resource := snapshotData + "/" + strconv.Itoa(i)
+		w.WriteJSON(resource, value)
+		w.WriteMeta(resource, snapshotMetaRoot, root)
+	}
+
+	ids, err := store.ListPolicies(ctx, txn)
+	if err != nil {
+		return retainedSnapshot{}, false, err
+	}
+
+	modulesInfo, err := readModuleInfoFromStore(ctx, store, txn)
+	if err != nil {
+		return retainedSnapshot{}, false, fmt.Errorf("failed to read module info from store: %w", err)
+	}
+
+	// The policies of the bundle are stored under its name: no need to
+	// parse them to find those under its roots.
+	prefix := modulePathWithPrefix(name, "") + "/"
+
+	for i, id := range ids {
+		if !strings.HasPrefix(id, prefix) {
+			continue
+		}
+
+		bs, err := store.GetPolicy(ctx, txn, id)
+		if err != nil {
+			return retainedSnapshot{}, false, err
+		}
+
+		info, hasInfo := modulesInfo[id]
+
+		resource := snapshotPolicies + "/" + strconv.Itoa(i)
+		w.WriteBlob(resource, bjson.NewBlob(bs))
+		w.WriteMeta(resource, snapshotMetaID, id)
+		if hasInfo {
+			w.WriteMeta(resource, snapshotMetaVersion, strconv.Itoa(info.RegoVersion.Int()))
+		}
+	}
+
+	return retainedSnapshot{revision: revision, snapshot: w.Prepare(time.Now())}, true, nil
+}
+
+// RollbackBundle restores the retained snapshot of the revision of the
+// bundle, replacing its current data, policies and manifest. Like an
+// activation, the policies restored take effect once the transaction is
+// committed, and the compiler updated from the store. Wasm modules are not
+// retained, and not restored. Rolling back fails if the restored roots
+// overlap with the roots of another bundle activated since.
+func (a *CustomActivator) RollbackBundle(ctx context.Context, store storage.Store, txn storage.Transaction, name, toRevision string) error {
+	snapshot, ok := a.findSnapshot(store, name, toRevision)
+	if !ok {
+		return fmt.Errorf("%w: %s revision %q", ErrSnapshotNotFound, name, toRevision)
+	}
+
+	var manifest bundleApi.Manifest
+	if err := bjson.Unmarshal(snapshot.Resource(snapshotManifest).JSON(), &manifest); err != nil {
+		return err
+	}
+	manifest.Init()
+
+	// Other bundles may have taken over the restored roots since.
+	if err := hasRootsOverlap(ctx, store, txn, map[string]*bundleApi.Bundle{name: {Manifest: manifest}}); err != nil {
+		return err
+	}
+
+	// Erase the current state of the bundle, at both its current and
+	// restored roots.
+	roots, err := ReadBundleRootsFromStore(ctx, store, txn, name)
+	if suppressNotFound(err) != nil {
+		return err
+	}
+
+	erase := map[string]struct{}{}
+	for _, root := range append(roots, *manifest.Roots...) {
+		erase[root] = struct{}{}
+	}
+
+	if _, err := eraseBundles(ctx, store, txn, ast.ParserOptions{}, map[string]struct{}{name: {}}, erase); err != nil {
+		return err
+	}
+
+	for _, r := range snapshotResources(snapshot, snapshotData) {
+		root, _ := r.Meta(snapshotMetaRoot)
+		path, ok := storage.ParsePathEscaped("/" + root)
+		if !ok {
+			return fmt.Errorf("manifest root path invalid: %v", root)
+		}
+
+		if err := storage.MakeDir(ctx, store, txn, path[:len(path)-1]); err != nil {
+			return err
+		}
+		if err := store.Write(ctx, txn, storage.AddOp, path, r.JSON()); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range snapshotResources(snapshot, snapshotPolicies) {
+		id, _ := r.Meta(snapshotMetaID)
+		if err := store.UpsertPolicy(ctx, txn, id, r.Blob().Value()); err != nil {
+			return err
+		}
+
+		if v, ok := r.Meta(snapshotMetaVersion); ok {
+			version, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("corrupt rego version")
+			}
+			if err := write(ctx, store, txn, moduleRegoVersionPath(id), bjson.NewFloatInt(int64(version))); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := WriteManifestToStore(ctx, store, txn, name, manifest); err != nil {
+		return err
+	}
+
+	if r := snapshot.Resource(snapshotEtag); r != nil {
+		if err := WriteEtagToStore(ctx, store, txn, name, r.JSON().(*bjson.String).Value()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// snapshotResources returns the resources of the snapshot directory, if
+// any was captured.
+func snapshotResources(snapshot bjson.Collections, dir string) []bjson.Resource {
+	r := snapshot.Resource(dir)
+	if r == nil {
+		return nil
+	}
+	return r.Resources()
+}
+
+func readBJSON(ctx context.Context, store storage.Store, txn storage.Transaction, path storage.Path) (bjson.Json, error) {
+	if r, ok := store.(interface {
+		ReadBJSON(context.Context, storage.Transaction, storage.Path) (bjson.Json, error)
+	}); ok {
+		return r.ReadBJSON(ctx, txn, path)
+	}
+
+	value, err := store.Read(ctx, txn, path)
+	if err != nil {
+		return nil, err
+	}
+	return bjson.New(value)
+}
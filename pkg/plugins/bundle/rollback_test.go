@@ -0,0 +1,160 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	bundleApi "github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/metrics"
+	"github.com/open-policy-agent/opa/v1/storage"
+
+	"github.com/open-policy-agent/eopa/pkg/plugins/bundle"
+	"github.com/open-policy-agent/eopa/pkg/storage/inmem"
+)
+
+func TestRollbackBundle(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+
+	activator := &bundle.CustomActivator{}
+	activator.SetRetainedSnapshots(2)
+
+	activate := func(revision string, value int, module string) {
+		t.Helper()
+
+		roots := []string{"a"}
+		b := &bundleApi.Bundle{
+			Manifest: bundleApi.Manifest{Revision: revision, Roots: &roots},
+			Data:     map[string]any{"a": map[string]any{"x": value}},
+			Modules: []bundleApi.ModuleFile{
+				{Path: "/a/policy.rego", Raw: []byte(module), Parsed: ast.MustParseModule(module)},
+			},
+			Etag: "etag-" + revision,
+		}
+
+		txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+		if err := activator.Activate(&bundleApi.ActivateOpts{
+			Ctx:      ctx,
+			Store:    store,
+			Txn:      txn,
+			Compiler: ast.NewCompiler(),
+			Metrics:  metrics.New(),
+			Bundles:  map[string]*bundleApi.Bundle{"test": b},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Commit(ctx, txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	activate("v1", 1, "package a\n\np := 1")
+	activate("v2", 2, "package a\n\np := 2")
+	activate("v3", 3, "package a\n\np := 3")
+	activate("v4", 4, "package a\n\np := 4")
+
+	if revisions := activator.RetainedRevisions("test"); !slices.Equal(revisions, []string{"v2", "v3"}) {
+		t.Fatalf("expected revisions [v2 v3], got %v", revisions)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	if err := activator.RollbackBundle(ctx, store, txn, "test", "v1"); !errors.Is(err, bundle.ErrSnapshotNotFound) {
+		t.Fatalf("expected snapshot not found, got %v", err)
+	}
+	if err := activator.RollbackBundle(ctx, store, txn, "test", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	revision, err := bundle.ReadBundleRevisionFromStore(ctx, store, txn, "test")
+	if err != nil || revision != "v2" {
+		t.Fatalf("expected revision v2, got %q (%v)", revision, err)
+	}
+
+	etag, err := bundle.ReadBundleEtagFromStore(ctx, store, txn, "test")
+	if err != nil || etag != "etag-v2" {
+		t.Fatalf("expected etag etag-v2, got %q (%v)", etag, err)
+	}
+
+	value, err := store.Read(ctx, txn, storage.MustParsePath("/a/x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ast.MustInterfaceToValue(value).Compare(ast.Number("2")) != 0 {
+		t.Fatalf("expected data 2, got %v", value)
+	}
+
+	policy, err := store.GetPolicy(ctx, txn, "test/a/policy.rego")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(policy) != "package a\n\np := 2" {
+		t.Fatalf("unexpected policy %q", policy)
+	}
+}
+
+func TestRollbackBundleRootsTaken(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+
+	activator := &bundle.CustomActivator{}
+	activator.SetRetainedSnapshots(2)
+
+	activate := func(name, revision, root string) {
+		t.Helper()
+
+		roots := []string{root}
+		b := &bundleApi.Bundle{
+			Manifest: bundleApi.Manifest{Revision: revision, Roots: &roots},
+			Data:     map[string]any{root: map[string]any{"owner": name}},
+		}
+
+		txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+		if err := activator.Activate(&bundleApi.ActivateOpts{
+			Ctx:      ctx,
+			Store:    store,
+			Txn:      txn,
+			Compiler: ast.NewCompiler(),
+			Metrics:  metrics.New(),
+			Bundles:  map[string]*bundleApi.Bundle{name: b},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Commit(ctx, txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The bundle moves from root a to root b, and another bundle takes
+	// over root a.
+	activate("test", "v1", "a")
+	activate("test", "v2", "b")
+	activate("other", "v1", "a")
+
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	defer store.Abort(ctx, txn)
+
+	if err := activator.RollbackBundle(ctx, store, txn, "test", "v1"); err == nil || !strings.Contains(err.Error(), "overlapping roots") {
+		t.Fatalf("expected overlapping roots, got %v", err)
+	}
+
+	value, err := store.Read(ctx, txn, storage.MustParsePath("/a/owner"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ast.MustInterfaceToValue(value).Compare(ast.String("other")) != 0 {
+		t.Fatalf("expected the data of the other bundle, got %v", value)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package decisionlogs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/plugins/logs"
+	"github.com/open-policy-agent/opa/v1/rego"
+
+	bjson "github.com/open-policy-agent/eopa/pkg/json"
+	"github.com/open-policy-agent/eopa/pkg/rego_vm"
+	"github.com/open-policy-agent/eopa/pkg/storage"
+)
+
+// ReplayKey is the key of the replay record in the custom fields of the
+// decision log events.
+const ReplayKey = "replay"
+
+// ReplayRecord holds the input and the data a decision was evaluated
+// with, serialized in the binary form, for replaying the decision. The
+// data digest identifies the data, for the replay to verify it replays
+// against the data recorded.
+type ReplayRecord struct {
+	Input      []byte `json:"input,omitempty"`
+	Data       []byte `json:"data"`
+	DataDigest string `json:"data_digest"`
+}
+
+// NewReplayRecord serializes the input, nil if the decision had none, and
+// the data object.
+func NewReplayRecord(input, data bjson.Json) (*ReplayRecord, error) {
+	if _, ok := data.(bjson.Object); !ok {
+		return nil, errors.New("replay data must be an object")
+	}
+
+	var r ReplayRecord
+	if input != nil {
+		bs, err := bjson.Marshal(input)
+		if err != nil {
+			return nil, fmt.Errorf("replay input: %w", err)
+		}
+		r.Input = bs
+	}
+
+	bs, err := bjson.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("replay data: %w", err)
+	}
+	r.Data = bs
+	r.DataDigest = dataDigest(bs)
+
+	return &r, nil
+}
+
+// AddReplayRecord records the input and the data with the event, under
+// the ReplayKey custom field.
+func AddReplayRecord(e *logs.EventV1, input, data bjson.Json) error {
+	r, err := NewReplayRecord(input, data)
+	if err != nil {
+		return err
+	}
+
+	if e.Custom == nil {
+		e.Custom = make(map[string]any, 1)
+	}
+	e.Custom[ReplayKey] = r
+	return nil
+}
+
+// ReadReplayRecord reads the replay record from the custom fields of a
+// logged event, as recorded by AddReplayRecord, or returns nil if there
+// is none.
+func ReadReplayRecord(custom map[string]any) (*ReplayRecord, error) {
+	v, ok := custom[ReplayKey]
+	if !ok {
+		return nil, nil
+	}
+	if r, ok := v.(*ReplayRecord); ok {
+		return r, nil
+	}
+
+	// Events read back from the sinks hold the record as decoded JSON.
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var r ReplayRecord
+	if err := json.Unmarshal(bs, &r); err != nil {
+		return nil, fmt.Errorf("replay record: %w", err)
+	}
+	return &r, nil
+}
+
+// Decode verifies the data against its digest and returns the input, nil
+// if none was recorded, and the data.
+func (r *ReplayRecord) Decode() (bjson.Json, bjson.Json, error) {
+	if digest := dataDigest(r.Data); digest != r.DataDigest {
+		return nil, nil, fmt.Errorf("replay data digest mismatch: expected %s, got %s", r.DataDigest, digest)
+	}
+
+	data, err := bjson.NewFromBinary(r.Data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("replay data: %w", err)
+	}
+
+	if r.Input == nil {
+		return nil, data, nil
+	}
+
+	input, err := bjson.NewFromBinary(r.Input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("replay input: %w", err)
+	}
+	return input, data, nil
+}
+
+// Options returns the options for replaying the decision through the VM:
+// the target, a store holding the data, and the input.
+func (r *ReplayRecord) Options() ([]func(*rego.Rego), error) {
+	input, data, err := r.Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []func(*rego.Rego){
+		rego.Target(rego_vm.Target),
+		rego.Store(storage.NewFromObject(data)),
+	}
+	if input != nil {
+		opts = append(opts, rego.Input(input))
+	}
+	return opts, nil
+}
+
+// Verify checks the data the decision is about to be replayed against
+// is the data recorded.
+func (r *ReplayRecord) Verify(data bjson.Json) error {
+	bs, err := bjson.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if digest := dataDigest(bs); digest != r.DataDigest {
+		return fmt.Errorf("replay data digest mismatch: expected %s, got %s", r.DataDigest, digest)
+	}
+	return nil
+}
+
+// dataDigest returns the SHA-256 digest of the serialized data.
+func dataDigest(bs []byte) string {
+	sum := sha256.Sum256(bs)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package decisionlogs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/plugins/logs"
+	"github.com/open-policy-agent/opa/v1/rego"
+
+	bjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+func TestReplayRecord(t *testing.T) {
+	ctx := context.Background()
+	input := bjson.MustNew(map[string]any{"n": 2})
+	data := bjson.MustNew(map[string]any{"limits": map[string]any{"max": 40}})
+
+	var e logs.EventV1
+	if err := AddReplayRecord(&e, input, data); err != nil {
+		t.Fatal(err)
+	}
+
+	// The record survives the event being logged as JSON and read back.
+	bs, err := json.Marshal(e.Custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var custom map[string]any
+	if err := json.Unmarshal(bs, &custom); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := ReadReplayRecord(custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Verify(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Verify(bjson.MustNew(map[string]any{})); err == nil {
+		t.Fatal("expected other data to fail verification")
+	}
+
+	opts, err := r.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := rego.New(append(opts, rego.Query("x := data.limits.max + input.n"))...).Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || fmt.Sprint(rs[0].Bindings["x"]) != "42" {
+		t.Fatalf("unexpected replay result %v", rs)
+	}
+
+	r.DataDigest = "sha256:00"
+	if _, _, err := r.Decode(); err == nil {
+		t.Fatal("expected digest mismatch")
+	}
+
+	if r, err := ReadReplayRecord(map[string]any{}); err != nil || r != nil {
+		t.Fatalf("expected no record, got %v, %v", r, err)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package rego_vm
+
+import (
+	"cmp"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExecutableInfo describes an executable compiled for evaluation by the
+// VM, and its evaluations so far.
+type ExecutableInfo struct {
+	// ID identifies the executable for the lifetime of the process.
+	ID uint64 `json:"id"`
+
+	// Query is the query the executable was last evaluated for, if any.
+	Query string `json:"query,omitempty"`
+
+	// Entrypoints are the plans of the executable.
+	Entrypoints []string `json:"entrypoints"`
+
+	// Size is the size of the executable, in bytes.
+	Size int `json:"size"`
+
+	// Builtins are the builtins the executable calls.
+	Builtins []string `json:"builtins"`
+
+	// CompiledAt is the time the executable was compiled, taking
+	// CompileTime to optimize and compile. An executable compiled by
+	// warming, and reused since, keeps the time of that compilation.
+	CompiledAt  time.Time     `json:"compiled_at"`
+	CompileTime time.Duration `json:"compile_time_ns"`
+
+	// Evaluations is the number of evaluations of the executable.
+	Evaluations uint64 `json:"evaluations"`
+}
+
+// executableStats are the statistics of a registered executable, updated
+// by its evaluations.
+type executableStats struct {
+	info        ExecutableInfo
+	query       atomic.Pointer[string]
+	evaluations atomic.Uint64
+}
+
+// executables are the executables prepared for evaluation, registered
+// until the evaluators holding them are garbage collected.
+var executables = struct {
+	sync.Mutex
+	next  uint64
+	stats map[uint64]*executableStats
+}{stats: map[uint64]*executableStats{}}
+
+// registerExecutable registers the executable of the evaluator, until
+// the evaluator is garbage collected.
+func registerExecutable(e *vme, c *compiledExecutable) {
+	plans := c.executable.Plans()
+	entrypoints := make([]string, plans.Len())
+	for i := range entrypoints {
+		entrypoints[i] = plans.Plan(i).Name()
+	}
+
+	s := &executableStats{info: ExecutableInfo{
+		Entrypoints: entrypoints,
+		Size:        len(c.executable),
+		Builtins:    c.executable.UsedBuiltins(),
+		CompiledAt:  c.compiledAt,
+		CompileTime: c.compileTime,
+	}}
+
+	executables.Lock()
+	executables.next++
+	s.info.ID = executables.next
+	executables.stats[s.info.ID] = s
+	executables.Unlock()
+
+	e.stats = s
+	runtime.AddCleanup(e, unregisterExecutable, s.info.ID)
+}
+
+func unregisterExecutable(id uint64) {
+	executables.Lock()
+	delete(executables.stats, id)
+	executables.Unlock()
+}
+
+// evaluated counts an evaluation of the executable for the query.
+func (s *executableStats) evaluated(query string) {
+	if q := s.query.Load(); q == nil || *q != query {
+		s.query.Store(&query)
+	}
+	s.evaluations.Add(1)
+}
+
+// Executables returns the executables currently prepared for evaluation
+// by the VM, in the order they were compiled.
+func Executables() []ExecutableInfo {
+	executables.Lock()
+	infos := make([]ExecutableInfo, 0, len(executables.stats))
+	for _, s := range executables.stats {
+		info := s.info
+		if q := s.query.Load(); q != nil {
+			info.Query = *q
+		}
+		info.Evaluations = s.evaluations.Load()
+		infos = append(infos, info)
+	}
+	executables.Unlock()
+
+	slices.SortFunc(infos, func(a, b ExecutableInfo) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return infos
+}
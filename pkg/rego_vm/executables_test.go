@@ -0,0 +1,65 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package rego_vm_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+
+	"github.com/open-policy-agent/eopa/pkg/rego_vm"
+)
+
+func TestExecutables(t *testing.T) {
+	ctx := context.Background()
+
+	var last uint64
+	for _, info := range rego_vm.Executables() {
+		last = max(last, info.ID)
+	}
+
+	pq, err := rego.New(
+		rego.Target(rego_vm.Target),
+		rego.Query("data.x.p = x"),
+		rego.Module("test.rego", "package x\n\np := count(input.xs)"),
+	).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := pq.Eval(ctx, rego.EvalInput(map[string]any{"xs": []any{1, 2}})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var found []rego_vm.ExecutableInfo
+	for _, info := range rego_vm.Executables() {
+		if info.ID > last {
+			found = append(found, info)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected one new executable, got %v", found)
+	}
+
+	info := found[0]
+	if info.Evaluations != 2 {
+		t.Errorf("expected 2 evaluations, got %d", info.Evaluations)
+	}
+	if info.Query == "" {
+		t.Error("expected the query evaluated")
+	}
+	if !slices.Equal(info.Entrypoints, []string{"eval"}) {
+		t.Errorf("expected the eval entrypoint, got %v", info.Entrypoints)
+	}
+	if !slices.Contains(info.Builtins, "count") {
+		t.Errorf("expected count used, got %v", info.Builtins)
+	}
+	if info.Size == 0 || info.CompiledAt.IsZero() || info.CompileTime <= 0 {
+		t.Errorf("expected size and compilation time, got %+v", info)
+	}
+}
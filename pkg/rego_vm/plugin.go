@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto/rand"
 	"sync"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -64,7 +65,12 @@ func (*vmp) IsTarget(t string) bool {
 
 // Applies the current server-wide optimization schedule before building
 // EOPA VM bytecode from the policy.
-func (*vmp) PrepareForEval(_ context.Context, policy *ir.Policy, opts ...rego.PrepareOption) (rego.TargetPluginEval, error) {
+func (*vmp) PrepareForEval(ctx context.Context, policy *ir.Policy, opts ...rego.PrepareOption) (_ rego.TargetPluginEval, err error) {
+	_, span := tracer.Start(ctx, "compile", trace.WithAttributes(attribute.Int("plans", len(policy.Plans.Plans))))
+	defer func() { vm.EndSpan(span, err) }()
+
+	start := time.Now()
+
 	po := &rego.PrepareConfig{}
 	for _, o := range opts {
 		o(po)
@@ -78,26 +84,59 @@ func (*vmp) PrepareForEval(_ context.Context, policy *ir.Policy, opts ...rego.Pr
 		bis = bi.BuiltinFuncs()
 	}
 
-	// Note(philip): This is where the IR optimization passes are applied.
-	optimizedPolicy, err := iropt.RunPasses(policy, iropt.RegoVMIROptimizationPassSchedule)
-	if err != nil {
-		return nil, err
+	// With warming enabled, the policy may have been compiled already.
+	bp := vm.GetBuiltinPolicy()
+	key, cached := executableKey(policy, bis, bp)
+	c := (*compiledExecutable)(nil)
+	if cached {
+		c = cachedExecutable(key)
 	}
 
-	executable, err := vm.NewCompiler().WithPolicy(optimizedPolicy).WithBuiltins(bis).Compile()
-	if err != nil {
-		return nil, err
+	if c == nil {
+		// Note(philip): This is where the IR optimization passes are applied.
+		optimizedPolicy, err := iropt.RunPasses(policy, iropt.RegoVMIROptimizationPassSchedule)
+		if err != nil {
+			return nil, err
+		}
+
+		executable, err := vm.NewCompiler().WithPolicy(optimizedPolicy).WithBuiltins(bis).WithBuiltinPolicy(bp).Compile()
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		c = &compiledExecutable{
+			executable:  executable,
+			astPaths:    vm.DelegatedDataPaths(optimizedPolicy),
+			compiledAt:  now,
+			compileTime: now.Sub(start),
+		}
+		if cached {
+			cacheExecutable(key, c)
+		}
 	}
 
-	return &vme{
+	e := &vme{
 		builtinFuncs: bis,
-		e:            executable,
-	}, nil
+		pool:         vm.NewPool(c.executable),
+	}
+	if len(c.astPaths) > 0 {
+		e.astPaths = c.astPaths
+		e.astCache = bjson.NewSharedASTCache()
+	}
+	registerExecutable(e, c)
+	return e, nil
 }
 
 type vme struct {
 	builtinFuncs map[string]*topdown.Builtin
-	e            vm.Executable
+	pool         *vm.Pool
+	stats        *executableStats
+
+	// The data the builtins delegated to topdown are passed, converted
+	// to AST once per data revision.
+	astPaths [][]string
+	astCache *bjson.SharedASTCache
 }
 
 var tracer = otel.Tracer(Name)
@@ -111,9 +150,13 @@ func spanFromContext(ctx context.Context, query string) (ctx0 context.Context, s
 }
 
 func (t *vme) Eval(ctx context.Context, ectx *rego.EvalContext, rt ast.Value) (ast.Value, error) {
-	v := vm.NewVM().WithExecutable(t.e)
+	v := t.pool.Get()
+	defer t.pool.Put(v)
+	query := ectx.CompiledQuery().String()
+	t.stats.evaluated(query)
+
 	var span trace.Span
-	ctx, span = spanFromContext(ctx, ectx.CompiledQuery().String())
+	ctx, span = spanFromContext(ctx, query)
 	defer span.End()
 
 	input := ectx.RawInput()
@@ -136,6 +179,7 @@ func (t *vme) Eval(ctx context.Context, ectx *rego.EvalContext, rt ast.Value) (a
 	// and feed its data to the VM. That will have subtle differences in behavior; but it
 	// is good enough for the remaining cases where this is allowed to happen: discovery
 	// document evaluation.
+	_, dataSpan := tracer.Start(ctx, "data")
 	txn := ectx.Transaction()
 	if txn0, ok := txn.(interface {
 		Write(storage.PatchOp, storage.Path, any) error // only OPA's inmem txn has that
@@ -143,6 +187,7 @@ func (t *vme) Eval(ctx context.Context, ectx *rego.EvalContext, rt ast.Value) (a
 	}); ok {
 		x, err := txn0.Read(storage.Path{})
 		if err != nil {
+			vm.EndSpan(dataSpan, err)
 			return nil, err
 		}
 
@@ -150,18 +195,29 @@ func (t *vme) Eval(ctx context.Context, ectx *rego.EvalContext, rt ast.Value) (a
 		case ast.Object:
 			x, err = ast.ValueToInterface(x0, nil)
 			if err != nil {
+				vm.EndSpan(dataSpan, err)
 				return nil, err
 			}
 		}
 
 		y, err := bjson.New(x)
 		if err != nil {
+			vm.EndSpan(dataSpan, err)
 			return nil, err
 		}
 		v = v.WithDataJSON(y)
 	} else {
 		v = v.WithDataNamespace(txn)
 	}
+	if t.astCache != nil {
+		// The conversions run in the background, once per data revision:
+		// the evaluations meanwhile convert the values themselves.
+		if err := v.PrecomputeASTInBackground(ctx, t.astCache, t.astPaths); err != nil {
+			vm.EndSpan(dataSpan, err)
+			return nil, err
+		}
+	}
+	dataSpan.End()
 
 	result, err := v.Eval(ctx, "eval", vm.EvalOpts{
 		Metrics:                     ectx.Metrics(),
@@ -181,6 +237,7 @@ func (t *vme) Eval(ctx context.Context, ectx *rego.EvalContext, rt ast.Value) (a
 		BuiltinFuncs:                t.builtinFuncs,
 		ExternalCancel:              ectx.ExternalCancel(),
 		QueryTracers:                ectx.QueryTracers(),
+		SharedASTCache:              t.astCache,
 	})
 	ectx.Metrics().Timer(evalTimer).Stop()
 	if err != nil {
@@ -0,0 +1,298 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package rego_vm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/open-policy-agent/eopa/pkg/vm"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/config"
+	"github.com/open-policy-agent/opa/v1/ir"
+	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/topdown"
+)
+
+// WarmOptions configure the compilation of the entrypoints in the
+// background after each activation. See EnableWarming.
+//
+// The options are under "extra/warming" in OPA config.
+type WarmOptions struct {
+	// Concurrency is the number of entrypoints compiled at once, one if
+	// not positive.
+	Concurrency int `json:"concurrency"`
+
+	// Entrypoints are slash separated data paths, such as "authz/allow",
+	// to compile in addition to the rules and packages annotated as
+	// entrypoints in the policies.
+	Entrypoints []string `json:"entrypoints"`
+}
+
+// WarmingConfig returns the warming options of the config, or nil if
+// warming is not configured.
+func WarmingConfig(conf *config.Config) (*WarmOptions, error) {
+	raw := conf.Extra["warming"]
+	if raw == nil {
+		return nil, nil
+	}
+
+	var opts WarmOptions
+	if err := json.Unmarshal(raw, &opts); err != nil {
+		return nil, fmt.Errorf("warming: %w", err)
+	}
+	return &opts, nil
+}
+
+// maxCachedExecutables bounds the executables cached per activation.
+const maxCachedExecutables = 1000
+
+// compiledExecutable is an executable compiled for a policy, cached for
+// preparing the policy again without compiling it again.
+type compiledExecutable struct {
+	executable  vm.Executable
+	astPaths    [][]string
+	compiledAt  time.Time
+	compileTime time.Duration
+}
+
+// executableCache holds the executables compiled for the policies of the
+// current activation, by the policies' hashes, while warming is enabled.
+var executableCache = struct {
+	sync.Mutex
+	enabled bool
+	entries map[[sha256.Size]byte]*compiledExecutable
+}{}
+
+var warmingEntrypoints = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "eopa_regovm_warming_entrypoints",
+	Help: "The number of entrypoints of the last activation by warming status (label \"status\" indicates pending, compiled or failed)",
+}, []string{"status"})
+
+// EnableWarming makes the VM compile the entrypoints of the policies in
+// the background after each activation, for the first evaluations of the
+// entrypoints not to wait for their compilation: the policies prepared
+// for evaluation reuse the executables compiled for the same policies,
+// until the next activation. The warming of an activation is cancelled by
+// the next one. The progress is exposed by the
+// eopa_regovm_warming_entrypoints metric.
+func EnableWarming(m *plugins.Manager, opts WarmOptions) error {
+	if reg := m.PrometheusRegister(); reg != nil {
+		if err := reg.Register(warmingEntrypoints); err != nil {
+			var are prometheus.AlreadyRegisteredError
+			if !errors.As(err, &are) {
+				return err
+			}
+		}
+	}
+
+	executableCache.Lock()
+	executableCache.enabled = true
+	executableCache.entries = make(map[[sha256.Size]byte]*compiledExecutable)
+	executableCache.Unlock()
+
+	w := &warmer{manager: m, opts: opts}
+	m.RegisterCompilerTrigger(w.trigger)
+	return nil
+}
+
+type warmer struct {
+	manager *plugins.Manager
+	opts    WarmOptions
+
+	mtx        sync.Mutex
+	generation uint64
+	cancel     context.CancelFunc
+}
+
+// trigger cancels the warming of the previous activation, if ongoing, and
+// starts warming the entrypoints of the activated policies.
+func (w *warmer) trigger(storage.Transaction) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.cancel != nil {
+		w.cancel()
+	}
+
+	executableCache.Lock()
+	clear(executableCache.entries)
+	executableCache.Unlock()
+
+	compiler := w.manager.GetCompiler()
+	entrypoints := warmEntrypoints(compiler, w.opts.Entrypoints)
+
+	w.generation++
+	warmingEntrypoints.WithLabelValues("pending").Set(float64(len(entrypoints)))
+	warmingEntrypoints.WithLabelValues("compiled").Set(0)
+	warmingEntrypoints.WithLabelValues("failed").Set(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	go w.warm(ctx, w.generation, compiler, entrypoints)
+}
+
+func (w *warmer) warm(ctx context.Context, generation uint64, compiler *ast.Compiler, entrypoints []string) {
+	paths := make(chan string)
+
+	var wg sync.WaitGroup
+	for range max(w.opts.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				w.compiled(generation, path, warmEntrypoint(ctx, compiler, path))
+			}
+		}()
+	}
+
+loop:
+	for _, path := range entrypoints {
+		select {
+		case paths <- path:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	close(paths)
+	wg.Wait()
+}
+
+// compiled records the compilation of the entrypoint, unless the warming
+// was cancelled by then.
+func (w *warmer) compiled(generation uint64, path string, err error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if generation != w.generation {
+		return
+	}
+
+	warmingEntrypoints.WithLabelValues("pending").Dec()
+	if err != nil {
+		warmingEntrypoints.WithLabelValues("failed").Inc()
+		w.manager.Logger().Warn("Failed to warm entrypoint %q: %v", path, err)
+		return
+	}
+	warmingEntrypoints.WithLabelValues("compiled").Inc()
+}
+
+// warmEntrypoint prepares the entrypoint for evaluation, as the server
+// does on its first evaluation, compiling it to an executable.
+func warmEntrypoint(ctx context.Context, compiler *ast.Compiler, path string) error {
+	p, ok := storage.ParsePathEscaped("/" + strings.Trim(path, "/"))
+	if !ok {
+		return errors.New("invalid entrypoint path")
+	}
+
+	_, err := rego.New(
+		rego.Target(Target),
+		rego.Compiler(compiler),
+		rego.ParsedQuery(ast.NewBody(ast.NewExpr(ast.NewTerm(p.Ref(ast.DefaultRootDocument))))),
+	).PrepareForEval(ctx)
+	return err
+}
+
+// warmEntrypoints returns the entrypoints given and those annotated in
+// the policies, sorted.
+func warmEntrypoints(compiler *ast.Compiler, entrypoints []string) []string {
+	paths := slices.Clone(entrypoints)
+
+	if compiler != nil && compiler.GetAnnotationSet() != nil {
+		for _, aref := range compiler.GetAnnotationSet().Flatten() {
+			if !aref.Annotations.Entrypoint {
+				continue
+			}
+
+			var ref ast.Ref
+			switch aref.Annotations.Scope {
+			case "package":
+				if p := aref.GetPackage(); p != nil {
+					ref = p.Path
+				}
+			case "document":
+				if r := aref.GetRule(); r != nil {
+					ref = r.Ref().GroundPrefix()
+				}
+			}
+
+			if len(ref) == 0 {
+				continue
+			}
+			if p, err := storage.NewPathForRef(ref); err == nil {
+				paths = append(paths, strings.Join(p, "/"))
+			}
+		}
+	}
+
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// executableKey returns the key of the executables compiled for the
+// policy, the builtins and the builtin policy, or false if they are not
+// cached.
+func executableKey(policy *ir.Policy, bis map[string]*topdown.Builtin, bp *vm.BuiltinPolicy) ([sha256.Size]byte, bool) {
+	executableCache.Lock()
+	enabled := executableCache.enabled
+	executableCache.Unlock()
+
+	if !enabled {
+		return [sha256.Size]byte{}, false
+	}
+
+	bs, err := json.Marshal(policy)
+	if err != nil {
+		return [sha256.Size]byte{}, false
+	}
+
+	h := sha256.New()
+	h.Write(bs)
+	for _, name := range slices.Sorted(maps.Keys(bis)) {
+		h.Write([]byte{0})
+		h.Write([]byte(name))
+	}
+
+	// The builtin policy is enforced by the compilation: the executables
+	// compiled before a change of the policy must not be reused after it.
+	bs, err = json.Marshal(bp)
+	if err != nil {
+		return [sha256.Size]byte{}, false
+	}
+	h.Write([]byte{0})
+	h.Write(bs)
+
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key, true
+}
+
+func cachedExecutable(key [sha256.Size]byte) *compiledExecutable {
+	executableCache.Lock()
+	defer executableCache.Unlock()
+	return executableCache.entries[key]
+}
+
+func cacheExecutable(key [sha256.Size]byte, c *compiledExecutable) {
+	executableCache.Lock()
+	defer executableCache.Unlock()
+
+	if len(executableCache.entries) < maxCachedExecutables {
+		executableCache.entries[key] = c
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package rego_vm_test
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/config"
+	"github.com/open-policy-agent/opa/v1/plugins"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+
+	"github.com/open-policy-agent/eopa/pkg/rego_vm"
+	"github.com/open-policy-agent/eopa/pkg/vm"
+)
+
+func TestEnableWarming(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	reg := prometheus.NewRegistry()
+
+	m, err := plugins.New([]byte("{}"), "test-instance-id", store,
+		plugins.WithPrometheusRegister(reg),
+		plugins.WithParserOptions(ast.ParserOptions{ProcessAnnotation: true}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := rego_vm.EnableWarming(m, rego_vm.WarmOptions{Concurrency: 2, Entrypoints: []string{"test/q"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	const policy = `package test
+
+# METADATA
+# entrypoint: true
+p := count(input.xs)
+
+q := input.x + 1
+`
+
+	if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		return store.UpsertPolicy(ctx, txn, "test.rego", []byte(policy))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]float64{"pending": 0, "compiled": 2, "failed": 0}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		actual := warmingStatus(t, reg)
+		if maps.Equal(actual, expected) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The executables warmed are not reused once the builtin policy
+	// disallows a builtin they call.
+	vm.SetBuiltinPolicy(&vm.BuiltinPolicy{Deny: []string{"count"}})
+	defer vm.SetBuiltinPolicy(nil)
+
+	_, err = rego.New(
+		rego.Target(rego_vm.Target),
+		rego.Compiler(m.GetCompiler()),
+		rego.Query("data.test.p"),
+	).PrepareForEval(ctx)
+	if err == nil || !strings.Contains(err.Error(), "is denied by the builtin policy") {
+		t.Fatalf("expected builtin policy error, got %v", err)
+	}
+}
+
+func TestWarmingConfig(t *testing.T) {
+	conf, err := config.ParseConfig([]byte(`{"warming": {"concurrency": 4, "entrypoints": ["authz/allow"]}}`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := rego_vm.WarmingConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts == nil || opts.Concurrency != 4 || !slices.Equal(opts.Entrypoints, []string{"authz/allow"}) {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+
+	conf, err = config.ParseConfig([]byte(`{}`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts, err := rego_vm.WarmingConfig(conf); err != nil || opts != nil {
+		t.Fatalf("expected no options, got %+v, %v", opts, err)
+	}
+}
+
+func warmingStatus(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := make(map[string]float64)
+	for _, mf := range mfs {
+		if mf.GetName() != "eopa_regovm_warming_entrypoints" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "status" {
+					status[l.GetValue()] = m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return status
+}
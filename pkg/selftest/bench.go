@@ -0,0 +1,171 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+// BenchOptions configure the comparison of the VM and topdown by Bench.
+type BenchOptions struct {
+	// Modules are the policies, by their file names.
+	Modules map[string]string
+	// Data is the base document.
+	Data map[string]any
+	// Input is the input document, or nil if there is none.
+	Input any
+	// Entrypoint is the query evaluated, such as "data.authz.allow".
+	Entrypoint string
+	// Count is the number of evaluations measured per engine.
+	Count int
+}
+
+// BenchStats are the latencies of the evaluations of an engine.
+type BenchStats struct {
+	Count int           `json:"count"`
+	Min   time.Duration `json:"min_ns"`
+	Mean  time.Duration `json:"mean_ns"`
+	P50   time.Duration `json:"p50_ns"`
+	P90   time.Duration `json:"p90_ns"`
+	P99   time.Duration `json:"p99_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+// BenchReport is the result of Bench. The speedup is the ratio of the
+// median latencies of topdown and the VM: above one, the VM is faster.
+type BenchReport struct {
+	Entrypoint string     `json:"entrypoint"`
+	VM         BenchStats `json:"vm"`
+	Topdown    BenchStats `json:"topdown"`
+	Speedup    float64    `json:"speedup"`
+}
+
+// Bench evaluates the entrypoint with both the VM and topdown, after a
+// first evaluation checking they agree on the result, and reports their
+// latency distributions. Both engines evaluate the same prepared query
+// against the same store, so only the evaluation itself is measured.
+func Bench(ctx context.Context, opts BenchOptions) (*BenchReport, error) {
+	if opts.Count < 1 {
+		return nil, fmt.Errorf("count must be positive, got %d", opts.Count)
+	}
+
+	vmResult, vmStats, err := bench(ctx, VMTarget, opts)
+	if err != nil {
+		return nil, fmt.Errorf("vm: %w", err)
+	}
+
+	tdResult, tdStats, err := bench(ctx, TopdownTarget, opts)
+	if err != nil {
+		return nil, fmt.Errorf("topdown: %w", err)
+	}
+
+	if !equal(vmResult, tdResult) {
+		return nil, fmt.Errorf("vm result %v, topdown result %v", format(vmResult), format(tdResult))
+	}
+
+	r := &BenchReport{Entrypoint: opts.Entrypoint, VM: vmStats, Topdown: tdStats}
+	if vmStats.P50 > 0 {
+		r.Speedup = float64(tdStats.P50) / float64(vmStats.P50)
+	}
+	return r, nil
+}
+
+// bench evaluates the entrypoint with the target, returning the result of
+// the first evaluation and the latencies of the others.
+func bench(ctx context.Context, target string, opts BenchOptions) (ast.Value, BenchStats, error) {
+	ropts := []func(*rego.Rego){
+		rego.Query(opts.Entrypoint),
+		rego.Store(inmem.NewFromObject(opts.Data)),
+		rego.Target(target),
+		rego.GenerateJSON(func(t *ast.Term, _ *rego.EvalContext) (any, error) {
+			return t.Value, nil
+		}),
+	}
+	for _, name := range slices.Sorted(maps.Keys(opts.Modules)) {
+		ropts = append(ropts, rego.Module(name, opts.Modules[name]))
+	}
+
+	pq, err := rego.New(ropts...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, BenchStats{}, err
+	}
+
+	var eopts []rego.EvalOption
+	if opts.Input != nil {
+		eopts = append(eopts, rego.EvalInput(opts.Input))
+	}
+
+	rs, err := pq.Eval(ctx, eopts...)
+	if err != nil {
+		return nil, BenchStats{}, err
+	}
+	var result ast.Value
+	if len(rs) > 0 && len(rs[0].Expressions) > 0 {
+		result, _ = rs[0].Expressions[0].Value.(ast.Value)
+	}
+
+	latencies := make([]time.Duration, opts.Count)
+	for i := range latencies {
+		start := time.Now()
+		if _, err := pq.Eval(ctx, eopts...); err != nil {
+			return nil, BenchStats{}, err
+		}
+		latencies[i] = time.Since(start)
+	}
+
+	return result, benchStats(latencies), nil
+}
+
+func benchStats(latencies []time.Duration) BenchStats {
+	slices.Sort(latencies)
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+
+	n := len(latencies)
+	percentile := func(p int) time.Duration {
+		return latencies[max((n*p+99)/100-1, 0)]
+	}
+
+	return BenchStats{
+		Count: n,
+		Min:   latencies[0],
+		Mean:  total / time.Duration(n),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P99:   percentile(99),
+		Max:   latencies[n-1],
+	}
+}
+
+// Write writes the report as a table.
+func (r *BenchReport) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "engine\tcount\tmin\tmean\tp50\tp90\tp99\tmax\t\n")
+	for _, e := range []struct {
+		name  string
+		stats BenchStats
+	}{{"vm", r.VM}, {"topdown", r.Topdown}} {
+		s := e.stats
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%v\t%v\t%v\t%v\t\n", e.name, s.Count, s.Min, s.Mean, s.P50, s.P90, s.P99, s.Max)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%s: VM speedup %.2fx (median)\n", r.Entrypoint, r.Speedup)
+	return err
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBench(t *testing.T) {
+	ctx := context.Background()
+	opts := BenchOptions{
+		Modules: map[string]string{
+			"authz.rego": "package authz\n\nallow if input.role in {\"admin\", \"owner\"}\n",
+		},
+		Data:       map[string]any{},
+		Input:      map[string]any{"role": "admin"},
+		Entrypoint: "data.authz.allow",
+		Count:      10,
+	}
+
+	r, err := Bench(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []BenchStats{r.VM, r.Topdown} {
+		if s.Count != 10 || s.Min > s.P50 || s.P50 > s.P90 || s.P90 > s.P99 || s.P99 > s.Max {
+			t.Errorf("unexpected stats %+v", s)
+		}
+	}
+	if r.Speedup <= 0 {
+		t.Errorf("expected positive speedup, got %v", r.Speedup)
+	}
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "data.authz.allow: VM speedup") {
+		t.Errorf("unexpected report:\n%s", buf.String())
+	}
+
+	opts.Count = 0
+	if _, err := Bench(ctx, opts); err == nil {
+		t.Error("expected error for non-positive count")
+	}
+}
+
+func TestBenchStats(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(100 - i)
+	}
+
+	s := benchStats(latencies)
+	if s.Min != 1 || s.P50 != 50 || s.P90 != 90 || s.P99 != 99 || s.Max != 100 || s.Mean != 50 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
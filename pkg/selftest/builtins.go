@@ -0,0 +1,751 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package selftest contains conformance checks run by the `eopa selftest`
+// command.
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+
+	"github.com/open-policy-agent/eopa/pkg/vm"
+)
+
+const (
+	// VMTarget is the rego target evaluating queries with the VM.
+	VMTarget = "vm"
+	// TopdownTarget is the rego target evaluating queries with topdown.
+	TopdownTarget = "rego"
+)
+
+// input is available to the builtin cases as `input`. Its values aren't
+// known to the type checker, which lets the cases exercise the operand
+// type errors raised at evaluation time.
+var input = map[string]any{
+	"null":   nil,
+	"number": 1,
+	"string": "a",
+	"array":  []any{1},
+	"object": map[string]any{"a": 1},
+}
+
+// values is a battery of operands for the builtins accepting any type.
+var values = []string{
+	`null`, `true`, `false`, `0`, `-1`, `1.5`, `-1e3`, `""`, `"a"`, `"日本語"`,
+	`[]`, `[1, [2]]`, `{}`, `{"a": {"b": [1]}}`, `{1: "a"}`, `set()`, `{1, "a", [2]}`,
+}
+
+// builtinCases lists the expressions exercising each native builtin
+// topdown implements on its own: the results of both are compared.
+var builtinCases = map[string][]string{
+	ast.Member.Name: {
+		`internal.member_2(1, [1, 2])`,
+		`internal.member_2(3, [1, 2])`,
+		`internal.member_2(1, [])`,
+		`internal.member_2(-1, [-1])`,
+		`internal.member_2("é", {"é"})`,
+		`internal.member_2([1], {[1]})`,
+		`internal.member_2(1, set())`,
+		`internal.member_2(1.0, {1, 2})`,
+		`internal.member_2(2, {1.5, 2.0})`,
+		`internal.member_2({"a": {1}}, {{"a": {1}}, [1]})`,
+		`internal.member_2({"a": 1}, {{"a": 1, "b": 2}})`,
+		`internal.member_2(null, {null})`,
+		`internal.member_2(1, {"a": 1})`,
+		`internal.member_2("a", {"a": 1})`,
+		`internal.member_2({"a": [1]}, [{"a": [1]}])`,
+		`internal.member_2(1, "a")`,
+	},
+	ast.MemberWithKey.Name: {
+		`internal.member_3(0, 1, [1])`,
+		`internal.member_3(1, 1, [1])`,
+		`internal.member_3(-1, 1, [1])`,
+		`internal.member_3("a", 1, {"a": 1})`,
+		`internal.member_3("b", 1, {"a": 1})`,
+		`internal.member_3([1], {2}, {[1]: {2}})`,
+		`internal.member_3(0, 1, [])`,
+		`internal.member_3(0, 1, {})`,
+		`internal.member_3(1, 1, {1})`,
+		`internal.member_3(0, "a", "a")`,
+	},
+	ast.ObjectGet.Name: {
+		`object.get({"a": {"b": 1}}, ["a", "b"], 0)`,
+		`object.get({"a": {"b": 1}}, ["a", "c"], 0)`,
+		`object.get({"a": 1}, "a", 0)`,
+		`object.get({"a": 1}, "b", null)`,
+		`object.get({"a": 1}, [], 0)`,
+		`object.get({}, "a", {})`,
+		`object.get({"a": [1, 2]}, ["a", 1], 0)`,
+		`object.get({"a": [1, 2]}, ["a", -1], 0)`,
+		`object.get({"a": {1, 2}}, ["a", 2], 0)`,
+		`object.get({"日本": "語"}, "日本", 0)`,
+		`object.get({[1]: 2}, [[1]], 0)`,
+		`object.get(input.array, "a", 0)`,
+	},
+	ast.ObjectKeys.Name: {
+		`object.keys({})`,
+		`object.keys({"a": 1, "b": 2})`,
+		`object.keys({[1]: 1, -1: 2, "é": 3})`,
+		`object.keys(input.array)`,
+	},
+	ast.ObjectRemove.Name: {
+		`object.remove({"a": 1, "b": 2}, ["a"])`,
+		`object.remove({"a": 1, "b": 2}, {"a", "c"})`,
+		`object.remove({"a": 1, "b": 2}, {"a": 0})`,
+		`object.remove({}, [])`,
+		`object.remove({"a": {"b": 1}}, ["b"])`,
+		`object.remove({1: 1, "1": 2}, [1])`,
+		`object.remove({"a": 1}, input.string)`,
+		`object.remove(input.array, [])`,
+	},
+	ast.ObjectFilter.Name: {
+		`object.filter({"a": 1, "b": 2}, ["a"])`,
+		`object.filter({"a": 1, "b": 2}, {"a", "c"})`,
+		`object.filter({"a": 1, "b": 2}, {"a": 0})`,
+		`object.filter({}, [])`,
+		`object.filter({"a": 1}, [])`,
+		`object.filter({"é": [1]}, ["é"])`,
+		`object.filter({"a": 1}, input.string)`,
+		`object.filter(input.array, [])`,
+	},
+	ast.ObjectUnion.Name: {
+		`object.union({"a": {"b": 1}}, {"a": {"c": 2}})`,
+		`object.union({}, {})`,
+		`object.union({"a": 1}, {"a": [1]})`,
+		`object.union({"a": {"b": {"c": 1}}}, {"a": {"b": 2}})`,
+		`object.union({"a": {1}}, {"a": {2}})`,
+		`object.union({1: 1}, {"1": 2})`,
+		`object.union(input.array, {})`,
+	},
+	ast.Concat.Name: {
+		`concat(",", ["a", "b"])`,
+		`concat("", [])`,
+		`concat(",", set())`,
+		`concat("-", {"b", "a"})`,
+		`concat("é", ["ü", "ö"])`,
+		`concat(",", ["", ""])`,
+		`concat(",", input.array)`,
+		`concat(input.number, ["a"])`,
+	},
+	ast.EndsWith.Name: {
+		`endswith("abc", "c")`,
+		`endswith("abc", "b")`,
+		`endswith("", "")`,
+		`endswith("a", "")`,
+		`endswith("", "a")`,
+		`endswith("日本語", "語")`,
+		`endswith(input.number, "a")`,
+	},
+	ast.StartsWith.Name: {
+		`startswith("abc", "a")`,
+		`startswith("abc", "b")`,
+		`startswith("", "")`,
+		`startswith("a", "")`,
+		`startswith("", "a")`,
+		`startswith("日本語", "日")`,
+		`startswith(input.number, "a")`,
+	},
+	ast.Sprintf.Name: {
+		`sprintf("", [])`,
+		`sprintf("%d %s", [1, "a"])`,
+		`sprintf("%d", [-1])`,
+		`sprintf("%.2f", [-1.5])`,
+		`sprintf("%v", [{"a": {1, 2}}])`,
+		`sprintf("%v", [[null, true, "é"]])`,
+		`sprintf("%s", ["日本語"])`,
+		`sprintf("%v %v", [1])`,
+		`sprintf(input.number, [])`,
+	},
+	ast.Upper.Name: {
+		`upper("abc")`,
+		`upper("ABC")`,
+		`upper("")`,
+		`upper("straße")`,
+		`upper("ǆemal")`,
+		`upper("日本語")`,
+		`upper(input.number)`,
+	},
+	ast.Lower.Name: {
+		`lower("ABC")`,
+		`lower("abc")`,
+		`lower("")`,
+		`lower("İSTANBUL")`,
+		`lower("ΣΊΣΥΦΟΣ")`,
+		`lower("日本語")`,
+		`lower(input.number)`,
+	},
+	ast.Plus.Name: {
+		`1 + 2`,
+		`-1 + 0.5`,
+		`0.1 + 0.2`,
+		`1.0 + 1`,
+		`1e3 + 1`,
+		`9223372036854775807 + 1`,
+		`1e20 + 1e20`,
+		`1e-20 + 1`,
+		`1.5e300 + 1`,
+		`9007199254740993 + 0.5`,
+		`input.number + 2147483648`,
+		`input.number + input.string`,
+		`input.string + 1`,
+	},
+	ast.Minus.Name: {
+		`1 - 2`,
+		`0.5 - 1`,
+		`-9223372036854775808 - 1`,
+		`3e20 - 1e20`,
+		`0.3 - 0.1`,
+		`9007199254740993 - 0.25`,
+		`{1, 2, [3]} - {1, [3]}`,
+		`{1, 2} - {1.0}`,
+		`{1} - set()`,
+		`1 - {1}`,
+		`{1} - 1`,
+		`{1} - input.string`,
+		`input.string - 1`,
+		`input.number - input.number`,
+	},
+	ast.Multiply.Name: {
+		`2 * 3`,
+		`-1.5 * 2`,
+		`0 * -1.5`,
+		`4294967296 * 4294967296`,
+		`2147483648 * -3`,
+		`0.1 * 3`,
+		`1e20 * 3`,
+		`2.5e-10 * 4e10`,
+		`9007199254740993 * 1.5`,
+		`input.number * input.string`,
+	},
+	ast.ArrayConcat.Name: {
+		`array.concat([1], [2])`,
+		`array.concat([], [])`,
+		`array.concat([[1]], [{"a"}])`,
+		`array.concat([-1], [])`,
+		`array.concat(input.object, [])`,
+	},
+	ast.ArraySlice.Name: {
+		`array.slice([1, 2, 3], 1, 2)`,
+		`array.slice([1, 2, 3], -1, 5)`,
+		`array.slice([1, 2, 3], 2, 1)`,
+		`array.slice([], 0, 0)`,
+		`array.slice([[1], {"a"}], 0, 1)`,
+		`array.slice(input.object, 0, 1)`,
+	},
+	ast.Count.Name: {
+		`count([])`,
+		`count({})`,
+		`count(set())`,
+		`count("")`,
+		`count("日本語")`,
+		`count([1, [2, 3]])`,
+		`count({"a": 1})`,
+		`count({1, 2})`,
+		`count(input.number)`,
+	},
+	ast.WalkBuiltin.Name: {
+		`[x | walk([], x)]`,
+		`{x | walk({}, x)}`,
+		`{x | walk({"a": [1, {"b": set()}]}, x)}`,
+		`{x | walk({"a": {1, [2]}}, x)}`,
+		`{x | walk("日本語", x)}`,
+		`{x | walk(-1, x)}`,
+	},
+	ast.Equal.Name: {
+		`equal(1, 1.0)`,
+		`equal(-1, 1)`,
+		`equal({1, 2}, {2, 1})`,
+		`equal({"a": []}, {"a": []})`,
+		`equal([1], [1, 2])`,
+		`equal("é", "é")`,
+		`equal(null, false)`,
+		`equal(set(), {})`,
+	},
+	ast.NotEqual.Name: {
+		`neq(1, 1.0)`,
+		`neq(-1, 1)`,
+		`neq({1, 2}, {2, 1})`,
+		`neq({"a": []}, {"a": [1]})`,
+		`neq("é", "e")`,
+		`neq(set(), [])`,
+	},
+	ast.Or.Name: {
+		`{1, 2} | {2, 3}`,
+		`set() | set()`,
+		`{[1]} | {{"a"}}`,
+		`{"é"} | set()`,
+		`input.array | {1}`,
+	},
+	ast.JSONUnmarshal.Name: {
+		`json.unmarshal("{\"a\": [1, 2]}")`,
+		`json.unmarshal("[]")`,
+		`json.unmarshal("{}")`,
+		`json.unmarshal("\"é\"")`,
+		`json.unmarshal("-1.5e3")`,
+		`json.unmarshal("null")`,
+		`json.unmarshal("{")`,
+		`json.unmarshal("")`,
+		`json.unmarshal(input.number)`,
+	},
+	ast.NumbersRange.Name: {
+		`numbers.range(1, 3)`,
+		`numbers.range(3, 1)`,
+		`numbers.range(0, 0)`,
+		`numbers.range(-2, 2)`,
+		`numbers.range(1.5, 2)`,
+		`numbers.range(input.string, 2)`,
+	},
+	ast.NumbersRangeStep.Name: {
+		`numbers.range_step(0, 10, 3)`,
+		`numbers.range_step(10, 0, 3)`,
+		`numbers.range_step(-5, 5, 5)`,
+		`numbers.range_step(0, 0, 1)`,
+		`numbers.range_step(0, 1, 0)`,
+		`numbers.range_step(0, 1, -1)`,
+		`numbers.range_step(0, 1.5, 1)`,
+	},
+	ast.GlobMatch.Name: {
+		`glob.match("*.github.com", ["."], "api.github.com")`,
+		`glob.match("*.github.com", ["."], "api.cdn.github.com")`,
+		`glob.match("**.github.com", ["."], "api.cdn.github.com")`,
+		`glob.match("*", [], "")`,
+		`glob.match("*", null, "a:b")`,
+		`glob.match("{a,b}*", [], "bc")`,
+		`glob.match("[!a]*", [], "abc")`,
+		`glob.match("日*", [], "日本語")`,
+		`glob.match("[", [], "a")`,
+		`glob.match(input.number, [], "a")`,
+	},
+}
+
+// goldenCase is an expression and its expected result, undefined if
+// empty, or its expected error. The error message is matched as a
+// substring, as topdown qualifies it with the expression and builtin.
+type goldenCase struct {
+	expr   string
+	result string
+	err    string
+}
+
+// goldenCases lists the expressions exercising each EOPA extension
+// builtin, with their expected results. Topdown evaluates these builtins
+// with the native implementation too (see vm.NativeBuiltin), so comparing
+// the VM against it would prove nothing.
+var goldenCases = map[string][]goldenCase{
+	vm.ObjectGetMergedName: {
+		{expr: `eopa.object.get_merged({"a": {"b": 1}}, "a", {"c": 2})`, result: `{"b": 1, "c": 2}`},
+		{expr: `eopa.object.get_merged({"a": {"b": 1}}, ["a", "b"], 0)`, result: `1`},
+		{expr: `eopa.object.get_merged({"a": {"b": {"c": 1}}}, "a", {"b": {"d": 2}})`, result: `{"b": {"c": 1, "d": 2}}`},
+		{expr: `eopa.object.get_merged({"a": 1}, "b", {"c": 2})`, result: `{"c": 2}`},
+		{expr: `eopa.object.get_merged({}, [], {})`, result: `{}`},
+		{expr: `eopa.object.get_merged({"a": [1]}, "a", {"é": 1})`, result: `[1]`},
+		{expr: `eopa.object.get_merged(input.array, "a", 0)`, err: `operand 1 must be object but got array`},
+	},
+	vm.ObjectHasPathName: {
+		{expr: `eopa.object.has_path({"a": {"b": null}}, ["a", "b"])`, result: `true`},
+		{expr: `eopa.object.has_path({"a": {"b": null}}, ["a", "c"])`, result: `false`},
+		{expr: `eopa.object.has_path({"a": [1, {"b": false}]}, ["a", 1, "b"])`, result: `true`},
+		{expr: `eopa.object.has_path({"a": 1}, ["a", "b"])`, result: `false`},
+		{expr: `eopa.object.has_path({"a": null}, "a")`, result: `true`},
+		{expr: `eopa.object.has_path({}, [])`, result: `true`},
+		{expr: `eopa.object.has_path({"é": 1}, "é")`, result: `true`},
+		{expr: `eopa.object.has_path(input.array, "a")`, err: `operand 1 must be object but got array`},
+	},
+	vm.NumbersParseBaseName: {
+		{expr: `eopa.numbers.parse_base("ff", 16)`, result: `255`},
+		{expr: `eopa.numbers.parse_base("-101", 2)`, result: `-5`},
+		{expr: `eopa.numbers.parse_base("z", 36)`, result: `35`},
+		{expr: `eopa.numbers.parse_base("0", 10)`, result: `0`},
+		{expr: `eopa.numbers.parse_base("", 10)`, err: `operand 1 invalid base 10 number: ""`},
+		{expr: `eopa.numbers.parse_base("2", 2)`, err: `operand 1 invalid base 2 number: "2"`},
+		{expr: `eopa.numbers.parse_base("1", 37)`, err: `operand 2 base must be between 2 and 36`},
+		{expr: `eopa.numbers.parse_base("日", 10)`, err: `operand 1 invalid base 10 number: "日"`},
+		{expr: `eopa.numbers.parse_base(input.number, 10)`, err: `operand 1 must be string but got number`},
+	},
+	vm.NumbersToBaseName: {
+		{expr: `eopa.numbers.to_base(255, 16)`, result: `"ff"`},
+		{expr: `eopa.numbers.to_base(-5, 2)`, result: `"-101"`},
+		{expr: `eopa.numbers.to_base(0, 36)`, result: `"0"`},
+		{expr: `eopa.numbers.to_base(1.5, 10)`, err: `operand 1 must be integer number but got floating-point number`},
+		{expr: `eopa.numbers.to_base(1, 1)`, err: `operand 2 base must be between 2 and 36`},
+		{expr: `eopa.numbers.to_base(input.string, 10)`, err: `operand 1 must be number but got string`},
+	},
+	vm.SetToSortedArrayName: {
+		{expr: `eopa.set.to_sorted_array(set())`, result: `[]`},
+		{expr: `eopa.set.to_sorted_array({3, -1, 2})`, result: `[-1, 2, 3]`},
+		{expr: `eopa.set.to_sorted_array({"b", "é", "a", 1, null, [1], {"a": 1}, {1}})`, result: `[null, 1, "a", "b", "é", [1], {"a": 1}, {1}]`},
+		{expr: `eopa.set.to_sorted_array(input.array)`, err: `operand 1 must be set but got array`},
+	},
+	vm.ArrayToSetName: {
+		{expr: `eopa.array.to_set([])`, result: `set()`},
+		{expr: `eopa.array.to_set([1, 1, -1])`, result: `{-1, 1}`},
+		{expr: `eopa.array.to_set([[1], [1], {"a": {1}}, "é"])`, result: `{"é", [1], {"a": {1}}}`},
+		{expr: `eopa.array.to_set(input.object)`, err: `operand 1 must be array but got object`},
+	},
+	vm.ArrayIndexOfName: {
+		{expr: `eopa.array.indexof([], 1)`, result: `-1`},
+		{expr: `eopa.array.indexof([1, 2, 1], 1)`, result: `0`},
+		{expr: `eopa.array.indexof([1, 2], 3)`, result: `-1`},
+		{expr: `eopa.array.indexof([[1], {"a": {1}}, "é"], {"a": {1}})`, result: `1`},
+		{expr: `eopa.array.indexof([1.0, 2], 2.0)`, result: `1`},
+		{expr: `eopa.array.indexof(input.object, 1)`, err: `operand 1 must be array but got object`},
+	},
+	vm.ObjectDiffName: {
+		{expr: `eopa.object.diff({}, {})`, result: `[]`},
+		{expr: `eopa.object.diff({"a": 1}, {"a": 2, "b": [1]})`, result: `[{"op": "replace", "path": "/a", "value": 2}, {"op": "add", "path": "/b", "value": [1]}]`},
+		{expr: `eopa.object.diff({"a": {"b": 1, "c": 2}}, {"a": {"b": 1}})`, result: `[{"op": "remove", "path": "/a/c"}]`},
+		{expr: `eopa.object.diff({"a": [1, 2]}, {"a": [1, 3]})`, result: `[{"op": "replace", "path": "/a/1", "value": 3}]`},
+		{expr: `eopa.object.diff({"a": [1]}, {"a": [1, 2]})`, result: `[{"op": "replace", "path": "/a", "value": [1, 2]}]`},
+		{expr: `eopa.object.diff({"a/b~c": 1}, {"a/b~c": "é"})`, result: `[{"op": "replace", "path": "/a~1b~0c", "value": "é"}]`},
+		{expr: `eopa.object.diff({"a": 1}, input.array)`, err: `operand 2 must be object but got array`},
+	},
+	vm.WalkUntilName: {
+		{expr: `eopa.walk_until({}, {})`, result: `[[], {}]`},
+		{expr: `eopa.walk_until({"a": [1, {"b": 2, "c": 3}]}, {"b": 2})`, result: `[["a", 1], {"b": 2, "c": 3}]`},
+		{expr: `eopa.walk_until({"a": [1, {"b": 2}]}, {"b": 3})`},
+		{expr: `eopa.walk_until([[1, 2], [3]], 3)`, result: `[[1, 0], 3]`},
+		{expr: `eopa.walk_until({"a": {"b": {"c": "é"}}}, "é")`, result: `[["a", "b", "c"], "é"]`},
+		{expr: `eopa.walk_until(input.object, {"b": [1]})`},
+	},
+	vm.HashName: {
+		{expr: `eopa.hash(null)`, result: `"e934a84adb052768"`},
+		{expr: `eopa.hash(1) == eopa.hash(1.0)`, result: `true`},
+		{expr: `eopa.hash("é")`, result: `"6f17a851c4de1ea2"`},
+		{expr: `eopa.hash([1, "a", [true]])`, result: `"57edfaac5d2697d2"`},
+		{expr: `eopa.hash({"a": 1, "b": {2}}) == eopa.hash({"b": {2}, "a": 1})`, result: `true`},
+		{expr: `eopa.hash({1: "a"})`, result: `"09721b689e41e2f0"`},
+		{expr: `eopa.hash(input.object)`, result: `"b4821103d63958f6"`},
+	},
+	vm.SizeBytesName: {
+		{expr: `eopa.size_bytes(null)`, result: `4`},
+		{expr: `eopa.size_bytes(1.5)`, result: `3`},
+		{expr: `eopa.size_bytes("é\n\"")`, result: `8`},
+		{expr: `eopa.size_bytes([])`, result: `2`},
+		{expr: `eopa.size_bytes([1, "a", [true, {}]])`, result: `17`},
+		{expr: `eopa.size_bytes({"a": 1, "b": {"c": null}})`, result: `22`},
+		{expr: `eopa.size_bytes({1, 2})`, result: `5`},
+		{expr: `eopa.size_bytes(input.object)`, result: `7`},
+	},
+	vm.IsValidEmailName: {
+		{expr: `eopa.is_valid_email("a@example.com")`, result: `true`},
+		{expr: `eopa.is_valid_email("a.b+c@sub.example.com")`, result: `true`},
+		{expr: `eopa.is_valid_email("a@bücher.example")`, result: `true`},
+		{expr: `eopa.is_valid_email("A <a@example.com>")`, result: `false`},
+		{expr: `eopa.is_valid_email("a@-example.com")`, result: `false`},
+		{expr: `eopa.is_valid_email("a")`, result: `false`},
+		{expr: `eopa.is_valid_email("")`, result: `false`},
+		{expr: `eopa.is_valid_email(input.number)`, err: `operand 1 must be string but got number`},
+	},
+	vm.IsValidHostnameName: {
+		{expr: `eopa.is_valid_hostname("example.com")`, result: `true`},
+		{expr: `eopa.is_valid_hostname("example.com.")`, result: `true`},
+		{expr: `eopa.is_valid_hostname("bücher.example")`, result: `true`},
+		{expr: `eopa.is_valid_hostname("xn--bcher-kva.example")`, result: `true`},
+		{expr: `eopa.is_valid_hostname("-a.example")`, result: `false`},
+		{expr: `eopa.is_valid_hostname("a..example")`, result: `false`},
+		{expr: `eopa.is_valid_hostname("a_b.example")`, result: `false`},
+		{expr: `eopa.is_valid_hostname("")`, result: `false`},
+		{expr: `eopa.is_valid_hostname(input.number)`, err: `operand 1 must be string but got number`},
+	},
+	vm.ParseURLName: {
+		{expr: `eopa.parse_url("https://user:pw@example.com:8443/a/b?x=1&x=2&y#frag")`, result: `{"fragment": "frag", "host": "example.com:8443", "hostname": "example.com", "path": "/a/b", "port": "8443", "query": {"x": ["1", "2"], "y": [""]}, "scheme": "https", "username": "user"}`},
+		{expr: `eopa.parse_url("/relative/path")`, result: `{"fragment": "", "host": "", "hostname": "", "path": "/relative/path", "port": "", "query": {}, "scheme": ""}`},
+		{expr: `eopa.parse_url("mailto:a@example.com")`, result: `{"fragment": "", "host": "", "hostname": "", "path": "", "port": "", "query": {}, "scheme": "mailto"}`},
+		{expr: `eopa.parse_url("http://bücher.example/")`, result: `{"fragment": "", "host": "bücher.example", "hostname": "bücher.example", "path": "/", "port": "", "query": {}, "scheme": "http"}`},
+		{expr: `eopa.parse_url("http://[::1]:80/")`, result: `{"fragment": "", "host": "[::1]:80", "hostname": "::1", "path": "/", "port": "80", "query": {}, "scheme": "http"}`},
+		{expr: `eopa.parse_url("%zz")`},
+		{expr: `eopa.parse_url(input.number)`, err: `operand 1 must be string but got number`},
+	},
+	vm.SampleName: {
+		{expr: `eopa.sample([1, 2, 3, 4, 5], 2, "seed")`, result: `[2, 5]`},
+		{expr: `eopa.sample([1, 2, 3, 4, 5], 2, 42)`, result: `[3, 4]`},
+		{expr: `eopa.sample({"a", "b", "c", "d"}, 3, {"tenant": "x"})`, result: `{"b", "c", "d"}`},
+		{expr: `eopa.sample([1, 2], 5, "seed")`, result: `[1, 2]`},
+		{expr: `eopa.sample([1, 2], 0, "seed")`, result: `[]`},
+		{expr: `eopa.sample([], 1, "seed")`, result: `[]`},
+		{expr: `eopa.sample([1, 2], -1, "seed")`, err: `operand 2 must be non-negative integer`},
+		{expr: `eopa.sample([1, 2], 1.5, "seed")`, err: `operand 2 must be integer number but got floating-point number`},
+		{expr: `eopa.sample(input.object, 1, "seed")`, err: `operand 1 must be one of {array, set} but got object`},
+	},
+	vm.ObjectMergeName: {
+		{expr: `eopa.object.merge({"a": [1], "b": {"c": [2], "d": 1}}, {"a": [3], "b": {"c": [4], "e": 2}}, {"arrays": "concat"})`, result: `{"a": [1, 3], "b": {"c": [2, 4], "d": 1, "e": 2}}`},
+		{expr: `eopa.object.merge({"a": [1], "b": {"c": [2], "d": 1}}, {"a": [3], "b": {"c": [4], "e": 2}}, {"arrays": "replace"})`, result: `{"a": [3], "b": {"c": [4], "d": 1, "e": 2}}`},
+		{expr: `eopa.object.merge({"a": [1], "b": {"c": 1}}, {"a": {"x": 1}, "b": [2]}, {"arrays": "concat"})`, result: `{"a": {"x": 1}, "b": [2]}`},
+		{expr: `eopa.object.merge({"a": [1]}, {"a": [2]}, {})`, result: `{"a": [2]}`},
+		{expr: `eopa.object.merge({[1]: [1]}, {[1]: [2]}, {"arrays": "concat"})`, result: `{[1]: [1, 2]}`},
+		{expr: `eopa.object.merge(input.object, {"a": [2]}, {"arrays": "concat"})`, result: `{"a": [2]}`},
+		{expr: `eopa.object.merge({}, {}, {"arrays": "append"})`, err: `operand 3 arrays must be "concat" or "replace"`},
+		{expr: `eopa.object.merge(input.array, {}, {})`, err: `operand 1 must be object but got array`},
+	},
+	vm.CountAtLeastName: {
+		{expr: `eopa.count_at_least([1, 2, 3], 2)`, result: `true`},
+		{expr: `eopa.count_at_least([1, 2, 3], 3)`, result: `true`},
+		{expr: `eopa.count_at_least([1, 2, 3], 4)`, result: `false`},
+		{expr: `eopa.count_at_least({1, 2}, 2)`, result: `true`},
+		{expr: `eopa.count_at_least({"a": 1}, 0)`, result: `true`},
+		{expr: `eopa.count_at_least("héllo", 5)`, result: `true`},
+		{expr: `eopa.count_at_least("héllo", 6)`, result: `false`},
+		{expr: `eopa.count_at_least(input.object, 1)`, result: `true`},
+		{expr: `eopa.count_at_least([], -1)`, result: `true`},
+		{expr: `eopa.count_at_least([1], 1.5)`, err: `operand 2 must be integer number but got floating-point number`},
+		{expr: `eopa.count_at_least(input.number, 1)`, err: `operand 1 must be one of {array, object, set, string} but got number`},
+	},
+	vm.CountAtMostName: {
+		{expr: `eopa.count_at_most([1, 2, 3], 2)`, result: `false`},
+		{expr: `eopa.count_at_most([1, 2, 3], 3)`, result: `true`},
+		{expr: `eopa.count_at_most([1, 2, 3], 4)`, result: `true`},
+		{expr: `eopa.count_at_most({1, 2}, 2)`, result: `true`},
+		{expr: `eopa.count_at_most({"a": 1}, 0)`, result: `false`},
+		{expr: `eopa.count_at_most("héllo", 5)`, result: `true`},
+		{expr: `eopa.count_at_most("héllo", 6)`, result: `true`},
+		{expr: `eopa.count_at_most(input.object, 1)`, result: `true`},
+		{expr: `eopa.count_at_most([], -1)`, result: `false`},
+		{expr: `eopa.count_at_most([1], 1.5)`, err: `operand 2 must be integer number but got floating-point number`},
+		{expr: `eopa.count_at_most(input.number, 1)`, err: `operand 1 must be one of {array, object, set, string} but got number`},
+	},
+	vm.JWTDecodeSegmentName: {
+		{expr: `eopa.jwt.decode_segment("eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9")`, result: `{"alg": "HS256", "typ": "JWT"}`},
+		{expr: `eopa.jwt.decode_segment("eyJhIjpbMSwyXX0=")`, result: `{"a": [1, 2]}`},
+		{expr: `eopa.jwt.decode_segment("eyJhIjpbMSwyXX0")`, result: `{"a": [1, 2]}`},
+		{expr: `eopa.jwt.decode_segment("e30")`, result: `{}`},
+		{expr: `eopa.jwt.decode_segment("WzFd")`, err: `operand 1 must encode JSON object`},
+		{expr: `eopa.jwt.decode_segment("eyJh")`, err: `operand 1 must encode valid JSON`},
+		{expr: `eopa.jwt.decode_segment("!!")`, err: `operand 1 must be base64url encoded: illegal base64 data at input byte 0`},
+		{expr: `eopa.jwt.decode_segment(input.number)`, err: `operand 1 must be string but got number`},
+	},
+	vm.FindName: {
+		{expr: `eopa.find({"items": [{"id": 1}, {"id": 2}, {}]}, "$.items[*].id")`, result: `[1, 2]`},
+		{expr: `eopa.find({"a": {"id": 1, "b": [{"id": 2}]}}, "$..id")`, result: `[1, 2]`},
+		{expr: `eopa.find({"a": [1, 2, 3]}, "$['a'][-1]")`, result: `[3]`},
+		{expr: `eopa.find({"b": 2, "a": 1}, "$.*")`, result: `[1, 2]`},
+		{expr: `eopa.find({1: "a", "b": 2}, "$.*")`, result: `["a", 2]`},
+		{expr: `eopa.find({"a": 1}, "$.b")`, result: `[]`},
+		{expr: `eopa.find([{"a"}], "$[0]")`, result: `[{"a"}]`},
+		{expr: `eopa.find(input.object, "$.a")`, result: `[1]`},
+		{expr: `eopa.find({}, "a")`, err: `operand 2 query "a" does not start with $`},
+		{expr: `eopa.find({}, "$[?(@.a)]")`, err: `operand 2 query "$[?(@.a)]" has invalid index "?(@.a)" at offset 2`},
+		{expr: `eopa.find({}, input.number)`, err: `operand 2 must be string but got number`},
+	},
+	vm.MatchesName: {
+		{expr: `eopa.matches({"kind": "Pod", "metadata": {"namespace": "x", "name": "y"}}, {"kind": "Pod", "metadata": {"namespace": "_"}})`, result: `true`},
+		{expr: `eopa.matches({"kind": "Pod", "metadata": {"name": "y"}}, {"kind": "Pod", "metadata": {"namespace": "_"}})`, result: `false`},
+		{expr: `eopa.matches({"kind": "Service"}, {"kind": "Pod"})`, result: `false`},
+		{expr: `eopa.matches([1, {"a": 2, "b": 3}], ["_", {"a": 2}])`, result: `true`},
+		{expr: `eopa.matches([1, 2], ["_"])`, result: `false`},
+		{expr: `eopa.matches({"a": [1]}, {"a": {"0": 1}})`, result: `false`},
+		{expr: `eopa.matches({1: "a"}, {1: "_"})`, result: `true`},
+		{expr: `eopa.matches({"a"}, {"a"})`, result: `true`},
+		{expr: `eopa.matches("x", "_")`, result: `true`},
+		{expr: `eopa.matches(1, 1.0)`, result: `true`},
+		{expr: `eopa.matches(input.object, {"a": "_"})`, result: `true`},
+	},
+	vm.PointerGetName: {
+		{expr: `eopa.pointer.get({"a": {"b": [1, 2]}}, "/a/b/1")`, result: `2`},
+		{expr: `eopa.pointer.get({"a": {"b": [1, 2]}}, "/a/b/2")`},
+		{expr: `eopa.pointer.get({"a/b": {"~": 1}}, "/a~1b/~0")`, result: `1`},
+		{expr: `eopa.pointer.get({"a": 1}, "")`, result: `{"a": 1}`},
+		{expr: `eopa.pointer.get({"a": 1}, "/b")`},
+		{expr: `eopa.pointer.get({1: {"a": 1}}, "/1")`},
+		{expr: `eopa.pointer.get({"a": {"b"}}, "/a/b")`},
+		{expr: `eopa.pointer.get(input.object, "/a")`, result: `1`},
+		{expr: `eopa.pointer.get({}, "a")`, err: `operand 2 must be a JSON pointer`},
+		{expr: `eopa.pointer.get({}, input.number)`, err: `operand 2 must be string but got number`},
+	},
+	vm.PointerSetName: {
+		{expr: `eopa.pointer.set({"a": {"b": [1, 2]}}, "/a/b/1", 3)`, result: `{"a": {"b": [1, 3]}}`},
+		{expr: `eopa.pointer.set({"a": {"b": [1, 2]}}, "/a/b/-", 3)`, result: `{"a": {"b": [1, 2, 3]}}`},
+		{expr: `eopa.pointer.set({"a": {"b": [1, 2]}}, "/a/b/3", 3)`},
+		{expr: `eopa.pointer.set({"a": {"b": [1, 2]}}, "/a/c", {"d": 3})`, result: `{"a": {"b": [1, 2], "c": {"d": 3}}}`},
+		{expr: `eopa.pointer.set({"a": 1}, "/b/c", 2)`},
+		{expr: `eopa.pointer.set({"a": 1}, "", 2)`, result: `2`},
+		{expr: `eopa.pointer.set({1: "a"}, "/b", 2)`, result: `{1: "a", "b": 2}`},
+		{expr: `eopa.pointer.set(input.object, "/a", 1)`, result: `{"a": 1}`},
+		{expr: `eopa.pointer.set({}, "a", 1)`, err: `operand 2 must be a JSON pointer`},
+		{expr: `eopa.pointer.set({}, input.number, 1)`, err: `operand 2 must be string but got number`},
+	},
+}
+
+func init() {
+	// The type predicates and type_name accept operands of any type.
+	for _, name := range []string{
+		ast.IsArray.Name, ast.IsString.Name, ast.IsBoolean.Name, ast.IsObject.Name,
+		ast.IsSet.Name, ast.IsNumber.Name, ast.IsNull.Name, ast.TypeNameBuiltin.Name,
+	} {
+		for _, v := range values {
+			builtinCases[name] = append(builtinCases[name], fmt.Sprintf("%s(%s)", name, v))
+		}
+	}
+}
+
+// Builtins evaluates the cases of every builtin the VM implements
+// natively, and reports the failing cases to w. The builtins topdown
+// implements are evaluated with both the VM and topdown, and fail if they
+// disagree, while the EOPA extension builtins are evaluated with both and
+// fail if either result differs from the expected one. Builtins the VM
+// implements but the selftest has no cases for count as failures, while
+// those not registered with OPA are skipped. Builtins returns the number
+// of failures.
+func Builtins(ctx context.Context, w io.Writer) (int, error) {
+	var failures int
+
+	for _, name := range vm.NativeBuiltins() {
+		if _, ok := ast.BuiltinMap[name]; !ok {
+			fmt.Fprintf(w, "SKIP %s: not registered\n", name)
+			continue
+		}
+
+		var exprs []string
+		var check func(context.Context, int) (string, error)
+		if cases, ok := goldenCases[name]; ok {
+			for _, c := range cases {
+				exprs = append(exprs, c.expr)
+			}
+			check = func(ctx context.Context, i int) (string, error) {
+				return checkGolden(ctx, cases[i])
+			}
+		} else if cases, ok := builtinCases[name]; ok {
+			exprs = cases
+			check = func(ctx context.Context, i int) (string, error) {
+				return compare(ctx, cases[i])
+			}
+		} else {
+			fmt.Fprintf(w, "FAIL %s: no cases\n", name)
+			failures++
+			continue
+		}
+
+		var mismatches int
+		for i, expr := range exprs {
+			msg, err := check(ctx, i)
+			if err != nil {
+				return failures, fmt.Errorf("%s: %w", expr, err)
+			}
+			if msg != "" {
+				fmt.Fprintf(w, "FAIL %s: %s\n", expr, msg)
+				mismatches++
+			}
+		}
+
+		if mismatches > 0 {
+			failures += mismatches
+			continue
+		}
+
+		fmt.Fprintf(w, "PASS %s (%d cases)\n", name, len(exprs))
+	}
+
+	return failures, nil
+}
+
+// checkGolden evaluates the expression with both targets, and returns a
+// description of their divergence from the expected result, if any.
+func checkGolden(ctx context.Context, c goldenCase) (string, error) {
+	var expected ast.Value
+	if c.result != "" {
+		t, err := ast.ParseTerm(c.result)
+		if err != nil {
+			return "", fmt.Errorf("expected result: %w", err)
+		}
+		expected = t.Value
+	}
+
+	for _, target := range []string{VMTarget, TopdownTarget} {
+		result, evalErr, err := eval(ctx, target, c.expr)
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case c.err != "" && evalErr == nil:
+			return fmt.Sprintf("%s result %v, expected error %q", target, format(result), c.err), nil
+		case c.err != "" && !strings.Contains(evalErr.Error(), c.err):
+			return fmt.Sprintf("%s error %q, expected error %q", target, evalErr, c.err), nil
+		case c.err != "":
+		case evalErr != nil:
+			return fmt.Sprintf("%s error %q, expected result %v", target, evalErr, format(expected)), nil
+		case !equal(result, expected):
+			return fmt.Sprintf("%s result %v, expected result %v", target, format(result), format(expected)), nil
+		}
+	}
+
+	return "", nil
+}
+
+// compare evaluates the expression with both targets, and returns a
+// description of their divergence, if any. Errors are compared by their
+// presence only, as their messages are free to differ.
+func compare(ctx context.Context, expr string) (string, error) {
+	vmResult, vmErr, err := eval(ctx, VMTarget, expr)
+	if err != nil {
+		return "", err
+	}
+
+	tdResult, tdErr, err := eval(ctx, TopdownTarget, expr)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case vmErr != nil && tdErr != nil:
+		return "", nil
+	case vmErr != nil:
+		return fmt.Sprintf("vm error %q, topdown result %v", vmErr, format(tdResult)), nil
+	case tdErr != nil:
+		return fmt.Sprintf("vm result %v, topdown error %q", format(vmResult), tdErr), nil
+	case !equal(vmResult, tdResult):
+		return fmt.Sprintf("vm result %v, topdown result %v", format(vmResult), format(tdResult)), nil
+	}
+
+	return "", nil
+}
+
+// eval evaluates the expression with the target. Evaluation errors are
+// returned as the second value, while failures to prepare the query are
+// returned as the error: the case itself is broken.
+func eval(ctx context.Context, target string, expr string) (ast.Value, error, error) {
+	pq, err := rego.New(
+		rego.Query("x := "+expr),
+		rego.Input(input),
+		rego.Target(target),
+		rego.StrictBuiltinErrors(true),
+		rego.GenerateJSON(func(t *ast.Term, _ *rego.EvalContext) (any, error) {
+			return t.Value, nil // Keep the sets.
+		}),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rs, err := pq.Eval(ctx)
+	if err != nil {
+		return nil, err, nil
+	}
+
+	if len(rs) == 0 {
+		return nil, nil, nil // undefined
+	}
+
+	return rs[0].Bindings["x"].(ast.Value), nil, nil
+}
+
+// equal compares the values as well as their text: numbers equal in value
+// may be formatted differently by the engines.
+func equal(a, b ast.Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Compare(b) == 0 && a.String() == b.String()
+}
+
+func format(v ast.Value) string {
+	if v == nil {
+		return "undefined"
+	}
+	return v.String()
+}
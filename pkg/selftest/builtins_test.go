@@ -0,0 +1,114 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/ir"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/topdown"
+	"github.com/open-policy-agent/opa/v1/types"
+
+	"github.com/open-policy-agent/eopa/pkg/vm"
+)
+
+// vmTarget stands in for the rego_vm plugin, evaluating the queries
+// with the VM.
+type vmTarget struct{}
+
+type vmEval struct {
+	vm           *vm.VM
+	builtinFuncs map[string]*topdown.Builtin
+}
+
+func (vmTarget) IsTarget(t string) bool {
+	return t == VMTarget
+}
+
+func (vmTarget) PrepareForEval(_ context.Context, policy *ir.Policy, opts ...rego.PrepareOption) (rego.TargetPluginEval, error) {
+	po := &rego.PrepareConfig{}
+	for _, o := range opts {
+		o(po)
+	}
+
+	bis := po.BuiltinFuncs()
+	executable, err := vm.NewCompiler().WithPolicy(policy).WithBuiltins(bis).Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	return &vmEval{vm: vm.NewVM().WithExecutable(executable).WithDataJSON(map[string]any{}), builtinFuncs: bis}, nil
+}
+
+func (e *vmEval) Eval(ctx context.Context, ectx *rego.EvalContext, _ ast.Value) (ast.Value, error) {
+	_, ctx = vm.WithStatistics(ctx)
+	return e.vm.Eval(ctx, "eval", vm.EvalOpts{
+		Input:               ectx.RawInput(),
+		Time:                ectx.Time(),
+		StrictBuiltinErrors: ectx.StrictBuiltinErrors(),
+		BuiltinFuncs:        e.builtinFuncs,
+	})
+}
+
+func init() {
+	rego.RegisterPlugin("selftest_vm", vmTarget{})
+
+	// The EOPA builtins are declared by pkg/builtins, along with the
+	// builtins of the database drivers. Declare them loosely here, for
+	// the golden cases to be evaluated.
+	for name, cases := range goldenCases {
+		if _, ok := ast.BuiltinMap[name]; ok {
+			continue
+		}
+
+		args := make([]types.Type, arity(name, cases[0].expr))
+		for i := range args {
+			args[i] = types.A
+		}
+		ast.RegisterBuiltin(&ast.Builtin{Name: name, Decl: types.NewFunction(args, types.A)})
+		topdown.RegisterBuiltinFunc(name, vm.NativeBuiltin(name))
+	}
+}
+
+// arity returns the number of operands the builtin is called with in the
+// expression.
+func arity(name, expr string) int {
+	var n int
+	ast.WalkTerms(ast.MustParseBody("x := "+expr), func(t *ast.Term) bool {
+		if call, ok := t.Value.(ast.Call); ok && call[0].String() == name {
+			n = len(call) - 1
+		}
+		return false
+	})
+	return n
+}
+
+func TestBuiltins(t *testing.T) {
+	var buf bytes.Buffer
+	failures, err := Builtins(context.Background(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures != 0 {
+		t.Fatalf("expected no failures, got %d:\n%s", failures, buf.String())
+	}
+}
+
+func TestBuiltinsCovered(t *testing.T) {
+	for _, name := range vm.NativeBuiltins() {
+		switch {
+		case strings.HasPrefix(name, "eopa."):
+			if len(goldenCases[name]) == 0 {
+				t.Errorf("no golden cases for native builtin %s", name)
+			}
+		case len(builtinCases[name]) == 0:
+			t.Errorf("no cases for native builtin %s", name)
+		}
+	}
+}
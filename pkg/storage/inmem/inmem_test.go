@@ -657,6 +657,47 @@ func TestInMemoryContext(t *testing.T) {
 	}
 }
 
+func TestInMemoryReadBinaryArray(t *testing.T) {
+	ctx := context.Background()
+	store := NewFromObject(loadBinaryArrayTestData(t, 1000)).(*store)
+
+	// The array is read as a view over the binary data, not decoded.
+	value, err := readOne(ctx, store, storage.MustParsePath("/bigarray"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := value.(bjson.ArrayBinary); !ok {
+		t.Fatalf("expected binary array, got %T", value)
+	}
+
+	value, err = readOne(ctx, store, storage.MustParsePath("/bigarray/42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := bjson.NewFloatInt(42); exp.Compare(value) != 0 {
+		t.Fatalf("expected %v, got %v", exp, value)
+	}
+}
+
+func BenchmarkInMemoryReadBinaryArrayIndex(b *testing.B) {
+	ctx := context.Background()
+
+	for _, n := range []int{1000, 1000000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			store := NewFromObject(loadBinaryArrayTestData(b, n)).(*store)
+			path := storage.MustParsePath(fmt.Sprintf("/bigarray/%d", n/2))
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := readOne(ctx, store, path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func loadExpectedResult(input string) interface{} {
 	if len(input) == 0 {
 		return nil
@@ -759,6 +800,26 @@ func loadObjectBinaryTestData() bjson.Object {
 	return obj3
 }
 
+// loadBinaryArrayTestData returns binary data with an array of n
+// integers at "bigarray".
+func loadBinaryArrayTestData(tb testing.TB, n int) bjson.Json {
+	elems := make([]any, n)
+	for i := range elems {
+		elems[i] = i
+	}
+
+	bs, err := bjson.Marshal(bjson.MustNew(map[string]any{"bigarray": elems}))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	data, err := bjson.NewFromBinary(bs)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
 func readOne(ctx context.Context, db *store, path storage.Path) (bjson.Json, error) {
 	txn, err := db.NewTransaction(ctx)
 	if err != nil {
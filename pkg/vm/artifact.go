@@ -0,0 +1,168 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/ir"
+	"github.com/open-policy-agent/opa/v1/topdown"
+	opa_version "github.com/open-policy-agent/opa/v1/version"
+
+	eopa_version "github.com/open-policy-agent/eopa/internal/version"
+	"github.com/open-policy-agent/eopa/pkg/iropt"
+)
+
+const (
+	artifactMagic   = "rgoa"
+	artifactVersion = 1
+)
+
+var ErrInvalidArtifact = errors.New("invalid artifact")
+
+type (
+	// Artifact is a compiled policy, loadable without the bundle it was
+	// compiled from. Its binary form is versioned, and holds the
+	// executable with the metadata the runtime loading it needs.
+	Artifact struct {
+		Executable Executable
+		ArtifactMetadata
+	}
+
+	// ArtifactMetadata describes the executable of an artifact.
+	ArtifactMetadata struct {
+		// Entrypoints are the plans the executable evaluates.
+		Entrypoints []string `json:"entrypoints"`
+
+		// Builtins are the builtins the executable calls, which the
+		// runtime loading the artifact is required to provide.
+		Builtins []string `json:"builtins"`
+
+		// Features are the features of the runtime the artifact was
+		// built with, which the runtime loading the artifact is required
+		// to support.
+		Features []string `json:"features"`
+
+		// OPAVersion and EOPAVersion are the versions of the runtime the
+		// artifact was built with.
+		OPAVersion  string `json:"opa_version"`
+		EOPAVersion string `json:"eopa_version"`
+
+		// DataDigest identifies the schema of the data the policy was
+		// compiled against.
+		DataDigest string `json:"data_digest,omitempty"`
+	}
+)
+
+// BuildArtifact compiles the policy into an artifact, optimized and
+// checked against the builtin policy like the policies prepared for
+// evaluation. The builtins are the builtins the policy may call in
+// addition to the builtins of the runtime, like for the compiler.
+func BuildArtifact(policy *ir.Policy, builtins map[string]*topdown.Builtin, dataDigest string) (Artifact, error) {
+	optimizedPolicy, err := iropt.RunPasses(policy, iropt.RegoVMIROptimizationPassSchedule)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	executable, err := NewCompiler().WithPolicy(optimizedPolicy).WithBuiltins(builtins).WithBuiltinPolicy(GetBuiltinPolicy()).Compile()
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	entrypoints := make([]string, 0, len(policy.Plans.Plans))
+	for _, plan := range policy.Plans.Plans {
+		entrypoints = append(entrypoints, plan.Name)
+	}
+
+	return Artifact{
+		Executable: executable,
+		ArtifactMetadata: ArtifactMetadata{
+			Entrypoints: entrypoints,
+			Builtins:    executable.UsedBuiltins(),
+			Features:    ast.CapabilitiesForThisVersion().Features,
+			OPAVersion:  opa_version.Version,
+			EOPAVersion: eopa_version.Version,
+			DataDigest:  dataDigest,
+		},
+	}, nil
+}
+
+// LoadArtifact decodes an artifact, failing if its version is not
+// supported, or the runtime misses any builtin or feature the artifact
+// requires. The builtins are those the runtime provides in addition to
+// its builtins. The executable refers to the data, which must not be
+// modified.
+func LoadArtifact(data []byte, builtins map[string]*topdown.Builtin) (Artifact, error) {
+	if len(data) < 12 || !bytes.Equal(data[:4], []byte(artifactMagic)) {
+		return Artifact{}, ErrInvalidArtifact
+	}
+
+	if v := getUint32(data, 4); v != artifactVersion {
+		return Artifact{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidArtifact, v)
+	}
+
+	n := getUint32(data, 8)
+	if uint64(n) > uint64(len(data)-12) {
+		return Artifact{}, ErrInvalidArtifact
+	}
+
+	var a Artifact
+	if err := json.Unmarshal(data[12:12+n], &a.ArtifactMetadata); err != nil {
+		return Artifact{}, fmt.Errorf("%w: %v", ErrInvalidArtifact, err)
+	}
+
+	a.Executable = Executable(data[12+n:])
+	if !a.Executable.IsValid() {
+		return Artifact{}, ErrInvalidExecutable
+	}
+
+	if err := a.CheckCapabilities(builtins); err != nil {
+		return Artifact{}, err
+	}
+
+	return a, nil
+}
+
+// CheckCapabilities returns an error if any builtin the artifact requires
+// is neither a builtin of the runtime nor in the builtins provided, or if
+// the runtime does not support any feature the artifact requires.
+func (a Artifact) CheckCapabilities(builtins map[string]*topdown.Builtin) error {
+	for _, name := range a.Builtins {
+		if _, ok := builtins[name]; ok {
+			continue
+		}
+		if topdown.GetBuiltin(name) == nil {
+			return fmt.Errorf("artifact requires builtin not provided by the runtime: %s", name)
+		}
+	}
+
+	features := ast.CapabilitiesForThisVersion().Features
+	for _, feature := range a.Features {
+		if !slices.Contains(features, feature) {
+			return fmt.Errorf("artifact built by OPA %s, EOPA %s requires feature not supported by the runtime: %s", a.OPAVersion, a.EOPAVersion, feature)
+		}
+	}
+	return nil
+}
+
+// MarshalBinary encodes the artifact, for LoadArtifact to decode.
+func (a Artifact) MarshalBinary() ([]byte, error) {
+	metadata, err := json.Marshal(a.ArtifactMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	d := make([]byte, 0, 12+len(metadata)+len(a.Executable))
+	d = append(d, artifactMagic...)
+	d = appendUint32(d, artifactVersion)
+	d = appendUint32(d, uint32(len(metadata)))
+	d = append(d, metadata...)
+	d = append(d, a.Executable...)
+	return d, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"errors"
+	"slices"
+	gostrings "strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/topdown"
+)
+
+func TestArtifact(t *testing.T) {
+	rego := "package test\nallow if count(input.roles) > 1"
+	policy := setup(t, rego, "test/allow")
+
+	artifact, err := BuildArtifact(&policy, nil, "sha256:digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := artifact.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadArtifact(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(loaded.Entrypoints, []string{"test/allow"}) || loaded.DataDigest != "sha256:digest" {
+		t.Fatalf("unexpected metadata %+v", loaded.ArtifactMetadata)
+	}
+	if !slices.Contains(loaded.Builtins, "count") {
+		t.Fatalf("expected count required, got %v", loaded.Builtins)
+	}
+	if !slices.Equal(loaded.Features, ast.CapabilitiesForThisVersion().Features) || loaded.OPAVersion == "" || loaded.EOPAVersion == "" {
+		t.Fatalf("unexpected capabilities %+v", loaded.ArtifactMetadata)
+	}
+
+	input := any(map[string]any{"roles": []any{"a", "b"}})
+	_, ctx := WithStatistics(context.Background())
+	result, err := NewVM().WithExecutable(loaded.Executable).Eval(ctx, "test/allow", EvalOpts{
+		Input: &input,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ast.MustParseTerm(`{{"result": true}}`).Value.Compare(result) != 0 {
+		t.Fatalf("unexpected result %v", result)
+	}
+
+	// A runtime missing a required builtin fails the load.
+	artifact.Builtins = append(artifact.Builtins, "test.missing")
+	data, err = artifact.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadArtifact(data, nil); err == nil || err.Error() != "artifact requires builtin not provided by the runtime: test.missing" {
+		t.Fatalf("expected missing builtin error, got %v", err)
+	}
+
+	// Unless the runtime provides it.
+	if _, err := LoadArtifact(data, map[string]*topdown.Builtin{"test.missing": {}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A runtime missing a required feature fails the load.
+	artifact.Features = append(artifact.Features, "test_feature")
+	data, err = artifact.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadArtifact(data, map[string]*topdown.Builtin{"test.missing": {}}); err == nil || !gostrings.Contains(err.Error(), "requires feature not supported by the runtime: test_feature") {
+		t.Fatalf("expected missing feature error, got %v", err)
+	}
+
+	// Unsupported versions and corrupted artifacts fail the load.
+	putUint32(data, 4, artifactVersion+1)
+	if _, err := LoadArtifact(data, nil); !errors.Is(err, ErrInvalidArtifact) {
+		t.Fatalf("expected invalid artifact, got %v", err)
+	}
+	if _, err := LoadArtifact(data[:8], nil); !errors.Is(err, ErrInvalidArtifact) {
+		t.Fatalf("expected invalid artifact, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	gostrings "strings"
+	"sync/atomic"
+
+	"github.com/open-policy-agent/opa/v1/config"
+	"github.com/open-policy-agent/opa/v1/ir"
+)
+
+type (
+	// BuiltinPolicy restricts the builtins the compiled policies may
+	// call, for instance to forbid the network access of untrusted
+	// policies. A builtin is allowed if it is in the allow list, or the
+	// allow list is nil, and it is not in the deny list. The builtins
+	// the compiler inserts, named "internal.*", are always allowed.
+	//
+	// The builtin policy is under "extra/builtin_policy" in OPA config.
+	BuiltinPolicy struct {
+		Allow []string `json:"allow"`
+		Deny  []string `json:"deny"`
+	}
+
+	// BuiltinPolicyHook receives OPA configuration change callbacks
+	// and sets the builtin policy the VM compiler enforces.
+	BuiltinPolicyHook struct{}
+
+	// DisallowedBuiltinError is returned by the compiler if the policy
+	// calls a builtin the builtin policy disallows.
+	DisallowedBuiltinError struct {
+		Builtin string // Name of the builtin.
+		Rule    string // Rule or query calling the builtin.
+		Denied  bool   // True if denied, false if not allowed.
+	}
+)
+
+var builtinPolicy atomic.Pointer[BuiltinPolicy]
+
+// SetBuiltinPolicy sets the builtin policy enforced when compiling the
+// policies with the VM compiler; nil removes the restrictions.
+func SetBuiltinPolicy(p *BuiltinPolicy) {
+	builtinPolicy.Store(p)
+}
+
+// GetBuiltinPolicy returns the builtin policy set with SetBuiltinPolicy.
+func GetBuiltinPolicy() *BuiltinPolicy {
+	return builtinPolicy.Load()
+}
+
+// Check returns an error if any of the builtins is disallowed. The
+// error does not name the rule calling the builtin.
+func (p *BuiltinPolicy) Check(names []string) error {
+	if p == nil {
+		return nil
+	}
+
+	for _, name := range names {
+		if gostrings.HasPrefix(name, "internal.") {
+			continue
+		}
+
+		if slices.Contains(p.Deny, name) {
+			return &DisallowedBuiltinError{Builtin: name, Denied: true}
+		}
+		if p.Allow != nil && !slices.Contains(p.Allow, name) {
+			return &DisallowedBuiltinError{Builtin: name}
+		}
+	}
+	return nil
+}
+
+func (e *DisallowedBuiltinError) Error() string {
+	reason := "is not in the allow list of the builtin policy"
+	if e.Denied {
+		reason = "is denied by the builtin policy"
+	}
+
+	if e.Rule == "" {
+		return fmt.Sprintf("builtin %s %s", e.Builtin, reason)
+	}
+	return fmt.Sprintf("builtin %s called by %s %s", e.Builtin, e.Rule, reason)
+}
+
+// builtinCaller returns the name of the first rule, or query plan,
+// calling the builtin.
+func builtinCaller(policy *ir.Policy, name string) string {
+	for _, fn := range policy.Funcs.Funcs {
+		if callsBuiltin(fn, name) {
+			if len(fn.Path) > 1 {
+				return "data." + gostrings.Join(fn.Path[1:], ".") // Skip the function group, "g0".
+			}
+			return fn.Name
+		}
+	}
+
+	for _, plan := range policy.Plans.Plans {
+		if callsBuiltin(plan, name) {
+			return plan.Name
+		}
+	}
+
+	return ""
+}
+
+func callsBuiltin(x any, name string) bool {
+	v := &builtinCallVisitor{name: name}
+	_ = ir.Walk(v, x)
+	return v.found
+}
+
+type builtinCallVisitor struct {
+	name  string
+	found bool
+}
+
+func (*builtinCallVisitor) Before(any) {}
+
+func (*builtinCallVisitor) After(any) {}
+
+func (v *builtinCallVisitor) Visit(x any) (ir.Visitor, error) {
+	if call, ok := x.(*ir.CallStmt); ok && call.Func == v.name {
+		v.found = true
+	}
+	if v.found {
+		return nil, nil
+	}
+	return v, nil
+}
+
+func NewBuiltinPolicyHook() *BuiltinPolicyHook {
+	return &BuiltinPolicyHook{}
+}
+
+func (h *BuiltinPolicyHook) OnConfigDiscovery(ctx context.Context, conf *config.Config) (*config.Config, error) {
+	return h.onConfig(ctx, conf)
+}
+
+func (h *BuiltinPolicyHook) OnConfig(ctx context.Context, conf *config.Config) (*config.Config, error) {
+	return h.onConfig(ctx, conf)
+}
+
+func (*BuiltinPolicyHook) onConfig(_ context.Context, conf *config.Config) (*config.Config, error) {
+	raw := conf.Extra["builtin_policy"]
+	if raw == nil {
+		SetBuiltinPolicy(nil)
+		return conf, nil
+	}
+
+	var policy BuiltinPolicy
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return conf, err
+	}
+
+	SetBuiltinPolicy(&policy)
+	return conf, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/config"
+)
+
+func TestBuiltinPolicy(t *testing.T) {
+	rego := `package test
+
+allow if {
+	count(input.roles) > 0
+	fetch.status_code == 200
+}
+
+fetch := http.send({"method": "GET", "url": input.url})
+`
+
+	policy := setup(t, rego, "test/allow")
+	executable, err := NewCompiler().WithPolicy(&policy).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"count", "http.send", "gt"} {
+		if !slices.Contains(executable.UsedBuiltins(), name) {
+			t.Fatalf("expected %s used, got %v", name, executable.UsedBuiltins())
+		}
+	}
+
+	for _, tc := range []struct {
+		note   string
+		policy *BuiltinPolicy
+		err    string
+	}{
+		{
+			note: "no policy",
+		},
+		{
+			note:   "denied",
+			policy: &BuiltinPolicy{Deny: []string{"opa.runtime", "http.send"}},
+			err:    "builtin http.send called by data.test.fetch is denied by the builtin policy",
+		},
+		{
+			note:   "not allowed",
+			policy: &BuiltinPolicy{Allow: []string{"count", "gt"}},
+			err:    "builtin http.send called by data.test.fetch is not in the allow list of the builtin policy",
+		},
+		{
+			note:   "allowed",
+			policy: &BuiltinPolicy{Allow: []string{"count", "gt", "http.send"}, Deny: []string{"opa.runtime"}},
+		},
+	} {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := NewCompiler().WithPolicy(&policy).WithBuiltinPolicy(tc.policy).Compile()
+			switch {
+			case tc.err == "" && err != nil:
+				t.Fatal(err)
+			case tc.err != "":
+				var disallowed *DisallowedBuiltinError
+				if !errors.As(err, &disallowed) || err.Error() != tc.err {
+					t.Fatalf("expected error %q, got %v", tc.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBuiltinPolicyHook(t *testing.T) {
+	defer SetBuiltinPolicy(nil)
+
+	hook := NewBuiltinPolicyHook()
+
+	if _, err := hook.OnConfig(context.Background(), &config.Config{
+		Extra: map[string]json.RawMessage{"builtin_policy": json.RawMessage(`{"deny": ["http.send"]}`)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if p := GetBuiltinPolicy(); p == nil || !slices.Equal(p.Deny, []string{"http.send"}) || p.Allow != nil {
+		t.Fatalf("unexpected policy %v", p)
+	}
+
+	if _, err := hook.OnConfig(context.Background(), &config.Config{
+		Extra: map[string]json.RawMessage{"builtin_policy": json.RawMessage(`{"deny": "http.send"}`)},
+	}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if _, err := hook.OnConfig(context.Background(), &config.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if p := GetBuiltinPolicy(); p != nil {
+		t.Fatalf("expected no policy, got %v", p)
+	}
+}
@@ -4,6 +4,7 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"math/big"
@@ -45,6 +46,22 @@ func memberBuiltin(state *State, args []Value) error {
 		return nil
 	}
 
+	// Sets are hashed, and are looked up instead of scanned. Objects are
+	// scanned too, membership testing their values, not keys.
+	isSet, err := state.ValueOps().IsSet(state.Globals.Ctx, args[1])
+	if err != nil {
+		return err
+	}
+	if isSet {
+		_, found, err := state.ValueOps().Get(state.Globals.Ctx, args[1], args[0])
+		if err != nil {
+			return err
+		}
+
+		state.SetReturnValue(Unused, state.ValueOps().MakeBoolean(found))
+		return nil
+	}
+
 	var found bool
 
 	if err := func(f func(key, value any) (bool, error)) error {
@@ -65,12 +82,18 @@ func memberWithKeyBuiltin(state *State, args []Value) error {
 		return nil
 	}
 
+	// Like topdown, sets have no keys to match.
+	isSet, err := state.ValueOps().IsSet(state.Globals.Ctx, args[2])
+	if err != nil {
+		return err
+	}
+
 	var eq bool
 	v, ok, err := state.ValueOps().Get(state.Globals.Ctx, args[2], args[0])
 	if err != nil {
 		return err
 	}
-	if ok {
+	if ok && !isSet {
 		var err error
 		eq, err = state.ValueOps().Equal(state.Globals.Ctx, args[1], v)
 		if err != nil {
@@ -102,23 +125,38 @@ func objectGetBuiltin(state *State, args []Value) error {
 		return nil
 	}
 
-	if isPath, err := state.ValueOps().IsArray(state.Globals.Ctx, path); err != nil {
+	value, found, err := objectGetPath(state, obj, path)
+	if err != nil {
 		return err
+	}
+
+	if found {
+		state.SetReturnValue(Unused, value)
+	} else {
+		state.SetReturnValue(Unused, def)
+	}
+	return nil
+}
+
+// objectGetPath looks up the value at the path, which is either a
+// single key or an array of keys, within the object.
+func objectGetPath(state *State, obj, path Value) (Value, bool, error) {
+	if isPath, err := state.ValueOps().IsArray(state.Globals.Ctx, path); err != nil {
+		return nil, false, err
 	} else if !isPath {
-		return objectGetBuiltinKey(state, obj, path, def)
+		return state.ValueOps().ObjectGet(state.Globals.Ctx, obj, path)
 	}
 
 	length, err := state.ValueOps().Len(state.Globals.Ctx, path)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
 	eq, err := state.ValueOps().Equal(state.Globals.Ctx, length, state.ValueOps().MakeNumberZero())
 	if err != nil {
-		return err
+		return nil, false, err
 	}
 	if eq {
-		state.SetReturnValue(Unused, obj)
-		return nil
+		return obj, true, nil
 	}
 
 	var found bool
@@ -129,15 +167,10 @@ func objectGetBuiltin(state *State, args []Value) error {
 		obj, found, _ = state.ValueOps().Get(state.Globals.Ctx, obj, v)
 		return !found, nil // always iterate path array to the end if found
 	}); err != nil {
-		return err
+		return nil, false, err
 	}
 
-	if found {
-		state.SetReturnValue(Unused, obj)
-	} else {
-		state.SetReturnValue(Unused, def)
-	}
-	return nil
+	return obj, found, nil
 }
 
 func objectKeysBuiltin(state *State, args []Value) error {
@@ -277,19 +310,6 @@ func objectSelect(state *State, args []Value, keep bool) error {
 	return nil
 }
 
-func objectGetBuiltinKey(state *State, obj, key, def Value) error {
-	val, found, err := state.ValueOps().ObjectGet(state.Globals.Ctx, obj, key)
-	if err != nil {
-		return err
-	}
-	if found {
-		state.SetReturnValue(Unused, val)
-	} else {
-		state.SetReturnValue(Unused, def)
-	}
-	return nil
-}
-
 func stringsConcatBuiltin(state *State, args []Value) error {
 	if isUndefinedType(args[1]) || isUndefinedType(args[0]) {
 		return nil
@@ -426,6 +446,34 @@ func stringsStartsWithBuiltin(state *State, args []Value) error {
 	return nil
 }
 
+func stringsUpperBuiltin(state *State, args []Value) error {
+	return stringsCaseBuiltin(state, args, gostrings.ToUpper)
+}
+
+func stringsLowerBuiltin(state *State, args []Value) error {
+	return stringsCaseBuiltin(state, args, gostrings.ToLower)
+}
+
+// stringsCaseBuiltin maps the case of the string operand, locale
+// independently, as topdown does.
+func stringsCaseBuiltin(state *State, args []Value, f func(string) string) error {
+	if isUndefinedType(args[0]) {
+		return nil
+	}
+
+	s, ok, err := builtinStringOperand(state, args[0], 1)
+	if err != nil || !ok {
+		return err
+	}
+
+	if c := f(s); c != s {
+		state.SetReturnValue(Unused, state.ValueOps().MakeString(c))
+	} else {
+		state.SetReturnValue(Unused, args[0])
+	}
+	return nil
+}
+
 func stringsSprintfBuiltin(state *State, args []Value) error {
 	if isUndefinedType(args[0]) || isUndefinedType(args[1]) {
 		return nil
@@ -541,6 +589,99 @@ func countBuiltin(state *State, args []Value) error {
 	return nil
 }
 
+func plusBuiltin(state *State, args []Value) error {
+	return arithmeticBuiltin(state, args, fjson.Float.Add)
+}
+
+func multiplyBuiltin(state *State, args []Value) error {
+	return arithmeticBuiltin(state, args, fjson.Float.Multiply)
+}
+
+// arithmeticBuiltin applies the operation on the number operands. Unlike
+// topdown, it is exact for integers of any magnitude.
+func arithmeticBuiltin(state *State, args []Value, op func(a, b fjson.Float) fjson.Float) error {
+	if isUndefinedType(args[0]) || isUndefinedType(args[1]) {
+		return nil
+	}
+
+	a, err := builtinNumberOperand(state, args[0], 1)
+	if err != nil || a == nil {
+		return err
+	}
+
+	b, err := builtinNumberOperand(state, args[1], 2)
+	if err != nil || b == nil {
+		return err
+	}
+
+	state.SetReturnValue(Unused, op(*a, *b))
+	return nil
+}
+
+// minusBuiltin subtracts the numbers, exactly for integers as plus, or
+// the sets.
+func minusBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[0]) || isUndefinedType(args[1]) {
+		return nil
+	}
+
+	switch a := args[0].(type) {
+	case fjson.Float:
+		if b, ok := args[1].(fjson.Float); ok {
+			state.SetReturnValue(Unused, a.Sub(b))
+			return nil
+		}
+
+	case fjson.Set:
+		b, ok := args[1].(fjson.Set)
+		if !ok {
+			break
+		}
+
+		diff := fjson.NewSet(a.Len())
+		if _, err := a.Iter(func(v fjson.Json) (bool, error) {
+			if _, ok := b.Get(v); !ok {
+				diff = diff.Add(v)
+			}
+			return false, nil
+		}); err != nil {
+			return err
+		}
+
+		state.SetReturnValue(Unused, diff)
+		return nil
+
+	default:
+		v, err := state.ValueOps().ToAST(state.Globals.Ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+			Code:    topdown.TypeErr,
+			Message: builtins.NewOperandTypeErr(1, v, "number", "set").Error(),
+		})
+		return nil
+	}
+
+	// As topdown, expecting a set only after a number.
+	expected := "number"
+	if _, ok := args[1].(fjson.Float); ok {
+		expected = "set"
+	}
+
+	v, err := state.ValueOps().ToAST(state.Globals.Ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+		Code:    topdown.TypeErr,
+		Message: builtins.NewOperandTypeErr(2, v, expected).Error(),
+	})
+	return nil
+}
+
 func builtinStringOperand(state *State, value Value, pos int) (string, bool, error) {
 	s, ok := value.(*fjson.String)
 	if ok {
@@ -693,6 +834,10 @@ func walkBuiltin(state *State, args []Value) error {
 	return err
 }
 
+// errWalkStop stops the walk of do, once record has found what it
+// looks for.
+var errWalkStop = errors.New("walk stopped")
+
 func do(state *State, path Value, val Value, record func(*State, Value, Value) error) error {
 	if err := record(state, path, val); err != nil {
 		return err
@@ -1103,8 +1248,8 @@ func globMatchBuiltin(state *State, args []Value) error {
 const metricGlobCacheHit = "rego_builtin_glob_interquery_value_cache_hits"
 
 func globCompile(m metrics.Metrics, c cache.InterQueryValueCache, id, pattern string, delimiters []rune) (glob.Glob, error) {
-	if c == nil { // 'eopa eval', just do it
-		return glob.Compile(pattern, delimiters...)
+	if c == nil { // 'eopa eval', or no inter-query value cache configured
+		return patterns.glob(pattern, delimiters)
 	}
 
 	val, ok := c.Get(ast.String(id))
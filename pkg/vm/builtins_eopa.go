@@ -0,0 +1,1169 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	gostrings "strings"
+
+	"golang.org/x/net/idna"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/topdown"
+	"github.com/open-policy-agent/opa/v1/topdown/builtins"
+
+	fjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+// Names of the EOPA extension builtins implemented natively by the VM.
+// Their declarations are registered by the builtins package.
+const (
+	ObjectGetMergedName  = "eopa.object.get_merged"
+	ObjectHasPathName    = "eopa.object.has_path"
+	NumbersParseBaseName = "eopa.numbers.parse_base"
+	NumbersToBaseName    = "eopa.numbers.to_base"
+	SetToSortedArrayName = "eopa.set.to_sorted_array"
+	ArrayToSetName       = "eopa.array.to_set"
+	ArrayIndexOfName     = "eopa.array.indexof"
+	ObjectDiffName       = "eopa.object.diff"
+	WalkUntilName        = "eopa.walk_until"
+	HashName             = "eopa.hash"
+	SizeBytesName        = "eopa.size_bytes"
+	IsValidEmailName     = "eopa.is_valid_email"
+	IsValidHostnameName  = "eopa.is_valid_hostname"
+	ParseURLName         = "eopa.parse_url"
+	SampleName           = "eopa.sample"
+	ObjectMergeName      = "eopa.object.merge"
+	CountAtLeastName     = "eopa.count_at_least"
+	CountAtMostName      = "eopa.count_at_most"
+	JWTDecodeSegmentName = "eopa.jwt.decode_segment"
+	FindName             = "eopa.find"
+	MatchesName          = "eopa.matches"
+	PointerGetName       = "eopa.pointer.get"
+	PointerSetName       = "eopa.pointer.set"
+)
+
+// NativeBuiltins returns the names of the builtins the VM implements
+// natively, sorted.
+func NativeBuiltins() []string {
+	names := make([]string, 0, len(specializedBuiltins))
+	for name := range specializedBuiltins {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// NativeBuiltin returns a topdown implementation of a builtin the VM
+// implements natively. It evaluates the native implementation on a
+// standalone state, and is used whenever the builtin is evaluated
+// outside of the VM.
+func NativeBuiltin(name string) topdown.BuiltinFunc {
+	num, ok := specializedBuiltins[name]
+	if !ok {
+		panic(fmt.Sprintf("native builtin not found: %s", name))
+	}
+
+	impl := specializedBuiltinsByNum[num]
+
+	return func(bctx topdown.BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+		globals := &Globals{
+			vm:                          NewVM(),
+			Ctx:                         bctx.Context,
+			Metrics:                     bctx.Metrics,
+			Seed:                        bctx.Seed,
+			Cache:                       bctx.Cache,
+			InterQueryBuiltinCache:      bctx.InterQueryBuiltinCache,
+			InterQueryBuiltinValueCache: bctx.InterQueryBuiltinValueCache,
+			Capabilities:                bctx.Capabilities,
+			Runtime:                     bctx.Runtime,
+			Limits:                      DefaultLimits,
+			memoize:                     []map[k]Value{{}},
+		}
+
+		state := newState(globals, &Statistics{})
+		defer state.Release()
+
+		args := make([]Value, len(operands))
+		for i := range operands {
+			v, err := state.ValueOps().FromInterface(bctx.Context, operands[i].Value)
+			if err != nil {
+				return err
+			}
+			args[i] = v
+		}
+
+		if err := impl(state, args); err != nil {
+			return err
+		}
+
+		if len(globals.BuiltinErrors) > 0 {
+			err := globals.BuiltinErrors[0]
+			var terr *topdown.Error
+			if errors.As(err, &terr) {
+				// Let topdown qualify the message with the builtin name.
+				switch terr.Code {
+				case topdown.TypeErr:
+					return builtins.ErrOperand(terr.Message)
+				case topdown.BuiltinErr:
+					return errors.New(terr.Message)
+				}
+			}
+			return err
+		}
+
+		ret, ok := state.Return()
+		if !ok {
+			return nil
+		}
+
+		v, err := state.ValueOps().ToAST(bctx.Context, state.Local(ret))
+		if err != nil {
+			return err
+		}
+
+		return iter(ast.NewTerm(v))
+	}
+}
+
+func objectGetMergedBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[2]) || isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+	obj, path, def := args[0], args[1], args[2]
+
+	if ok, err := builtinObjectOperand(state, obj, 1); err != nil || !ok {
+		return err
+	}
+
+	value, found, err := objectGetPath(state, obj, path)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		state.SetReturnValue(Unused, def)
+		return nil
+	}
+
+	// Only objects found are merged over the default, any other
+	// value replaces it.
+
+	if ok, err := state.ValueOps().IsObject(state.Globals.Ctx, value); err != nil {
+		return err
+	} else if !ok {
+		state.SetReturnValue(Unused, value)
+		return nil
+	}
+
+	if ok, err := state.ValueOps().IsObject(state.Globals.Ctx, def); err != nil {
+		return err
+	} else if !ok {
+		state.SetReturnValue(Unused, value)
+		return nil
+	}
+
+	a, err := castJSON(state.Globals.Ctx, def)
+	if err != nil {
+		return err
+	}
+
+	b, err := castJSON(state.Globals.Ctx, value)
+	if err != nil {
+		return err
+	}
+
+	state.SetReturnValue(Unused, fjson.UnionObjects(a, b))
+	return nil
+}
+
+func objectHasPathBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	if ok, err := builtinObjectOperand(state, args[0], 1); err != nil || !ok {
+		return err
+	}
+
+	_, found, err := objectGetPath(state, args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	state.SetReturnValue(Unused, state.ValueOps().MakeBoolean(found))
+	return nil
+}
+
+func numbersParseBaseBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	s, ok, err := builtinStringOperand(state, args[0], 1)
+	if err != nil || !ok {
+		return err
+	}
+
+	base, ok, err := builtinBaseOperand(state, args[1], 2)
+	if err != nil || !ok {
+		return err
+	}
+
+	i, err := strconv.ParseInt(s, base, 64)
+	if err != nil {
+		state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+			Code:    topdown.BuiltinErr,
+			Message: builtins.NewOperandErr(1, "invalid base %d number: %q", base, s).Error(),
+		})
+		return nil
+	}
+
+	state.SetReturnValue(Unused, fjson.NewFloatInt(i))
+	return nil
+}
+
+func numbersToBaseBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	n, ok, err := builtinIntegerOperand(state, args[0], 1)
+	if err != nil || !ok {
+		return err
+	}
+
+	base, ok, err := builtinBaseOperand(state, args[1], 2)
+	if err != nil || !ok {
+		return err
+	}
+
+	state.SetReturnValue(Unused, state.ValueOps().MakeString(strconv.FormatInt(int64(n), base)))
+	return nil
+}
+
+// builtinBaseOperand returns the integer operand if it's a base
+// supported by strconv, that is, between 2 and 36.
+func builtinBaseOperand(state *State, value Value, pos int) (int, bool, error) {
+	base, ok, err := builtinIntegerOperand(state, value, pos)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+
+	if base < 2 || base > 36 {
+		state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+			Code:    topdown.BuiltinErr,
+			Message: builtins.NewOperandErr(pos, "base must be between 2 and 36").Error(),
+		})
+		return 0, false, nil
+	}
+
+	return base, true, nil
+}
+
+func setToSortedArrayBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[0]) {
+		return nil
+	}
+
+	set, err := builtinSetOperand(state, args[0], 1)
+	if err != nil || set == nil {
+		return err
+	}
+
+	// Sort in the AST order, which is the order of sort() and the
+	// order sets are serialized in. fjson sets and objects can't be
+	// compared with Json.Compare.
+	type element struct {
+		v fjson.Json
+		a ast.Value
+	}
+
+	elements := make([]element, 0, set.Len())
+	if _, err := set.Iter(func(v fjson.Json) (bool, error) {
+		elements = append(elements, element{v: v, a: v.AST()})
+		return false, nil
+	}); err != nil {
+		return err
+	}
+
+	slices.SortFunc(elements, func(a, b element) int {
+		return a.a.Compare(b.a)
+	})
+
+	result := make([]fjson.File, len(elements))
+	for i := range elements {
+		result[i] = elements[i].v
+	}
+
+	state.SetReturnValue(Unused, fjson.NewArray(result, len(result)))
+	return nil
+}
+
+func arrayToSetBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[0]) {
+		return nil
+	}
+
+	arr, err := builtinArrayOperand(state, args[0], 1)
+	if err != nil || arr == nil {
+		return err
+	}
+
+	n := arr.Len()
+	set := fjson.NewSet(n)
+	for i := 0; i < n; i++ {
+		set = set.Add(arr.Iterate(i))
+	}
+
+	state.SetReturnValue(Unused, set)
+	return nil
+}
+
+func arrayIndexOfBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	arr, err := builtinArrayOperand(state, args[0], 1)
+	if err != nil || arr == nil {
+		return err
+	}
+
+	elem, err := castJSON(state.Globals.Ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i, n := 0, arr.Len(); i < n; i++ {
+		if fjson.Equal(arr.Iterate(i), elem) {
+			index = i
+			break
+		}
+	}
+
+	state.SetReturnValue(Unused, fjson.NewFloatInt(int64(index)))
+	return nil
+}
+
+func objectDiffBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	if ok, err := builtinObjectOperand(state, args[0], 1); !ok || err != nil {
+		return err
+	}
+
+	if ok, err := builtinObjectOperand(state, args[1], 2); !ok || err != nil {
+		return err
+	}
+
+	a, err := castJSON(state.Globals.Ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	b, err := castJSON(state.Globals.Ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	patch := fjson.Diff(a, b)
+
+	ops := make([]fjson.File, len(patch))
+	for i, op := range patch {
+		m := map[string]fjson.File{
+			"op":   fjson.NewString(string(op.Op)),
+			"path": fjson.NewString(op.Path),
+		}
+		if op.Value != nil {
+			m["value"] = op.Value
+		}
+		ops[i] = fjson.NewObject(m)
+	}
+
+	state.SetReturnValue(Unused, fjson.NewArray(ops, len(ops)))
+	return nil
+}
+
+func walkUntilBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+	pattern := args[1]
+
+	var found Value
+	err := do(state, state.ValueOps().MakeArray(0), args[0], func(state *State, path, val Value) error {
+		if ok, err := walkMatch(state, pattern, val); err != nil || !ok {
+			return err
+		}
+
+		tuple, _, err := state.ValueOps().ArrayAppend(state.Globals.Ctx, state.ValueOps().MakeArray(2), path)
+		if err != nil {
+			return err
+		}
+		found, _, err = state.ValueOps().ArrayAppend(state.Globals.Ctx, tuple, val)
+		if err != nil {
+			return err
+		}
+		return errWalkStop
+	})
+	if err != nil && !errors.Is(err, errWalkStop) {
+		return err
+	}
+
+	if found != nil {
+		state.SetReturnValue(Unused, found)
+	}
+	return nil
+}
+
+// walkMatch checks if the value matches the pattern of walk_until: an
+// object pattern matches the objects having all its members, and any
+// other pattern matches equal values.
+func walkMatch(state *State, pattern, val Value) (bool, error) {
+	ctx := state.Globals.Ctx
+	ops := state.ValueOps()
+
+	if isObj, err := ops.IsObject(ctx, pattern); err != nil || !isObj {
+		if err != nil {
+			return false, err
+		}
+		return ops.Equal(ctx, pattern, val)
+	}
+
+	if isObj, err := ops.IsObject(ctx, val); err != nil || !isObj {
+		return false, err
+	}
+
+	match := true
+	err := ops.Iter(ctx, pattern, func(k, v any) (bool, error) {
+		w, ok, err := ops.Get(ctx, val, k)
+		if err != nil {
+			return true, err
+		}
+		if ok {
+			ok, err = ops.Equal(ctx, v, w)
+		}
+		match = ok
+		return !ok || err != nil, err
+	})
+	return match, err
+}
+
+func hashBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[0]) {
+		return nil
+	}
+
+	j, err := castJSON(state.Globals.Ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	state.SetReturnValue(Unused, state.ValueOps().MakeString(fmt.Sprintf("%016x", fjson.Hash(j))))
+	return nil
+}
+
+func sizeBytesBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[0]) {
+		return nil
+	}
+
+	j, err := castJSON(state.Globals.Ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	n, err := jsonSize(j)
+	if err != nil {
+		return err
+	}
+
+	state.SetReturnValue(Unused, fjson.NewFloatInt(n))
+	return nil
+}
+
+func isValidEmailBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[0]) {
+		return nil
+	}
+
+	s, ok, err := builtinStringOperand(state, args[0], 1)
+	if err != nil || !ok {
+		return err
+	}
+
+	state.SetReturnValue(Unused, fjson.NewBool(validEmail(s)))
+	return nil
+}
+
+func isValidHostnameBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[0]) {
+		return nil
+	}
+
+	s, ok, err := builtinStringOperand(state, args[0], 1)
+	if err != nil || !ok {
+		return err
+	}
+
+	state.SetReturnValue(Unused, fjson.NewBool(validHostname(s)))
+	return nil
+}
+
+func parseURLBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[0]) {
+		return nil
+	}
+
+	s, ok, err := builtinStringOperand(state, args[0], 1)
+	if err != nil || !ok {
+		return err
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil // Undefined for the strings not parsing as URL references.
+	}
+
+	query := make(map[string]fjson.File)
+	for name, values := range u.Query() {
+		array := make([]fjson.File, len(values))
+		for i, v := range values {
+			array[i] = fjson.NewString(v)
+		}
+		query[name] = fjson.NewArray(array, len(array))
+	}
+
+	m := map[string]fjson.File{
+		"scheme":   fjson.NewString(u.Scheme),
+		"host":     fjson.NewString(u.Host),
+		"hostname": fjson.NewString(u.Hostname()),
+		"port":     fjson.NewString(u.Port()),
+		"path":     fjson.NewString(u.Path),
+		"query":    fjson.NewObject(query),
+		"fragment": fjson.NewString(u.Fragment),
+	}
+	if u.User != nil {
+		m["username"] = fjson.NewString(u.User.Username())
+	}
+
+	state.SetReturnValue(Unused, fjson.NewObject(m))
+	return nil
+}
+
+func sampleBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[2]) || isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	var elements []fjson.File
+	_, isSet := args[0].(fjson.Set)
+	switch a := args[0].(type) {
+	case fjson.Array:
+		elements = make([]fjson.File, a.Len())
+		for i := range elements {
+			elements[i] = a.Iterate(i)
+		}
+
+	case fjson.Set:
+		// Sets iterate in no particular order: sample from them in the
+		// AST order for the samples to be reproducible.
+		type element struct {
+			v fjson.Json
+			a ast.Value
+		}
+
+		sorted := make([]element, 0, a.Len())
+		if _, err := a.Iter(func(v fjson.Json) (bool, error) {
+			sorted = append(sorted, element{v: v, a: v.AST()})
+			return false, nil
+		}); err != nil {
+			return err
+		}
+
+		slices.SortFunc(sorted, func(a, b element) int {
+			return a.a.Compare(b.a)
+		})
+
+		elements = make([]fjson.File, len(sorted))
+		for i := range sorted {
+			elements[i] = sorted[i].v
+		}
+
+	default:
+		v, err := state.ValueOps().ToAST(state.Globals.Ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+			Code:    topdown.TypeErr,
+			Message: builtins.NewOperandTypeErr(1, v, "array", "set").Error(),
+		})
+		return nil
+	}
+
+	n, ok, err := builtinIntegerOperand(state, args[1], 2)
+	if err != nil || !ok {
+		return err
+	}
+
+	if n < 0 {
+		state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+			Code:    topdown.BuiltinErr,
+			Message: builtins.NewOperandErr(2, "must be non-negative integer").Error(),
+		})
+		return nil
+	}
+
+	seed, err := castJSON(state.Globals.Ctx, args[2])
+	if err != nil {
+		return err
+	}
+
+	if n < len(elements) {
+		var s uint64
+		if _, ok := seed.(fjson.Null); ok {
+			// No seed given: draw one from the source of randomness of
+			// the evaluation.
+			r := state.Globals.Seed
+			if r == nil {
+				r = rand.Reader
+			}
+
+			var b [8]byte
+			if _, err := io.ReadFull(r, b[:]); err != nil {
+				return err
+			}
+			s = binary.LittleEndian.Uint64(b[:])
+		} else {
+			s = fjson.Hash(seed)
+		}
+
+		elements = sample(elements, n, mathrand.New(mathrand.NewPCG(s, s)))
+	}
+
+	if isSet {
+		result := fjson.NewSet(len(elements))
+		for _, e := range elements {
+			result = result.Add(e.(fjson.Json))
+		}
+		state.SetReturnValue(Unused, result)
+		return nil
+	}
+
+	state.SetReturnValue(Unused, fjson.NewArray(elements, len(elements)))
+	return nil
+}
+
+func objectMergeBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[2]) || isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	// Topdown passes the output operand along: only check the three
+	// declared ones.
+	for i := range 3 {
+		if ok, err := builtinObjectOperand(state, args[i], i+1); !ok || err != nil {
+			return err
+		}
+	}
+
+	concat := false
+	if arrays, ok, err := state.ValueOps().Get(state.Globals.Ctx, args[2], state.ValueOps().MakeString("arrays")); err != nil {
+		return err
+	} else if ok {
+		s, _ := arrays.(*fjson.String)
+		switch {
+		case s != nil && s.Value() == "concat":
+			concat = true
+		case s != nil && s.Value() == "replace":
+		default:
+			state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+				Code:    topdown.BuiltinErr,
+				Message: builtins.NewOperandErr(3, `arrays must be "concat" or "replace"`).Error(),
+			})
+			return nil
+		}
+	}
+
+	a, err := castJSON(state.Globals.Ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	b, err := castJSON(state.Globals.Ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	state.SetReturnValue(Unused, fjson.MergeObjects(a, b, concat))
+	return nil
+}
+
+func countAtLeastBuiltin(state *State, args []Value) error {
+	return countCompareBuiltin(state, args, func(count func(limit int) (int, error), n int) (bool, error) {
+		c, err := count(n)
+		return c >= n, err
+	})
+}
+
+func countAtMostBuiltin(state *State, args []Value) error {
+	return countCompareBuiltin(state, args, func(count func(limit int) (int, error), n int) (bool, error) {
+		c, err := count(n + 1)
+		return c <= n, err
+	})
+}
+
+// countCompareBuiltin compares the count of the elements of the
+// collection to the integer operand, given the count of the elements
+// up to a limit: the collections iterated to count them are not
+// iterated past the limit.
+func countCompareBuiltin(state *State, args []Value, cmp func(count func(limit int) (int, error), n int) (bool, error)) error {
+	if isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	var count func(limit int) (int, error)
+	switch a := args[0].(type) {
+	case fjson.Array:
+		count = func(int) (int, error) { return a.Len(), nil }
+	case fjson.Object:
+		count = func(int) (int, error) { return a.Len(), nil }
+	case fjson.Object2:
+		count = func(int) (int, error) { return a.Len(), nil }
+	case fjson.Set:
+		count = func(int) (int, error) { return a.Len(), nil }
+	case IterableObject:
+		count = func(limit int) (int, error) {
+			var n int
+			if limit <= 0 {
+				return n, nil
+			}
+			err := a.Iter(state.Globals.Ctx, func(_ any, _ any) (bool, error) {
+				n++
+				return n >= limit, nil
+			})
+			return n, err
+		}
+	case *fjson.String:
+		count = func(limit int) (int, error) {
+			var n int
+			for range a.Value() {
+				if n >= limit {
+					break
+				}
+				n++
+			}
+			return n, nil
+		}
+	default:
+		v, err := state.ValueOps().ToAST(state.Globals.Ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+			Code:    topdown.TypeErr,
+			Message: builtins.NewOperandTypeErr(1, v, "array", "object", "set", "string").Error(),
+		})
+		return nil
+	}
+
+	n, ok, err := builtinIntegerOperand(state, args[1], 2)
+	if err != nil || !ok {
+		return err
+	}
+
+	result, err := cmp(count, n)
+	if err != nil {
+		return err
+	}
+
+	state.SetReturnValue(Unused, fjson.NewBool(result))
+	return nil
+}
+
+// sample returns n of the elements picked at random without replacement,
+// in their order in the elements.
+func sample(elements []fjson.File, n int, r *mathrand.Rand) []fjson.File {
+	indices := make([]int, len(elements))
+	for i := range indices {
+		indices[i] = i
+	}
+
+	// Partial Fisher-Yates shuffle: the first n indices are the sample.
+	for i := range n {
+		j := i + r.IntN(len(indices)-i)
+		indices[i], indices[j] = indices[j], indices[i]
+	}
+
+	picked := indices[:n]
+	slices.Sort(picked)
+
+	result := make([]fjson.File, n)
+	for i, j := range picked {
+		result[i] = elements[j]
+	}
+	return result
+}
+
+// validEmail checks the string is a bare address (RFC 5322), without a
+// display name or angle brackets, at a valid hostname.
+func validEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return false
+	}
+
+	i := gostrings.LastIndexByte(s, '@')
+	return i > 0 && validHostname(s[i+1:])
+}
+
+// validHostname checks the string is a hostname of letters, digits and
+// hyphens (RFC 1123), with an optional trailing dot. Internationalized
+// domain names are valid if they convert to ASCII (IDNA 2008, as for
+// lookups), and are checked in their ASCII form.
+func validHostname(s string) bool {
+	s = gostrings.TrimSuffix(s, ".")
+
+	ascii, err := idna.Lookup.ToASCII(s)
+	if err != nil || len(ascii) == 0 || len(ascii) > 253 {
+		return false
+	}
+
+	for label := range gostrings.SplitSeq(ascii, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, c := range []byte(label) {
+			if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// jsonSize returns the size of the compact JSON serialization of the
+// value, in bytes, without serializing its arrays, objects and sets. Sets
+// serialize as arrays.
+func jsonSize(j fjson.Json) (int64, error) {
+	switch v := j.(type) {
+	case fjson.Array:
+		n := 2 + int64(max(v.Len()-1, 0))
+		for i := 0; i < v.Len(); i++ {
+			m, err := jsonSize(v.Iterate(i))
+			if err != nil {
+				return 0, err
+			}
+			n += m
+		}
+		return n, nil
+
+	case fjson.Object:
+		names := v.Names()
+		n := 2 + int64(max(len(names)-1, 0))
+		for i, name := range names {
+			k, err := jsonSize(fjson.NewString(name))
+			if err != nil {
+				return 0, err
+			}
+
+			m, err := jsonSize(v.Iterate(i))
+			if err != nil {
+				return 0, err
+			}
+			n += k + 1 + m
+		}
+		return n, nil
+
+	case fjson.Object2:
+		var n int64 = 1
+		err := v.Iter(func(key, value fjson.Json) (bool, error) {
+			// Non-string keys serialize as strings, like in OPA.
+			name, ok := key.(*fjson.String)
+			if !ok {
+				name = fjson.NewString(key.String())
+			}
+
+			k, err := jsonSize(name)
+			if err != nil {
+				return true, err
+			}
+
+			m, err := jsonSize(value)
+			n += k + 1 + m + 1
+			return err != nil, err
+		})
+		return max(n, 2), err
+
+	case fjson.Set:
+		n := 2 + int64(max(v.Len()-1, 0))
+		_, err := v.Iter(func(v fjson.Json) (bool, error) {
+			m, err := jsonSize(v)
+			n += m
+			return false, err
+		})
+		return n, err
+	}
+
+	var w countingWriter
+	_, err := j.WriteTo(&w)
+	return int64(w), err
+}
+
+// countingWriter counts the bytes written to it, discarding them.
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+// jwtDecodeSegmentBuiltin decodes a base64url encoded JSON object, such
+// as the header or payload segment of a JWT, parsing the decoded bytes
+// in place instead of through an intermediate string. The padding is
+// optional, as with base64url.decode.
+func jwtDecodeSegmentBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[0]) {
+		return nil
+	}
+
+	s, ok, err := builtinStringOperand(state, args[0], 1)
+	if err != nil || !ok {
+		return err
+	}
+
+	s = gostrings.TrimRight(s, "=")
+	b := make([]byte, base64.RawURLEncoding.DecodedLen(len(s)))
+	n, err := base64.RawURLEncoding.Decode(b, []byte(s))
+	if err != nil {
+		state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+			Code:    topdown.BuiltinErr,
+			Message: builtins.NewOperandErr(1, "must be base64url encoded: %v", err).Error(),
+		})
+		return nil
+	}
+
+	decoded, err := fjson.NewBytesDecoder(b[:n]).Decode()
+	if err != nil {
+		state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+			Code:    topdown.BuiltinErr,
+			Message: builtins.NewOperandErr(1, "must encode valid JSON").Error(),
+		})
+		return nil
+	}
+
+	obj, ok := decoded.(fjson.Object)
+	if !ok {
+		state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+			Code:    topdown.BuiltinErr,
+			Message: builtins.NewOperandErr(1, "must encode JSON object").Error(),
+		})
+		return nil
+	}
+
+	state.SetReturnValue(Unused, obj)
+	return nil
+}
+
+// findBuiltin returns the values the JSONPath query selects from the
+// value, in an array, empty if none. See fjson.Query for the subset of
+// JSONPath supported.
+func findBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	path, ok, err := builtinStringOperand(state, args[1], 2)
+	if err != nil || !ok {
+		return err
+	}
+
+	value, err := castJSON(state.Globals.Ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	matches, err := fjson.Query(value, path)
+	if err != nil {
+		state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+			Code:    topdown.BuiltinErr,
+			Message: builtins.NewOperandErr(2, "%s", gostrings.TrimPrefix(err.Error(), "json: ")).Error(),
+		})
+		return nil
+	}
+
+	elements := make([]fjson.File, len(matches))
+	for i := range matches {
+		elements[i] = matches[i]
+	}
+
+	state.SetReturnValue(Unused, fjson.NewArray(elements, len(elements)))
+	return nil
+}
+
+// matchesWildcard is the pattern of eopa.matches matching any value.
+var matchesWildcard = fjson.NewString("_")
+
+func matchesBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	ok, err := matchPattern(state, args[1], args[0])
+	if err != nil {
+		return err
+	}
+
+	state.SetReturnValue(Unused, fjson.NewBool(ok))
+	return nil
+}
+
+// matchPattern checks if the value matches the pattern of eopa.matches:
+// the wildcard "_" matches any value, an object pattern matches the
+// objects having all its keys with matching values, an array pattern
+// matches the arrays of the same length with matching elements, and any
+// other pattern matches equal values.
+func matchPattern(state *State, pattern, val Value) (bool, error) {
+	ctx := state.Globals.Ctx
+	ops := state.ValueOps()
+
+	if ok, err := ops.Equal(ctx, pattern, matchesWildcard); err != nil || ok {
+		return ok, err
+	}
+
+	isObj, err := ops.IsObject(ctx, pattern)
+	if err != nil {
+		return false, err
+	}
+	isArr, err := ops.IsArray(ctx, pattern)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case isObj:
+		if isObj, err := ops.IsObject(ctx, val); err != nil || !isObj {
+			return false, err
+		}
+
+	case isArr:
+		if isArr, err := ops.IsArray(ctx, val); err != nil || !isArr {
+			return false, err
+		}
+
+		n, err := ops.Len(ctx, pattern)
+		if err != nil {
+			return false, err
+		}
+		m, err := ops.Len(ctx, val)
+		if err != nil {
+			return false, err
+		}
+		if ok, err := ops.Equal(ctx, n, m); err != nil || !ok {
+			return false, err
+		}
+
+	default:
+		return ops.Equal(ctx, pattern, val)
+	}
+
+	match := true
+	err = ops.Iter(ctx, pattern, func(k, v any) (bool, error) {
+		w, ok, err := ops.Get(ctx, val, k)
+		if err != nil {
+			return true, err
+		}
+		if ok {
+			ok, err = matchPattern(state, v, w)
+		}
+		match = ok
+		return !ok || err != nil, err
+	})
+	return match, err
+}
+
+func pointerGetBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	ptr, ok, err := pointerOperand(state, args[1])
+	if err != nil || !ok {
+		return err
+	}
+
+	value, err := castJSON(state.Globals.Ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if v, ok, _ := fjson.Lookup(value, ptr); ok {
+		state.SetReturnValue(Unused, v)
+	}
+	return nil
+}
+
+func pointerSetBuiltin(state *State, args []Value) error {
+	if isUndefinedType(args[2]) || isUndefinedType(args[1]) || isUndefinedType(args[0]) {
+		return nil
+	}
+
+	ptr, ok, err := pointerOperand(state, args[1])
+	if err != nil || !ok {
+		return err
+	}
+
+	value, err := castJSON(state.Globals.Ctx, args[0])
+	if err != nil {
+		return err
+	}
+	x, err := castJSON(state.Globals.Ctx, args[2])
+	if err != nil {
+		return err
+	}
+
+	// Undefined if the parent of the value set does not exist.
+	if v, err := fjson.SetAt(value, ptr, x); err == nil {
+		state.SetReturnValue(Unused, v)
+	}
+	return nil
+}
+
+// pointerOperand returns the JSON pointer of the second operand, or
+// records an error if it is not a valid one.
+func pointerOperand(state *State, arg Value) (string, bool, error) {
+	ptr, ok, err := builtinStringOperand(state, arg, 2)
+	if err != nil || !ok {
+		return "", false, err
+	}
+
+	if _, err := fjson.ParsePointer(ptr); err != nil {
+		state.Globals.BuiltinErrors = append(state.Globals.BuiltinErrors, &topdown.Error{
+			Code:    topdown.BuiltinErr,
+			Message: builtins.NewOperandErr(2, "must be a JSON pointer").Error(),
+		})
+		return "", false, nil
+	}
+
+	return ptr, true, nil
+}
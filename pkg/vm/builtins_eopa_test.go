@@ -0,0 +1,768 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	gostrings "strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/topdown"
+
+	fjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+func TestNativeBuiltin(t *testing.T) {
+	tests := []struct {
+		note   string
+		name   string
+		args   []string
+		result string // empty for undefined
+		err    string
+	}{
+		{
+			note:   "object.get_merged: nested merge",
+			name:   ObjectGetMergedName,
+			args:   []string{`{"a": {"b": {"x": 1}}}`, `["a", "b"]`, `{"x": 0, "y": 0}`},
+			result: `{"x": 1, "y": 0}`,
+		},
+		{
+			note:   "object.get_merged: deep merge",
+			name:   ObjectGetMergedName,
+			args:   []string{`{"a": {"b": {"x": 1}}}`, `"a"`, `{"b": {"y": 2}, "c": 3}`},
+			result: `{"b": {"x": 1, "y": 2}, "c": 3}`,
+		},
+		{
+			note:   "object.get_merged: missing path",
+			name:   ObjectGetMergedName,
+			args:   []string{`{"a": {}}`, `["a", "b"]`, `{"x": 0}`},
+			result: `{"x": 0}`,
+		},
+		{
+			note:   "object.get_merged: non-object value",
+			name:   ObjectGetMergedName,
+			args:   []string{`{"a": [1]}`, `["a"]`, `{"x": 0}`},
+			result: `[1]`,
+		},
+		{
+			note:   "object.get_merged: empty path",
+			name:   ObjectGetMergedName,
+			args:   []string{`{"a": 1}`, `[]`, `{"b": 2}`},
+			result: `{"a": 1, "b": 2}`,
+		},
+		{
+			note: "object.get_merged: not an object",
+			name: ObjectGetMergedName,
+			args: []string{`[]`, `["a"]`, `{}`},
+			err:  "operand 1 must be object but got array",
+		},
+		{
+			note:   "numbers.parse_base: binary",
+			name:   NumbersParseBaseName,
+			args:   []string{`"-1010"`, `2`},
+			result: `-10`,
+		},
+		{
+			note:   "numbers.parse_base: hexadecimal",
+			name:   NumbersParseBaseName,
+			args:   []string{`"FF"`, `16`},
+			result: `255`,
+		},
+		{
+			note: "numbers.parse_base: invalid digits",
+			name: NumbersParseBaseName,
+			args: []string{`"12"`, `2`},
+			err:  `operand 1 invalid base 2 number: "12"`,
+		},
+		{
+			note: "numbers.parse_base: invalid base",
+			name: NumbersParseBaseName,
+			args: []string{`"1"`, `37`},
+			err:  "operand 2 base must be between 2 and 36",
+		},
+		{
+			note: "numbers.parse_base: not a string",
+			name: NumbersParseBaseName,
+			args: []string{`1`, `10`},
+			err:  "operand 1 must be string but got number",
+		},
+		{
+			note:   "numbers.to_base: binary",
+			name:   NumbersToBaseName,
+			args:   []string{`-10`, `2`},
+			result: `"-1010"`,
+		},
+		{
+			note:   "numbers.to_base: base 36",
+			name:   NumbersToBaseName,
+			args:   []string{`1295`, `36`},
+			result: `"zz"`,
+		},
+		{
+			note: "numbers.to_base: floating-point number",
+			name: NumbersToBaseName,
+			args: []string{`1.5`, `2`},
+			err:  "operand 1 must be integer number but got floating-point number",
+		},
+		{
+			note: "numbers.to_base: invalid base",
+			name: NumbersToBaseName,
+			args: []string{`1`, `1`},
+			err:  "operand 2 base must be between 2 and 36",
+		},
+		{
+			note:   "set.to_sorted_array: mixed types",
+			name:   SetToSortedArrayName,
+			args:   []string{`{"b", 2, "a", 1, null, false, [1], {"x": 1}, {1}}`},
+			result: `[null, false, 1, 2, "a", "b", [1], {"x": 1}, {1}]`,
+		},
+		{
+			note:   "object.has_path: null value",
+			name:   ObjectHasPathName,
+			args:   []string{`{"a": {"b": null}}`, `["a", "b"]`},
+			result: `true`,
+		},
+		{
+			note:   "object.has_path: missing key",
+			name:   ObjectHasPathName,
+			args:   []string{`{"a": {"b": null}}`, `["a", "b", "c"]`},
+			result: `false`,
+		},
+		{
+			note:   "object.has_path: single key",
+			name:   ObjectHasPathName,
+			args:   []string{`{"a": false}`, `"a"`},
+			result: `true`,
+		},
+		{
+			note: "object.has_path: not an object",
+			name: ObjectHasPathName,
+			args: []string{`[1]`, `[0]`},
+			err:  "operand 1 must be object but got array",
+		},
+		{
+			note:   "set.to_sorted_array: empty",
+			name:   SetToSortedArrayName,
+			args:   []string{`set()`},
+			result: `[]`,
+		},
+		{
+			note: "set.to_sorted_array: not a set",
+			name: SetToSortedArrayName,
+			args: []string{`[1]`},
+			err:  "operand 1 must be set but got array",
+		},
+		{
+			note:   "array.to_set: duplicates",
+			name:   ArrayToSetName,
+			args:   []string{`[1, "a", 1, {"x": 1}, {"x": 1}]`},
+			result: `{1, "a", {"x": 1}}`,
+		},
+		{
+			note: "array.to_set: not an array",
+			name: ArrayToSetName,
+			args: []string{`{1}`},
+			err:  "operand 1 must be array but got set",
+		},
+		{
+			note:   "array.indexof: first match",
+			name:   ArrayIndexOfName,
+			args:   []string{`[1, {"x": [1]}, {"x": [1]}]`, `{"x": [1]}`},
+			result: `1`,
+		},
+		{
+			note:   "array.indexof: no match",
+			name:   ArrayIndexOfName,
+			args:   []string{`["a", "b"]`, `"c"`},
+			result: `-1`,
+		},
+		{
+			note: "array.indexof: not an array",
+			name: ArrayIndexOfName,
+			args: []string{`"abc"`, `"b"`},
+			err:  "operand 1 must be array but got string",
+		},
+		{
+			note:   "object.diff: changes",
+			name:   ObjectDiffName,
+			args:   []string{`{"a": 1, "b": {"c": [1, 2]}, "d": true}`, `{"b": {"c": [1, 3]}, "d": true, "e": null}`},
+			result: `[{"op": "remove", "path": "/a"}, {"op": "replace", "path": "/b/c/1", "value": 3}, {"op": "add", "path": "/e", "value": null}]`,
+		},
+		{
+			note:   "object.diff: equal",
+			name:   ObjectDiffName,
+			args:   []string{`{"a": {"b": 1}}`, `{"a": {"b": 1}}`},
+			result: `[]`,
+		},
+		{
+			note:   "object.diff: non-string keys",
+			name:   ObjectDiffName,
+			args:   []string{`{"a": {1: 2}}`, `{"a": {1: 3}}`},
+			result: `[{"op": "replace", "path": "/a", "value": {1: 3}}]`,
+		},
+		{
+			note: "object.diff: not an object",
+			name: ObjectDiffName,
+			args: []string{`{}`, `[]`},
+			err:  "operand 2 must be object but got array",
+		},
+		{
+			note:   "walk_until: object pattern",
+			name:   WalkUntilName,
+			args:   []string{`{"a": [{"kind": "x", "n": 1}, {"kind": "y", "n": 2}]}`, `{"kind": "y"}`},
+			result: `[["a", 1], {"kind": "y", "n": 2}]`,
+		},
+		{
+			note:   "walk_until: equal value",
+			name:   WalkUntilName,
+			args:   []string{`{"a": {"b": [1, "c"]}}`, `"c"`},
+			result: `[["a", "b", 1], "c"]`,
+		},
+		{
+			note:   "walk_until: parents first",
+			name:   WalkUntilName,
+			args:   []string{`{"a": {"k": 1, "b": {"k": 1}}}`, `{"k": 1}`},
+			result: `[["a"], {"k": 1, "b": {"k": 1}}]`,
+		},
+		{
+			note:   "walk_until: root",
+			name:   WalkUntilName,
+			args:   []string{`[1]`, `[1]`},
+			result: `[[], [1]]`,
+		},
+		{
+			note: "walk_until: no match",
+			name: WalkUntilName,
+			args: []string{`{"a": [1, {"b": 2}]}`, `{"b": 3}`},
+		},
+		{
+			note:   "size_bytes: object",
+			name:   SizeBytesName,
+			args:   []string{`{"a": [1, "b\n"], "c": {}, "d": null}`},
+			result: fmt.Sprint(len(`{"a":[1,"b\n"],"c":{},"d":null}`)),
+		},
+		{
+			note:   "size_bytes: set",
+			name:   SizeBytesName,
+			args:   []string{`{"a", 1}`},
+			result: fmt.Sprint(len(`["a",1]`)),
+		},
+		{
+			note:   "size_bytes: non-string keys",
+			name:   SizeBytesName,
+			args:   []string{`{1: "a"}`},
+			result: fmt.Sprint(len(`{"1":"a"}`)),
+		},
+		{
+			note:   "size_bytes: string",
+			name:   SizeBytesName,
+			args:   []string{`"é"`},
+			result: `4`,
+		},
+		{
+			note:   "hash: string",
+			name:   HashName,
+			args:   []string{`"a"`},
+			result: fmt.Sprintf(`"%016x"`, fjson.Hash(fjson.NewString("a"))),
+		},
+		{
+			note:   "hash: object",
+			name:   HashName,
+			args:   []string{`{"a": [1, "b"], "c": null}`},
+			result: fmt.Sprintf(`"%016x"`, fjson.Hash(fjson.MustNew(map[string]any{"c": nil, "a": []any{1.0, "b"}}))),
+		},
+		{
+			note:   "is_valid_email: valid",
+			name:   IsValidEmailName,
+			args:   []string{`"a.b+c@sub.example.com"`},
+			result: `true`,
+		},
+		{
+			note:   "is_valid_email: internationalized domain",
+			name:   IsValidEmailName,
+			args:   []string{`"a@bücher.example"`},
+			result: `true`,
+		},
+		{
+			note:   "is_valid_email: display name",
+			name:   IsValidEmailName,
+			args:   []string{`"A <a@example.com>"`},
+			result: `false`,
+		},
+		{
+			note:   "is_valid_email: invalid domain",
+			name:   IsValidEmailName,
+			args:   []string{`"a@exa_mple.com"`},
+			result: `false`,
+		},
+		{
+			note:   "is_valid_email: no domain",
+			name:   IsValidEmailName,
+			args:   []string{`"a"`},
+			result: `false`,
+		},
+		{
+			note: "is_valid_email: not a string",
+			name: IsValidEmailName,
+			args: []string{`1`},
+			err:  "operand 1 must be string but got number",
+		},
+		{
+			note:   "is_valid_hostname: valid",
+			name:   IsValidHostnameName,
+			args:   []string{`"a-b.example.com."`},
+			result: `true`,
+		},
+		{
+			note:   "is_valid_hostname: internationalized",
+			name:   IsValidHostnameName,
+			args:   []string{`"日本語.jp"`},
+			result: `true`,
+		},
+		{
+			note:   "is_valid_hostname: punycode",
+			name:   IsValidHostnameName,
+			args:   []string{`"xn--wgv71a119e.jp"`},
+			result: `true`,
+		},
+		{
+			note:   "is_valid_hostname: leading hyphen",
+			name:   IsValidHostnameName,
+			args:   []string{`"-a.example"`},
+			result: `false`,
+		},
+		{
+			note:   "is_valid_hostname: empty label",
+			name:   IsValidHostnameName,
+			args:   []string{`"a..example"`},
+			result: `false`,
+		},
+		{
+			note:   "is_valid_hostname: label too long",
+			name:   IsValidHostnameName,
+			args:   []string{`"` + gostrings.Repeat("a", 64) + `.example"`},
+			result: `false`,
+		},
+		{
+			note:   "parse_url: components",
+			name:   ParseURLName,
+			args:   []string{`"https://user:pw@example.com:8443/a/b?x=1&x=2&y#frag"`},
+			result: `{"scheme": "https", "host": "example.com:8443", "hostname": "example.com", "port": "8443", "path": "/a/b", "query": {"x": ["1", "2"], "y": [""]}, "fragment": "frag", "username": "user"}`,
+		},
+		{
+			note:   "parse_url: relative",
+			name:   ParseURLName,
+			args:   []string{`"/a?"`},
+			result: `{"scheme": "", "host": "", "hostname": "", "port": "", "path": "/a", "query": {}, "fragment": ""}`,
+		},
+		{
+			note:   "parse_url: internationalized",
+			name:   ParseURLName,
+			args:   []string{`"http://bücher.example/"`},
+			result: `{"scheme": "http", "host": "bücher.example", "hostname": "bücher.example", "port": "", "path": "/", "query": {}, "fragment": ""}`,
+		},
+		{
+			note: "parse_url: invalid",
+			name: ParseURLName,
+			args: []string{`"%zz"`},
+		},
+		{
+			note:   "sample: all of array",
+			name:   SampleName,
+			args:   []string{`[3, 1, 2]`, `5`, `"seed"`},
+			result: `[3, 1, 2]`,
+		},
+		{
+			note:   "sample: all of set",
+			name:   SampleName,
+			args:   []string{`{"a", "b"}`, `2`, `"seed"`},
+			result: `{"a", "b"}`,
+		},
+		{
+			note:   "sample: none",
+			name:   SampleName,
+			args:   []string{`[1, 2]`, `0`, `"seed"`},
+			result: `[]`,
+		},
+		{
+			note: "sample: negative",
+			name: SampleName,
+			args: []string{`[1, 2]`, `-1`, `"seed"`},
+			err:  "operand 2 must be non-negative integer",
+		},
+		{
+			note: "sample: not a collection",
+			name: SampleName,
+			args: []string{`{"a": 1}`, `1`, `"seed"`},
+			err:  "operand 1 must be one of {array, set} but got object",
+		},
+		{
+			note:   "object.merge: concat",
+			name:   ObjectMergeName,
+			args:   []string{`{"a": [1], "b": {"c": [2], "d": 1}}`, `{"a": [3], "b": {"c": [4], "e": 2}}`, `{"arrays": "concat"}`},
+			result: `{"a": [1, 3], "b": {"c": [2, 4], "d": 1, "e": 2}}`,
+		},
+		{
+			note:   "object.merge: replace",
+			name:   ObjectMergeName,
+			args:   []string{`{"a": [1], "b": {"c": [2], "d": 1}}`, `{"a": [3], "b": {"c": [4], "e": 2}}`, `{"arrays": "replace"}`},
+			result: `{"a": [3], "b": {"c": [4], "d": 1, "e": 2}}`,
+		},
+		{
+			note:   "object.merge: replace by default",
+			name:   ObjectMergeName,
+			args:   []string{`{"a": [1]}`, `{"a": [2]}`, `{}`},
+			result: `{"a": [2]}`,
+		},
+		{
+			note:   "object.merge: type conflicts",
+			name:   ObjectMergeName,
+			args:   []string{`{"a": [1], "b": {"c": 1}, "d": 1}`, `{"a": {"x": 1}, "b": [2], "d": [3]}`, `{"arrays": "concat"}`},
+			result: `{"a": {"x": 1}, "b": [2], "d": [3]}`,
+		},
+		{
+			note: "object.merge: invalid option",
+			name: ObjectMergeName,
+			args: []string{`{}`, `{}`, `{"arrays": "append"}`},
+			err:  `operand 3 arrays must be "concat" or "replace"`,
+		},
+		{
+			note:   "object.merge: output operand",
+			name:   ObjectMergeName,
+			args:   []string{`{"a": [1]}`, `{"a": [2]}`, `{"arrays": "concat"}`, `x`},
+			result: `{"a": [1, 2]}`,
+		},
+		{
+			note:   "count_at_least: reached",
+			name:   CountAtLeastName,
+			args:   []string{`[1, 2, 3]`, `3`},
+			result: `true`,
+		},
+		{
+			note:   "count_at_least: not reached",
+			name:   CountAtLeastName,
+			args:   []string{`{1, 2}`, `3`},
+			result: `false`,
+		},
+		{
+			note:   "count_at_least: string",
+			name:   CountAtLeastName,
+			args:   []string{`"héllo"`, `5`},
+			result: `true`,
+		},
+		{
+			note:   "count_at_least: negative",
+			name:   CountAtLeastName,
+			args:   []string{`[]`, `-1`},
+			result: `true`,
+		},
+		{
+			note:   "count_at_most: within",
+			name:   CountAtMostName,
+			args:   []string{`{"a": 1, "b": 2}`, `2`},
+			result: `true`,
+		},
+		{
+			note:   "count_at_most: exceeded",
+			name:   CountAtMostName,
+			args:   []string{`[1, 2, 3]`, `2`},
+			result: `false`,
+		},
+		{
+			note:   "count_at_most: string",
+			name:   CountAtMostName,
+			args:   []string{`"héllo"`, `4`},
+			result: `false`,
+		},
+		{
+			note:   "count_at_most: negative",
+			name:   CountAtMostName,
+			args:   []string{`[]`, `-1`},
+			result: `false`,
+		},
+		{
+			note: "count_at_least: not a collection",
+			name: CountAtLeastName,
+			args: []string{`1`, `1`},
+			err:  "operand 1 must be one of {array, object, set, string} but got number",
+		},
+		{
+			note:   "jwt.decode_segment: header",
+			name:   JWTDecodeSegmentName,
+			args:   []string{`"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9"`},
+			result: `{"alg": "HS256", "typ": "JWT"}`,
+		},
+		{
+			note:   "jwt.decode_segment: padded",
+			name:   JWTDecodeSegmentName,
+			args:   []string{`"eyJhIjpbMSwyXX0="`},
+			result: `{"a": [1, 2]}`,
+		},
+		{
+			note: "jwt.decode_segment: bad base64",
+			name: JWTDecodeSegmentName,
+			args: []string{`"e+30"`},
+			err:  "operand 1 must be base64url encoded: illegal base64 data at input byte 1",
+		},
+		{
+			note: "jwt.decode_segment: bad json",
+			name: JWTDecodeSegmentName,
+			args: []string{`"eyJh"`},
+			err:  "operand 1 must encode valid JSON",
+		},
+		{
+			note: "jwt.decode_segment: not an object",
+			name: JWTDecodeSegmentName,
+			args: []string{`"WzFd"`},
+			err:  "operand 1 must encode JSON object",
+		},
+		{
+			note:   "find: wildcard",
+			name:   FindName,
+			args:   []string{`{"items": [{"id": 1}, {"id": 2}, {"name": "x"}]}`, `"$.items[*].id"`},
+			result: `[1, 2]`,
+		},
+		{
+			note:   "find: descendants",
+			name:   FindName,
+			args:   []string{`{"a": {"id": 1, "b": [{"id": 2}]}}`, `"$..id"`},
+			result: `[1, 2]`,
+		},
+		{
+			note:   "find: no match",
+			name:   FindName,
+			args:   []string{`{"a": 1}`, `"$.b.c"`},
+			result: `[]`,
+		},
+		{
+			note:   "find: non-string keys",
+			name:   FindName,
+			args:   []string{`{"b": 2, 1: "a"}`, `"$.*"`},
+			result: `["a", 2]`,
+		},
+		{
+			note: "find: invalid query",
+			name: FindName,
+			args: []string{`{}`, `"$[a]"`},
+			err:  `operand 2 query "$[a]" has invalid index "a" at offset 2`,
+		},
+		{
+			note:   "matches: wildcard",
+			name:   MatchesName,
+			args:   []string{`{"kind": "Pod", "metadata": {"namespace": "x", "name": "y"}}`, `{"kind": "Pod", "metadata": {"namespace": "_"}}`},
+			result: `true`,
+		},
+		{
+			note:   "matches: missing key",
+			name:   MatchesName,
+			args:   []string{`{"kind": "Pod", "metadata": {}}`, `{"kind": "Pod", "metadata": {"namespace": "_"}}`},
+			result: `false`,
+		},
+		{
+			note:   "matches: array",
+			name:   MatchesName,
+			args:   []string{`[1, {"a": 2, "b": 3}]`, `["_", {"a": 2}]`},
+			result: `true`,
+		},
+		{
+			note:   "matches: array length",
+			name:   MatchesName,
+			args:   []string{`[1, 2]`, `["_"]`},
+			result: `false`,
+		},
+		{
+			note:   "matches: unequal",
+			name:   MatchesName,
+			args:   []string{`{"kind": "Service"}`, `{"kind": "Pod"}`},
+			result: `false`,
+		},
+		{
+			note:   "pointer.get",
+			name:   PointerGetName,
+			args:   []string{`{"a": {"b": [1, 2]}}`, `"/a/b/1"`},
+			result: `2`,
+		},
+		{
+			note: "pointer.get: missing",
+			name: PointerGetName,
+			args: []string{`{"a": {"b": [1, 2]}}`, `"/a/c"`},
+		},
+		{
+			note: "pointer.get: invalid pointer",
+			name: PointerGetName,
+			args: []string{`{}`, `"a"`},
+			err:  `operand 2 must be a JSON pointer`,
+		},
+		{
+			note:   "pointer.set",
+			name:   PointerSetName,
+			args:   []string{`{"a": {"b": [1, 2]}, "c": 3}`, `"/a/b/0"`, `{"d": 4}`},
+			result: `{"a": {"b": [{"d": 4}, 2]}, "c": 3}`,
+		},
+		{
+			note:   "pointer.set: append",
+			name:   PointerSetName,
+			args:   []string{`{"a": [1]}`, `"/a/-"`, `2`},
+			result: `{"a": [1, 2]}`,
+		},
+		{
+			note: "pointer.set: missing parent",
+			name: PointerSetName,
+			args: []string{`{"a": 1}`, `"/b/c"`, `2`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			operands := make([]*ast.Term, len(tc.args))
+			for i, arg := range tc.args {
+				operands[i] = ast.MustParseTerm(arg)
+			}
+
+			var result *ast.Term
+			err := NativeBuiltin(tc.name)(topdown.BuiltinContext{Context: context.Background()}, operands, func(t *ast.Term) error {
+				result = t
+				return nil
+			})
+
+			switch {
+			case tc.err != "":
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("expected error %q, got %v", tc.err, err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case tc.result == "":
+				if result != nil {
+					t.Fatalf("expected undefined, got %v", result)
+				}
+			case result == nil:
+				t.Fatal("expected result, got undefined")
+			case ast.MustParseTerm(tc.result).Value.Compare(result.Value) != 0:
+				t.Fatalf("expected %v, got %v", tc.result, result)
+			}
+		})
+	}
+}
+
+func TestSampleBuiltin(t *testing.T) {
+	eval := func(t *testing.T, seed io.Reader, args ...string) ast.Value {
+		t.Helper()
+
+		operands := make([]*ast.Term, len(args))
+		for i, arg := range args {
+			operands[i] = ast.MustParseTerm(arg)
+		}
+
+		var result *ast.Term
+		if err := NativeBuiltin(SampleName)(topdown.BuiltinContext{Context: context.Background(), Seed: seed}, operands, func(t *ast.Term) error {
+			result = t
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return result.Value
+	}
+
+	collection := `[0, 1, 2, 3, 4, 5, 6, 7, 8, 9]`
+
+	a := eval(t, nil, collection, `3`, `"seed"`)
+	if b := eval(t, nil, collection, `3`, `"seed"`); a.Compare(b) != 0 {
+		t.Fatalf("expected the same sample for the same seed, got %v and %v", a, b)
+	}
+
+	arr, ok := a.(*ast.Array)
+	if !ok || arr.Len() != 3 {
+		t.Fatalf("expected an array of 3 elements, got %v", a)
+	}
+	for i := 1; i < arr.Len(); i++ {
+		if arr.Elem(i-1).Value.Compare(arr.Elem(i).Value) >= 0 {
+			t.Fatalf("expected distinct elements in the collection order, got %v", a)
+		}
+	}
+
+	// Sets are sampled in the same order whatever their construction.
+	s := eval(t, nil, `{"e", "d", "c", "b", "a"}`, `2`, `{"tenant": "x"}`)
+	if set, ok := s.(ast.Set); !ok || set.Len() != 2 {
+		t.Fatalf("expected a set of 2 elements, got %v", s)
+	}
+	if o := eval(t, nil, `{"a", "b", "c", "d", "e"}`, `2`, `{"tenant": "x"}`); s.Compare(o) != 0 {
+		t.Fatalf("expected the same sample of equal sets, got %v and %v", s, o)
+	}
+
+	// Without a seed, the sample depends on the source of randomness.
+	random := bytes.Repeat([]byte{7}, 8)
+	a = eval(t, bytes.NewReader(random), collection, `3`, `null`)
+	if b := eval(t, bytes.NewReader(random), collection, `3`, `null`); a.Compare(b) != 0 {
+		t.Fatalf("expected the same sample for the same source, got %v and %v", a, b)
+	}
+}
+
+// countingIterableObject is an IterableObject of n elements, counting the
+// elements iterated.
+type countingIterableObject struct {
+	n        int
+	iterated int
+}
+
+func (o *countingIterableObject) Get(context.Context, any) (any, bool, error) {
+	return nil, false, nil
+}
+
+func (o *countingIterableObject) Iter(_ context.Context, f func(key, value any) (bool, error)) error {
+	for i := range o.n {
+		o.iterated++
+		if stop, err := f(fjson.NewFloatInt(int64(i)), fjson.NewNull()); err != nil || stop {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCountCompareBuiltinsEarlyExit(t *testing.T) {
+	tests := []struct {
+		note     string
+		impl     func(*State, []Value) error
+		n        int64
+		result   bool
+		iterated int
+	}{
+		{note: "at least", impl: countAtLeastBuiltin, n: 3, result: true, iterated: 3},
+		{note: "at least, zero", impl: countAtLeastBuiltin, n: 0, result: true, iterated: 0},
+		{note: "at most", impl: countAtMostBuiltin, n: 3, result: false, iterated: 4},
+		{note: "at most, all", impl: countAtMostBuiltin, n: 2000, result: true, iterated: 1000},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			state := newState(&Globals{
+				vm:      NewVM(),
+				Ctx:     context.Background(),
+				Limits:  DefaultLimits,
+				memoize: []map[k]Value{{}},
+			}, &Statistics{})
+			defer state.Release()
+
+			obj := &countingIterableObject{n: 1000}
+			if err := tc.impl(state, []Value{obj, fjson.NewFloatInt(tc.n)}); err != nil {
+				t.Fatal(err)
+			}
+
+			ret, ok := state.Return()
+			if !ok {
+				t.Fatal("expected result, got undefined")
+			}
+			if result := state.Local(ret); !fjson.Equal(result.(fjson.Json), fjson.NewBool(tc.result)) {
+				t.Errorf("expected %v, got %v", tc.result, result)
+			}
+			if obj.iterated != tc.iterated {
+				t.Errorf("expected %d elements iterated, got %d", tc.iterated, obj.iterated)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/topdown"
+
+	fjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+// testIterableObject is a minimal IterableObject, standing in for lazily
+// evaluated objects such as storage transactions.
+type testIterableObject map[string]fjson.Json
+
+func (o testIterableObject) Get(_ context.Context, key any) (any, bool, error) {
+	s, ok := key.(*fjson.String)
+	if !ok {
+		return nil, false, nil
+	}
+	v, ok := o[s.Value()]
+	return v, ok, nil
+}
+
+func (o testIterableObject) Iter(_ context.Context, f func(key, value any) (bool, error)) error {
+	for k, v := range o {
+		if stop, err := f(fjson.NewString(k), v); err != nil || stop {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestTypeBuiltins(t *testing.T) {
+	predicates := map[string]func(Value) bool{
+		"array":   typeArray,
+		"string":  typeString,
+		"boolean": typeBoolean,
+		"object":  typeObject,
+		"set":     typeSet,
+		"number":  typeNumber,
+		"null":    typeNull,
+	}
+
+	tests := []struct {
+		note     string
+		value    Value
+		typename string
+	}{
+		{note: "array", value: fjson.NewArray(nil, 0), typename: "array"},
+		{note: "string", value: fjson.NewString("a"), typename: "string"},
+		{note: "boolean", value: fjson.NewBool(true), typename: "boolean"},
+		{note: "null", value: fjson.NewNull(), typename: "null"},
+		{note: "number", value: fjson.NewFloatInt(1), typename: "number"},
+		{note: "set", value: fjson.NewSet(0), typename: "set"},
+		{note: "object", value: fjson.NewObject(nil), typename: "object"},
+		{note: "object2", value: fjson.NewObject2(0), typename: "object"},
+		{note: "iterable object", value: testIterableObject{"a": fjson.NewString("b")}, typename: "object"},
+	}
+
+	ops := &DataOperations{}
+	ctx := context.Background()
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			if actual := typename(tc.value); actual != tc.typename {
+				t.Fatalf("type_name: expected %q, got %q", tc.typename, actual)
+			}
+
+			for name, chk := range predicates {
+				if actual, expected := chk(tc.value), name == tc.typename; actual != expected {
+					t.Errorf("is_%s: expected %v, got %v", name, expected, actual)
+				}
+			}
+
+			for name, chk := range map[string]func(context.Context, any) (bool, error){
+				"array":  ops.IsArray,
+				"set":    ops.IsSet,
+				"object": ops.IsObject,
+			} {
+				actual, err := chk(ctx, tc.value)
+				if err != nil {
+					t.Fatalf("is %s: %v", name, err)
+				}
+				if expected := name == tc.typename; actual != expected {
+					t.Errorf("is %s: expected %v, got %v", name, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestStringsCaseBuiltins(t *testing.T) {
+	tests := []struct {
+		name  string
+		arg   string
+		upper string
+		lower string
+	}{
+		{name: "ascii", arg: "aBc", upper: "ABC", lower: "abc"},
+		{name: "empty", arg: "", upper: "", lower: ""},
+		{name: "latin", arg: "Ärger über straße", upper: "ÄRGER ÜBER STRAßE", lower: "ärger über straße"},
+		{name: "greek", arg: "ΣΊΣΥΦΟΣ", upper: "ΣΊΣΥΦΟΣ", lower: "σίσυφοσ"},
+		{name: "digraph", arg: "ǅemal", upper: "ǄEMAL", lower: "ǆemal"},
+		{name: "length changing", arg: "İ", upper: "İ", lower: "i"},
+		{name: "uncased", arg: "日本語", upper: "日本語", lower: "日本語"},
+	}
+
+	eval := func(t *testing.T, name string, arg *ast.Term) *ast.Term {
+		t.Helper()
+
+		var result *ast.Term
+		if err := NativeBuiltin(name)(topdown.BuiltinContext{Context: context.Background()}, []*ast.Term{arg}, func(t *ast.Term) error {
+			result = t
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, expected := range map[string]string{ast.Upper.Name: tc.upper, ast.Lower.Name: tc.lower} {
+				if result := eval(t, name, ast.StringTerm(tc.arg)); result == nil || !result.Equal(ast.StringTerm(expected)) {
+					t.Errorf("%s(%q): expected %q, got %v", name, tc.arg, expected, result)
+				}
+			}
+		})
+	}
+
+	if err := NativeBuiltin(ast.Upper.Name)(topdown.BuiltinContext{Context: context.Background()}, []*ast.Term{ast.IntNumberTerm(1)}, func(*ast.Term) error {
+		t.Fatal("expected no result")
+		return nil
+	}); err == nil {
+		t.Fatal("expected type error")
+	}
+}
+
+func TestArithmeticBuiltinsExact(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{name: ast.Plus.Name, a: "18446744073709551615", b: "18446744073709551615", expected: "36893488147419103230"},
+		{name: ast.Minus.Name, a: "100000000000000000000000000001", b: "1", expected: "100000000000000000000000000000"},
+		{name: ast.Multiply.Name, a: "12345678901234567890", b: "98765432109876543210", expected: "1219326311370217952237463801111263526900"},
+		{name: ast.Multiply.Name, a: "0.5", b: "3", expected: "1.5"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var result *ast.Term
+			if err := NativeBuiltin(tc.name)(topdown.BuiltinContext{Context: context.Background()}, []*ast.Term{ast.NumberTerm(json.Number(tc.a)), ast.NumberTerm(json.Number(tc.b))}, func(t *ast.Term) error {
+				result = t
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if expected := ast.NumberTerm(json.Number(tc.expected)); result == nil || result.Value.(ast.Number) != expected.Value.(ast.Number) {
+				t.Errorf("%s(%s, %s): expected %v, got %v", tc.name, tc.a, tc.b, expected, result)
+			}
+		})
+	}
+}
@@ -10,7 +10,6 @@ import (
 	gstrings "strings"
 	"sync/atomic"
 	"time"
-	"unsafe"
 
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/config"
@@ -53,6 +52,11 @@ const defaultEvalCacheTTL = "10s"
 
 var hook CacheHook
 
+// cacheIDs issues the VM identities keying the eval cache: the cached
+// results are shared only by the evaluations of one VM created by
+// NewVM, or of one VM obtained from a pool until put back.
+var cacheIDs atomic.Uint64
+
 func NewCacheHook() *CacheHook {
 	return &hook
 }
@@ -123,9 +127,9 @@ func (vm *VM) getEvalCacheKey(ctx context.Context, plan int, input *any) (ast.Ob
 
 	values := make([]*ast.Term, 0, len(config.InputPaths))
 
+paths:
 	for _, path := range config.InputPaths {
 		node := *input
-	next:
 		for i := range path {
 			key := path[i]
 			switch curr := node.(type) {
@@ -133,18 +137,18 @@ func (vm *VM) getEvalCacheKey(ctx context.Context, plan int, input *any) (ast.Ob
 				var ok bool
 				if node, ok = curr.Get(bjson.NewString(key)); !ok {
 					values = append(values, ast.NewTerm(ast.NewSet()))
-					break next
+					continue paths
 				}
 			case bjson.Array:
 				pos, err := ptr.ValidateArrayIndex(curr, key, path)
 				if err != nil {
 					values = append(values, ast.NewTerm(ast.NewSet()))
-					break next
+					continue paths
 				}
 				node = curr.Value(pos)
 			default:
 				values = append(values, ast.NewTerm(ast.NewSet()))
-				break next
+				continue paths
 			}
 		}
 
@@ -157,7 +161,7 @@ func (vm *VM) getEvalCacheKey(ctx context.Context, plan int, input *any) (ast.Ob
 	}
 
 	return ast.NewObject(
-		[2]*ast.Term{ast.StringTerm("vm"), ast.UIntNumberTerm(uint64(uintptr(unsafe.Pointer(vm))))},
+		[2]*ast.Term{ast.StringTerm("vm"), ast.UIntNumberTerm(vm.cacheID)},
 		[2]*ast.Term{ast.StringTerm("plan"), ast.IntNumberTerm(plan)},
 		[2]*ast.Term{ast.StringTerm("input_fields"), ast.NewTerm(ast.NewArray(values...))},
 	), nil
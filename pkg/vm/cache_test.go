@@ -95,6 +95,18 @@ func TestEvalCache(t *testing.T) {
 			expected: `{{"result": 2}}`,
 			time:     now.Add(6*time.Second + 1), // more than 5s passed since the cache population above.
 		},
+		{
+			note:     "cache miss (cache key path missing)",
+			input:    `{"version": 3}`,
+			expected: `{{"result": 3}}`,
+			time:     now.Add(7 * time.Second),
+		},
+		{
+			note:     "cache hit (cache key path still missing)",
+			input:    `{"version": 4}`,
+			expected: `{{"result": 3}}`,
+			time:     now.Add(7 * time.Second),
+		},
 	}
 
 	for _, tc := range cases {
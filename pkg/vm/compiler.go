@@ -16,6 +16,7 @@ type (
 		policy        *ir.Policy
 		functionIndex map[string]int
 		builtinFuncs  map[string]*topdown.Builtin
+		builtinPolicy *BuiltinPolicy
 	}
 )
 
@@ -50,6 +51,34 @@ const (
 	numbersRangeSF
 	numbersRangeStepSF
 	globMatchSF
+	objectGetMergedSF
+	objectHasPathSF
+	numbersParseBaseSF
+	numbersToBaseSF
+	setToSortedArraySF
+	arrayToSetSF
+	arrayIndexOfSF
+	objectDiffSF
+	walkUntilSF
+	hashSF
+	sizeBytesSF
+	upperSF
+	lowerSF
+	isValidEmailSF
+	isValidHostnameSF
+	parseURLSF
+	sampleSF
+	objectMergeSF
+	countAtLeastSF
+	countAtMostSF
+	jwtDecodeSegmentSF
+	plusSF
+	minusSF
+	multiplySF
+	findSF
+	matchesSF
+	pointerGetSF
+	pointerSetSF
 )
 
 var specializedBuiltins = map[string]uint32{
@@ -64,6 +93,8 @@ var specializedBuiltins = map[string]uint32{
 	ast.EndsWith.Name:         endsWithSF,
 	ast.StartsWith.Name:       startsWithSF,
 	ast.Sprintf.Name:          sprintfSF,
+	ast.Upper.Name:            upperSF,
+	ast.Lower.Name:            lowerSF,
 	ast.ArrayConcat.Name:      arrayConcatSF,
 	ast.ArraySlice.Name:       arraySliceSF,
 	ast.Count.Name:            countSF,
@@ -83,6 +114,32 @@ var specializedBuiltins = map[string]uint32{
 	ast.NumbersRange.Name:     numbersRangeSF,
 	ast.NumbersRangeStep.Name: numbersRangeStepSF,
 	ast.GlobMatch.Name:        globMatchSF,
+	ObjectGetMergedName:       objectGetMergedSF,
+	ObjectHasPathName:         objectHasPathSF,
+	NumbersParseBaseName:      numbersParseBaseSF,
+	NumbersToBaseName:         numbersToBaseSF,
+	SetToSortedArrayName:      setToSortedArraySF,
+	ArrayToSetName:            arrayToSetSF,
+	ArrayIndexOfName:          arrayIndexOfSF,
+	ObjectDiffName:            objectDiffSF,
+	WalkUntilName:             walkUntilSF,
+	HashName:                  hashSF,
+	SizeBytesName:             sizeBytesSF,
+	IsValidEmailName:          isValidEmailSF,
+	IsValidHostnameName:       isValidHostnameSF,
+	ParseURLName:              parseURLSF,
+	SampleName:                sampleSF,
+	ObjectMergeName:           objectMergeSF,
+	CountAtLeastName:          countAtLeastSF,
+	CountAtMostName:           countAtMostSF,
+	JWTDecodeSegmentName:      jwtDecodeSegmentSF,
+	ast.Plus.Name:             plusSF,
+	ast.Minus.Name:            minusSF,
+	ast.Multiply.Name:         multiplySF,
+	FindName:                  findSF,
+	MatchesName:               matchesSF,
+	PointerGetName:            pointerGetSF,
+	PointerSetName:            pointerSetSF,
 }
 
 var specializedBuiltinsByNum = [...]func(*State, []Value) error{
@@ -116,8 +173,36 @@ var specializedBuiltinsByNum = [...]func(*State, []Value) error{
 	numbersRangeSF:     numbersRangeBuiltin,
 	numbersRangeStepSF: numbersRangeStepBuiltin,
 	globMatchSF:        globMatchBuiltin,
+	objectGetMergedSF:  objectGetMergedBuiltin,
+	objectHasPathSF:    objectHasPathBuiltin,
+	numbersParseBaseSF: numbersParseBaseBuiltin,
+	numbersToBaseSF:    numbersToBaseBuiltin,
+	setToSortedArraySF: setToSortedArrayBuiltin,
+	arrayToSetSF:       arrayToSetBuiltin,
+	arrayIndexOfSF:     arrayIndexOfBuiltin,
+	objectDiffSF:       objectDiffBuiltin,
+	walkUntilSF:        walkUntilBuiltin,
+	hashSF:             hashBuiltin,
+	sizeBytesSF:        sizeBytesBuiltin,
+	upperSF:            stringsUpperBuiltin,
+	lowerSF:            stringsLowerBuiltin,
+	isValidEmailSF:     isValidEmailBuiltin,
+	isValidHostnameSF:  isValidHostnameBuiltin,
+	parseURLSF:         parseURLBuiltin,
+	sampleSF:           sampleBuiltin,
+	objectMergeSF:      objectMergeBuiltin,
+	countAtLeastSF:     countAtLeastBuiltin,
+	countAtMostSF:      countAtMostBuiltin,
+	jwtDecodeSegmentSF: jwtDecodeSegmentBuiltin,
+	plusSF:             plusBuiltin,
+	minusSF:            minusBuiltin,
+	multiplySF:         multiplyBuiltin,
+	findSF:             findBuiltin,
+	matchesSF:          matchesBuiltin,
+	pointerGetSF:       pointerGetBuiltin,
+	pointerSetSF:       pointerSetBuiltin,
 	// ...
-	31: nil,
+	127: nil,
 }
 
 func typeSpecializedBuiltinFunc(chk func(Value) bool) func(*State, []Value) error {
@@ -140,6 +225,13 @@ func (c *Compiler) WithBuiltins(bis map[string]*topdown.Builtin) *Compiler {
 	return c
 }
 
+// WithBuiltinPolicy makes the compilation fail if the policy calls any
+// builtin the builtin policy disallows.
+func (c *Compiler) WithBuiltinPolicy(p *BuiltinPolicy) *Compiler {
+	c.builtinPolicy = p
+	return c
+}
+
 // Compile turns the IR into VM executable instructions
 func (c *Compiler) Compile() (Executable, error) {
 	strings, err := c.compileStrings()
@@ -157,7 +249,16 @@ func (c *Compiler) Compile() (Executable, error) {
 		return Executable{}, err
 	}
 
-	return Executable{}.Write(strings, functions, plans), nil
+	executable := Executable(Executable{}.Write(strings, functions, plans))
+
+	if err := c.builtinPolicy.Check(executable.UsedBuiltins()); err != nil {
+		if err, ok := err.(*DisallowedBuiltinError); ok {
+			err.Rule = builtinCaller(c.policy, err.Builtin)
+		}
+		return Executable{}, err
+	}
+
+	return executable, nil
 }
 
 func (c *Compiler) compileStrings() ([]byte, error) {
@@ -36,7 +36,12 @@ func init() {
 }
 
 type (
-	DataOperations struct{}
+	// DataOperations implements the operations on values. The zero
+	// value is ready to use.
+	DataOperations struct {
+		astCache       *fjson.ASTCache       // nil unless enabled for the evaluation
+		sharedASTCache *fjson.SharedASTCache // nil unless given for the evaluation
+	}
 
 	// IterableObject is the interface for external, read-only (probably persisted) object implementations.
 	IterableObject interface {
@@ -240,6 +245,8 @@ func (o *DataOperations) FromInterface(ctx context.Context, x any) (fjson.Json,
 	switch x := x.(type) {
 	case nil:
 		return o.MakeNull(), nil
+	case fjson.Json:
+		return x, nil
 	case ast.Null:
 		return o.MakeNull(), nil
 	case bool:
@@ -417,6 +424,8 @@ func (*DataOperations) IsSet(ctx context.Context, v any) (bool, error) {
 	switch v.(type) {
 	case fjson.Set:
 		return true, nil
+	case IterableObject, fjson.Object, fjson.Object2:
+		return false, nil
 	default:
 		_, err := castJSON(ctx, v)
 		return false, err
@@ -705,6 +714,22 @@ func (*DataOperations) SetAdd(ctx context.Context, set, value any) (any, error)
 	return set, nil
 }
 
+// builtinOperandAST converts an operand of a builtin delegated to
+// topdown, reusing its precomputed conversion, if any, in which case it
+// returns true.
+func (o *DataOperations) builtinOperandAST(ctx context.Context, v any) (ast.Value, bool, error) {
+	if o.sharedASTCache != nil {
+		if j, ok := v.(fjson.Json); ok {
+			if a, ok := o.sharedASTCache.Get(j); ok {
+				return a, true, nil
+			}
+		}
+	}
+
+	a, err := o.ToAST(ctx, v)
+	return a, false, err
+}
+
 func (o *DataOperations) ToAST(ctx context.Context, v any) (ast.Value, error) {
 	switch v := v.(type) {
 	case fjson.Null:
@@ -720,9 +745,15 @@ func (o *DataOperations) ToAST(ctx context.Context, v any) (ast.Value, error) {
 		return v.AST(), nil
 
 	case fjson.Array:
+		if o.astCache != nil {
+			return o.astCache.AST(v), nil
+		}
 		return v.AST(), nil
 
 	case fjson.Object:
+		if o.astCache != nil {
+			return o.astCache.AST(v), nil
+		}
 		return v.AST(), nil
 
 	case fjson.Set:
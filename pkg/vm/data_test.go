@@ -0,0 +1,74 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+
+	fjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+func TestToASTCache(t *testing.T) {
+	ctx := context.Background()
+	data, err := fjson.NewObjectBinary(map[string]any{"a": map[string]any{"b": []any{1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cached := &DataOperations{astCache: fjson.NewASTCache()}
+	first, err := cached.ToAST(ctx, data.Value("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := cached.ToAST(ctx, data.Value("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatal("expected the conversion cached")
+	}
+
+	uncached := &DataOperations{}
+	third, err := uncached.ToAST(ctx, data.Value("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == third || first.Compare(third) != 0 {
+		t.Fatal("expected an equal, separate conversion")
+	}
+}
+
+func TestEvalCacheAST(t *testing.T) {
+	_, ctx := WithStatistics(context.Background())
+
+	if _, err := rego.New(rego.Query("x := data.a; y := [data.a, data.a]"), rego.Target("vm_page_test")).PrepareForEval(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	executable, err := NewCompiler().WithPolicy(pageTestPlans.policy).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fjson.NewObjectBinary(map[string]any{"a": map[string]any{"b": []any{1, "c"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm := NewVM().WithExecutable(executable).WithDataJSON(data)
+
+	result, err := vm.Eval(ctx, "eval", EvalOpts{CacheAST: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ast.MustParseTerm(`{{"x": {"b": [1, "c"]}, "y": [{"b": [1, "c"]}, {"b": [1, "c"]}]}}`).Value
+	if expected.Compare(result) != 0 {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"slices"
+	gostrings "strings"
+
+	"github.com/open-policy-agent/opa/v1/ir"
+
+	fjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+// DelegatedDataPaths returns the data paths whose values the policy
+// passes to the builtins the VM delegates to topdown, sorted. The
+// analysis is static: it follows the lookups of constant keys from data
+// within each plan and function, and into the functions the values are
+// passed to as arguments, but misses the values reaching the builtins
+// any other way, such as through dynamic lookups.
+func DelegatedDataPaths(policy *ir.Policy) [][]string {
+	if policy.Static == nil {
+		return nil
+	}
+
+	v := &delegatedDataVisitor{
+		strings:   policy.Static.Strings,
+		delegated: make(map[string]struct{}),
+		funcs:     make(map[string]*ir.Func),
+		analyzed:  make(map[string]struct{}),
+		found:     make(map[string][]string),
+	}
+	for _, bi := range policy.Static.BuiltinFuncs {
+		if _, ok := specializedBuiltins[bi.Name]; !ok {
+			v.delegated[bi.Name] = struct{}{}
+		}
+	}
+
+	if len(v.delegated) == 0 {
+		return nil
+	}
+
+	if policy.Funcs != nil {
+		for _, fn := range policy.Funcs.Funcs {
+			v.funcs[fn.Name] = fn
+		}
+	}
+
+	// Locals are scoped to their plan or function: analyze each apart.
+	if policy.Plans != nil {
+		for _, plan := range policy.Plans.Plans {
+			v.paths = map[ir.Local][]string{ir.Data: {}}
+			_ = ir.Walk(v, plan)
+		}
+	}
+	if policy.Funcs != nil {
+		for _, fn := range policy.Funcs.Funcs {
+			v.calls = append(v.calls, delegatedDataCall{fn: fn})
+		}
+	}
+
+	// Then the functions, once per combination of the data paths their
+	// parameters hold, as the calls found while analyzing them add more.
+	for len(v.calls) > 0 {
+		call := v.calls[len(v.calls)-1]
+		v.calls = v.calls[:len(v.calls)-1]
+
+		v.paths = map[ir.Local][]string{ir.Data: {}}
+		for i, path := range call.params {
+			if path != nil && i < len(call.fn.Params) {
+				v.paths[call.fn.Params[i]] = path
+			}
+		}
+		_ = ir.Walk(v, call.fn)
+	}
+
+	paths := make([][]string, 0, len(v.found))
+	for _, path := range v.found {
+		paths = append(paths, path)
+	}
+	slices.SortFunc(paths, slices.Compare)
+	return paths
+}
+
+// delegatedDataVisitor tracks the locals holding values looked up from
+// data, and records their paths when passed to a delegated builtin.
+type delegatedDataVisitor struct {
+	strings   []*ir.StringConst
+	delegated map[string]struct{}
+	funcs     map[string]*ir.Func
+	paths     map[ir.Local][]string // Data paths of the locals.
+	found     map[string][]string   // Paths found, by their joined segments.
+	calls     []delegatedDataCall   // Function calls left to analyze.
+	analyzed  map[string]struct{}   // Function calls analyzed or left to, by their keys.
+}
+
+// delegatedDataCall is a call of a function with data paths as
+// arguments: the params hold the paths of the arguments, nil for the
+// arguments not looked up from data.
+type delegatedDataCall struct {
+	fn     *ir.Func
+	params [][]string
+}
+
+func (*delegatedDataVisitor) Before(any) {}
+
+func (*delegatedDataVisitor) After(any) {}
+
+func (v *delegatedDataVisitor) Visit(x any) (ir.Visitor, error) {
+	switch stmt := x.(type) {
+	case *ir.DotStmt:
+		source, ok := v.path(stmt.Source)
+		if !ok {
+			break
+		}
+
+		var key int
+		switch k := stmt.Key.Value.(type) {
+		case ir.StringIndex:
+			key = int(k)
+		case *ir.StringIndex:
+			key = int(*k)
+		default:
+			return v, nil
+		}
+
+		if key < len(v.strings) {
+			v.paths[stmt.Target] = append(slices.Clip(source), v.strings[key].Value)
+		}
+
+	case *ir.AssignVarStmt:
+		if source, ok := v.path(stmt.Source); ok {
+			v.paths[stmt.Target] = source
+		}
+
+	case *ir.AssignVarOnceStmt:
+		if source, ok := v.path(stmt.Source); ok {
+			v.paths[stmt.Target] = source
+		}
+
+	case *ir.CallStmt:
+		if fn, ok := v.funcs[stmt.Func]; ok {
+			v.call(fn, stmt.Args)
+			break
+		}
+
+		if _, ok := v.delegated[stmt.Func]; !ok {
+			break
+		}
+
+		for _, arg := range stmt.Args {
+			// The whole of data is left out: converting it is the
+			// cost to avoid in the first place.
+			if path, ok := v.path(arg); ok && len(path) > 0 {
+				v.found[gostrings.Join(path, "\x00")] = path
+			}
+		}
+	}
+
+	return v, nil
+}
+
+// call queues the function for analysis with the data paths of the
+// arguments, unless none is looked up from data, beyond data itself, or
+// the function was queued with the same paths already.
+func (v *delegatedDataVisitor) call(fn *ir.Func, args []ir.Operand) {
+	params := make([][]string, len(args))
+	var key gostrings.Builder
+	key.WriteString(fn.Name)
+
+	found := false
+	for i, arg := range args {
+		key.WriteByte(0)
+		if path, ok := v.path(arg); ok && len(path) > 0 {
+			params[i] = path
+			found = true
+			key.WriteString(gostrings.Join(path, "\x00"))
+		}
+		key.WriteByte(1)
+	}
+
+	if !found {
+		return
+	}
+	if _, ok := v.analyzed[key.String()]; ok {
+		return
+	}
+
+	v.analyzed[key.String()] = struct{}{}
+	v.calls = append(v.calls, delegatedDataCall{fn: fn, params: params})
+}
+
+func (v *delegatedDataVisitor) path(op ir.Operand) ([]string, bool) {
+	var local ir.Local
+	switch l := op.Value.(type) {
+	case ir.Local:
+		local = l
+	case *ir.Local:
+		local = *l
+	default:
+		return nil, false
+	}
+
+	path, ok := v.paths[local]
+	return path, ok
+}
+
+// PrecomputeAST converts the values at the data paths to AST into the
+// cache, for the builtins delegated to topdown to reuse the conversions
+// through EvalOpts.SharedASTCache. The values already converted for the
+// current revision of the data are not converted again, and the paths
+// not found, or not holding binary arrays or objects, are skipped.
+func (vm *VM) PrecomputeAST(ctx context.Context, cache *fjson.SharedASTCache, paths [][]string) error {
+	values, err := vm.delegatedDataValues(ctx, cache, paths)
+	for _, j := range values {
+		cache.Precompute(j)
+	}
+	return err
+}
+
+// PrecomputeASTInBackground is PrecomputeAST converting the values in a
+// goroutine: only the lookups of the values are done before returning.
+// The evaluations before the conversions complete convert the values
+// themselves. The binary values of the data remain valid once the
+// transaction they were read in is closed, as they are never modified.
+func (vm *VM) PrecomputeASTInBackground(ctx context.Context, cache *fjson.SharedASTCache, paths [][]string) error {
+	values, err := vm.delegatedDataValues(ctx, cache, paths)
+	if len(values) > 0 {
+		go func() {
+			for _, j := range values {
+				cache.Precompute(j)
+			}
+		}()
+	}
+	return err
+}
+
+// delegatedDataValues returns the values at the data paths not converted
+// in the cache yet.
+func (vm *VM) delegatedDataValues(ctx context.Context, cache *fjson.SharedASTCache, paths [][]string) ([]fjson.Json, error) {
+	if vm.data == nil {
+		return nil, nil
+	}
+
+	var values []fjson.Json
+	for _, path := range paths {
+		value, ok := *vm.data, true
+		for _, seg := range path {
+			var err error
+			if value, ok, err = vm.ops.Get(ctx, value, vm.ops.MakeString(seg)); err != nil {
+				return nil, err
+			} else if !ok {
+				break
+			}
+		}
+
+		if j, isJSON := value.(fjson.Json); ok && isJSON {
+			if _, cached := cache.Get(j); !cached {
+				values = append(values, j)
+			}
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+
+	fjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+func TestDelegatedDataPaths(t *testing.T) {
+	module := `package test
+
+x := json.marshal(data.config.users)
+
+y := count(data.config.groups)
+
+z := f(data.config.roles)
+
+f(r) := json.marshal(r)
+`
+
+	policy := setup(t, module, "test/x", "test/y", "test/z")
+
+	// count is native, and the roles reach json.marshal through a
+	// function argument.
+	paths := DelegatedDataPaths(&policy)
+	if expected := [][]string{{"config", "roles"}, {"config", "users"}}; !slices.EqualFunc(paths, expected, slices.Equal) {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+
+	executable, err := NewCompiler().WithPolicy(&policy).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fjson.NewObjectBinary(map[string]any{
+		"config": map[string]any{"users": []any{"alice", "bob"}, "groups": []any{}, "roles": []any{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm := NewVM().WithExecutable(executable).WithDataJSON(data)
+	cache := fjson.NewSharedASTCache()
+	if err := vm.PrecomputeAST(context.Background(), cache, append(paths, []string{"missing", "path"})); err != nil {
+		t.Fatal(err)
+	}
+
+	users := data.Value("config").(fjson.Object).Value("users")
+	if v, ok := cache.Get(users); !ok || v.Compare(users.AST()) != 0 {
+		t.Fatalf("expected the users precomputed, got %v", v)
+	}
+
+	_, ctx := WithStatistics(context.Background())
+	result, err := vm.Eval(ctx, "test/x", EvalOpts{SharedASTCache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := ast.MustParseTerm(`{{"result": "[\"alice\",\"bob\"]"}}`).Value; expected.Compare(result) != 0 {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestDelegatedDataCacheHits(t *testing.T) {
+	module := `package test
+
+filtered := f(data.config)
+
+f(x) := g(x.teams)
+
+g(teams) := json.filter(teams, ["a/lead"])
+
+names := [name | walk(data.config.teams, [[name], _])]
+`
+
+	policy := setup(t, module, "test/filtered", "test/names")
+
+	// The teams reach json.filter through two function calls. walk is
+	// native, and converts nothing.
+	paths := DelegatedDataPaths(&policy)
+	if expected := [][]string{{"config", "teams"}}; !slices.EqualFunc(paths, expected, slices.Equal) {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+
+	executable, err := NewCompiler().WithPolicy(&policy).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fjson.NewObjectBinary(map[string]any{
+		"config": map[string]any{"teams": map[string]any{"a": map[string]any{"lead": "alice"}, "b": map[string]any{"lead": "bob"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm := NewVM().WithExecutable(executable).WithDataJSON(data)
+	cache := fjson.NewSharedASTCache()
+	if err := vm.PrecomputeASTInBackground(context.Background(), cache, paths); err != nil {
+		t.Fatal(err)
+	}
+
+	teams := data.Value("config").(fjson.Object).Value("teams")
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, ok := cache.Get(teams); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the teams precomputed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, tc := range []struct {
+		entrypoint string
+		expected   string
+		hits       int64
+	}{
+		{entrypoint: "test/filtered", expected: `{{"result": {"a": {"lead": "alice"}}}}`, hits: 1},
+		{entrypoint: "test/names", expected: `{{"result": ["a", "b"]}}`},
+	} {
+		stats, ctx := WithStatistics(context.Background())
+		result, err := vm.Eval(ctx, tc.entrypoint, EvalOpts{SharedASTCache: cache})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := ast.MustParseTerm(tc.expected).Value; expected.Compare(result) != 0 {
+			t.Fatalf("%s: expected %v, got %v", tc.entrypoint, expected, result)
+		}
+		if stats.SharedASTCacheHits != tc.hits {
+			t.Fatalf("%s: expected %d cache hits, got %d", tc.entrypoint, tc.hits, stats.SharedASTCacheHits)
+		}
+	}
+}
@@ -218,6 +218,19 @@ func (e Executable) IsValid() bool {
 	return true
 }
 
+// UsedBuiltins returns the names of the builtins the executable calls,
+// in the order the policy declares them.
+func (e Executable) UsedBuiltins() []string {
+	var names []string
+	fs := e.Functions()
+	for i := 0; i < fs.Len(); i++ {
+		if f := fs.Function(i); f.IsBuiltin() {
+			names = append(names, f.BuiltinName())
+		}
+	}
+	return names
+}
+
 func (e Executable) Strings() strings {
 	stringsOffset := header(e).StringsOffset()
 	return strings(e[headerLength+stringsOffset:])
@@ -323,6 +336,15 @@ func (f function) IsBuiltin() bool {
 	return false
 }
 
+// BuiltinName returns the name of a builtin function. The specialized
+// builtins are followed by their generic encoding, holding the name.
+func (f function) BuiltinName() string {
+	if f.Type() == typeBuiltin {
+		return builtin(f).Name()
+	}
+	return builtin(f[getLen(f):]).Name()
+}
+
 func (f function) Type() uint32 {
 	return getUint32(f, 4)
 }
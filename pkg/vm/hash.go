@@ -121,6 +121,30 @@ func hashImpl[T io.Writer](ctx context.Context, value any, hasher T) error {
 		binary.BigEndian.PutUint64(b, m)
 		hasher.Write(b)
 
+	case fjson.Object2:
+		hasher.Write([]byte{typeHashObject})
+
+		var m uint64
+		if err := value.Iter(func(k, v fjson.Json) (bool, error) {
+			hasher := xxhash.New()
+			if err := hashImpl(ctx, k, hasher); err != nil {
+				return true, err
+			}
+
+			if err := hashImpl(ctx, v, hasher); err != nil {
+				return true, err
+			}
+
+			m += hasher.Sum64()
+			return false, nil
+		}); err != nil {
+			return err
+		}
+
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, m)
+		hasher.Write(b)
+
 	case fjson.Set:
 		hasher.Write([]byte{typeHashSet})
 
@@ -0,0 +1,56 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/config"
+
+	bjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+type (
+	// ObjectIndexHook receives OPA configuration change callbacks
+	// and configures the indexing of large binary data objects.
+	ObjectIndexHook struct{}
+
+	// ObjectIndexConfig is under "extra/object_index" in OPA config.
+	// Lookups is the number of name lookups on a binary data object
+	// after which its names are hash indexed; zero disables the
+	// indexing.
+	ObjectIndexConfig struct {
+		Lookups int `json:"lookups"`
+	}
+)
+
+func NewObjectIndexHook() *ObjectIndexHook {
+	return &ObjectIndexHook{}
+}
+
+func (h *ObjectIndexHook) OnConfigDiscovery(ctx context.Context, conf *config.Config) (*config.Config, error) {
+	return h.onConfig(ctx, conf)
+}
+
+func (h *ObjectIndexHook) OnConfig(ctx context.Context, conf *config.Config) (*config.Config, error) {
+	return h.onConfig(ctx, conf)
+}
+
+func (*ObjectIndexHook) onConfig(_ context.Context, conf *config.Config) (*config.Config, error) {
+	var config ObjectIndexConfig
+	if raw := conf.Extra["object_index"]; raw != nil {
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return conf, err
+		}
+	}
+
+	if config.Lookups < 0 {
+		return conf, fmt.Errorf("invalid object index lookups: %d", config.Lookups)
+	}
+
+	bjson.SetObjectIndexThreshold(config.Lookups)
+	return conf, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/config"
+
+	bjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+func TestObjectIndexHook(t *testing.T) {
+	defer bjson.SetObjectIndexThreshold(0)
+
+	for _, tc := range []struct {
+		note  string
+		extra string
+		err   bool
+	}{
+		{note: "enabled", extra: `{"lookups": 10}`},
+		{note: "disabled", extra: `{"lookups": 0}`},
+		{note: "negative", extra: `{"lookups": -1}`, err: true},
+		{note: "invalid", extra: `{"lookups": "x"}`, err: true},
+	} {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := NewObjectIndexHook().OnConfig(context.Background(), &config.Config{
+				Extra: map[string]json.RawMessage{"object_index": json.RawMessage(tc.extra)},
+			})
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
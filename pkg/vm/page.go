@@ -0,0 +1,193 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+var (
+	ErrInvalidPageSize  = errors.New("invalid page size")
+	ErrInvalidPageToken = errors.New("invalid page token")
+
+	// errPageFull unwinds the evaluation once a page has collected
+	// all the results it needs.
+	errPageFull = errors.New("page full")
+)
+
+const pageTokenVersion = 1
+
+// page collects a window of the results of an evaluation, in the
+// order the evaluation adds them to the result set.
+type page struct {
+	skip    int // results still to skip before collecting
+	limit   int // results to collect
+	results []Value
+	emit    func(Value) error // if set, receives the results instead of collecting them
+}
+
+func (p *page) add(v Value) error {
+	if p.skip > 0 {
+		p.skip--
+		return nil
+	}
+
+	if p.emit != nil {
+		return p.emit(v)
+	}
+
+	p.results = append(p.results, v)
+	if len(p.results) >= p.limit {
+		return errPageFull
+	}
+
+	return nil
+}
+
+// EvalPage evaluates the query with the options given, returning at
+// most pageSize results, and a token to pass to the next call to
+// resume after them. The token is nil once all the results have been
+// returned; a nil token starts from the first result.
+//
+// The evaluation can't be suspended in the middle of the plan, so the
+// token records the number of results returned so far: resuming
+// re-evaluates the query, skipping these, and stops as soon as the page
+// is full. The results are enumerated in a stable order as long as the
+// data doesn't change between the calls. Tokens are bound to the query
+// and the input, and rejected with ErrInvalidPageToken if either
+// differs. Like Eval, EvalPage is thread safe.
+func (vm *VM) EvalPage(ctx context.Context, name string, opts EvalOpts, pageSize int, token []byte) ([]ast.Value, []byte, error) {
+	if pageSize <= 0 {
+		return nil, nil, ErrInvalidPageSize
+	}
+
+	fingerprint, err := vm.pageFingerprint(ctx, name, opts.Input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var offset int
+	if token != nil {
+		if offset, err = decodePageToken(token, fingerprint); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Collect one extra result to know if there's a next page.
+	p := &page{skip: offset, limit: pageSize + 1}
+	if _, err := vm.eval(ctx, name, opts, p); err != nil {
+		return nil, nil, err
+	}
+
+	var next []byte
+	if len(p.results) > pageSize {
+		p.results = p.results[:pageSize]
+		next = encodePageToken(offset+pageSize, fingerprint)
+	}
+
+	results := make([]ast.Value, len(p.results))
+	for i, v := range p.results {
+		if results[i], err = vm.ops.ToAST(ctx, v); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return results, next, nil
+}
+
+// EvalChan evaluates the query with the options given in the
+// background, sending each result to the returned channel as soon as
+// the evaluation adds it to the result set. The results are not
+// buffered: the evaluation waits for the caller to receive each one,
+// so a caller not reading applies backpressure. The result channel is
+// closed once the evaluation ends, and the error channel then receives
+// the error of the evaluation, if any, before being closed as well.
+//
+// Cancelling the context stops the evaluation promptly, whether it is
+// waiting for the caller or executing the plan: the channels are
+// closed, with the context error reported, and the caller doesn't need
+// to drain the remaining results. Like Eval, EvalChan is thread safe.
+func (vm *VM) EvalChan(ctx context.Context, name string, opts EvalOpts) (<-chan ast.Value, <-chan error) {
+	results := make(chan ast.Value)
+	errc := make(chan error, 1)
+
+	p := &page{emit: func(v Value) error {
+		result, err := vm.ops.ToAST(ctx, v)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case results <- result:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}}
+
+	go func() {
+		defer close(errc)
+		defer close(results)
+
+		if _, err := vm.eval(ctx, name, opts, p); err != nil {
+			errc <- err
+		}
+	}()
+
+	return results, errc
+}
+
+// pageFingerprint hashes the query name and the input to bind the
+// page tokens to them.
+func (vm *VM) pageFingerprint(ctx context.Context, name string, input *any) (uint64, error) {
+	hasher := xxhash.New()
+	hasher.WriteString(name)
+
+	if input != nil {
+		v, err := vm.ops.FromInterface(ctx, *input)
+		if err != nil {
+			return 0, err
+		}
+
+		hasher.Write([]byte{1})
+		if err := hashImpl(ctx, v, hasher); err != nil {
+			return 0, err
+		}
+	}
+
+	return hasher.Sum64(), nil
+}
+
+// encodePageToken encodes the token as the version, followed by the
+// fingerprint and the offset of the next result.
+func encodePageToken(offset int, fingerprint uint64) []byte {
+	token := make([]byte, 0, 1+8+binary.MaxVarintLen64)
+	token = append(token, pageTokenVersion)
+	token = binary.BigEndian.AppendUint64(token, fingerprint)
+	return binary.AppendUvarint(token, uint64(offset))
+}
+
+func decodePageToken(token []byte, fingerprint uint64) (int, error) {
+	if len(token) < 1+8 || token[0] != pageTokenVersion {
+		return 0, ErrInvalidPageToken
+	}
+
+	if binary.BigEndian.Uint64(token[1:9]) != fingerprint {
+		return 0, ErrInvalidPageToken
+	}
+
+	offset, n := binary.Uvarint(token[9:])
+	if n <= 0 || 9+n != len(token) || offset > math.MaxInt {
+		return 0, ErrInvalidPageToken
+	}
+
+	return int(offset), nil
+}
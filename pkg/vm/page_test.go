@@ -0,0 +1,170 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/ir"
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// planCapture is a rego target plugin capturing the plan of a query.
+type planCapture struct {
+	policy *ir.Policy
+}
+
+func (*planCapture) IsTarget(t string) bool {
+	return t == "vm_page_test"
+}
+
+func (p *planCapture) PrepareForEval(_ context.Context, policy *ir.Policy, _ ...rego.PrepareOption) (rego.TargetPluginEval, error) {
+	p.policy = policy
+	return nil, nil
+}
+
+var pageTestPlans = &planCapture{}
+
+func init() {
+	rego.RegisterPlugin("vm_page_test", pageTestPlans)
+}
+
+func TestEvalPage(t *testing.T) {
+	_, ctx := WithStatistics(context.Background())
+
+	if _, err := rego.New(rego.Query("x := input.items[_]"), rego.Target("vm_page_test")).PrepareForEval(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	executable, err := NewCompiler().WithPolicy(pageTestPlans.policy).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm := NewVM().WithExecutable(executable).WithDataJSON(map[string]any{})
+
+	var input any = map[string]any{"items": []any{"a", "b", "c", "b", "d", "e"}}
+	opts := EvalOpts{Input: &input}
+
+	var pages [][]ast.Value
+	var token []byte
+	for {
+		results, next, err := vm.EvalPage(ctx, "eval", opts, 2, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, results)
+		if next == nil {
+			break
+		}
+		token = next
+	}
+
+	expected := [][]string{
+		{`{"x": "a"}`, `{"x": "b"}`},
+		{`{"x": "c"}`, `{"x": "d"}`},
+		{`{"x": "e"}`},
+	}
+	if len(pages) != len(expected) {
+		t.Fatalf("expected %d pages, got %v", len(expected), pages)
+	}
+	for i := range expected {
+		if len(pages[i]) != len(expected[i]) {
+			t.Fatalf("page %d: expected %v, got %v", i, expected[i], pages[i])
+		}
+		for j := range expected[i] {
+			if exp := ast.MustParseTerm(expected[i][j]).Value; exp.Compare(pages[i][j]) != 0 {
+				t.Fatalf("page %d: expected %v, got %v", i, expected[i], pages[i])
+			}
+		}
+	}
+
+	t.Run("token bound to input", func(t *testing.T) {
+		var other any = map[string]any{"items": []any{"x"}}
+		_, _, err := vm.EvalPage(ctx, "eval", EvalOpts{Input: &other}, 2, token)
+		if !errors.Is(err, ErrInvalidPageToken) {
+			t.Fatalf("expected invalid token error, got %v", err)
+		}
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		_, _, err := vm.EvalPage(ctx, "eval", opts, 2, []byte{pageTokenVersion})
+		if !errors.Is(err, ErrInvalidPageToken) {
+			t.Fatalf("expected invalid token error, got %v", err)
+		}
+	})
+
+	t.Run("invalid page size", func(t *testing.T) {
+		_, _, err := vm.EvalPage(ctx, "eval", opts, 0, nil)
+		if !errors.Is(err, ErrInvalidPageSize) {
+			t.Fatalf("expected invalid page size error, got %v", err)
+		}
+	})
+}
+
+func TestEvalChan(t *testing.T) {
+	_, ctx := WithStatistics(context.Background())
+
+	if _, err := rego.New(rego.Query("x := input.items[_]"), rego.Target("vm_page_test")).PrepareForEval(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	executable, err := NewCompiler().WithPolicy(pageTestPlans.policy).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm := NewVM().WithExecutable(executable).WithDataJSON(map[string]any{})
+
+	var input any = map[string]any{"items": []any{"a", "b", "c", "b", "d"}}
+	opts := EvalOpts{Input: &input}
+
+	results, errc := vm.EvalChan(ctx, "eval", opts)
+
+	var actual []ast.Value
+	for result := range results {
+		actual = append(actual, result)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{`{"x": "a"}`, `{"x": "b"}`, `{"x": "c"}`, `{"x": "d"}`}
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+	for i := range expected {
+		if exp := ast.MustParseTerm(expected[i]).Value; exp.Compare(actual[i]) != 0 {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		results, errc := vm.EvalChan(ctx, "eval", opts)
+
+		<-results
+		cancel()
+
+		if err := <-errc; !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected cancellation, got %v", err)
+		}
+		if _, ok := <-results; ok {
+			t.Fatal("expected results closed")
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		results, errc := vm.EvalChan(ctx, "missing", opts)
+		if _, ok := <-results; ok {
+			t.Fatal("expected no results")
+		}
+		if err := <-errc; !errors.Is(err, ErrQueryNotFound) {
+			t.Fatalf("expected query not found, got %v", err)
+		}
+	})
+}
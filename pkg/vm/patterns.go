@@ -0,0 +1,109 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"sync/atomic"
+
+	"github.com/gobwas/glob"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// DefaultPatternCacheCapacity is the number of compiled patterns the
+// pattern cache holds unless configured otherwise.
+const DefaultPatternCacheCapacity = 1000
+
+type (
+	// PatternCacheStatistics describe the contents of the pattern cache.
+	PatternCacheStatistics struct {
+		// Entries is the number of compiled patterns cached.
+		Entries int `json:"entries"`
+
+		// Size is the total size of the patterns cached, in bytes,
+		// approximating the size of their compiled forms.
+		Size int64 `json:"size"`
+	}
+
+	// patternKey identifies a compiled glob pattern, as a pattern
+	// compiles differently per delimiters.
+	patternKey struct {
+		pattern    string
+		delimiters string
+	}
+
+	// patternCache is a bounded LRU of the patterns compiled by the
+	// native builtins, for the evaluations without an inter-query value
+	// cache to hold them.
+	patternCache struct {
+		globs *lru.Cache[patternKey, glob.Glob]
+		size  atomic.Int64
+	}
+)
+
+var patterns = newPatternCache(DefaultPatternCacheCapacity)
+
+func newPatternCache(capacity int) *patternCache {
+	c := &patternCache{}
+	c.globs, _ = lru.NewWithEvict(capacity, func(key patternKey, _ glob.Glob) {
+		c.size.Add(-key.size())
+	})
+	return c
+}
+
+func (k patternKey) size() int64 {
+	return int64(len(k.pattern) + len(k.delimiters))
+}
+
+func (c *patternCache) glob(pattern string, delimiters []rune) (glob.Glob, error) {
+	key := patternKey{pattern: pattern, delimiters: string(delimiters)}
+	if g, ok := c.globs.Get(key); ok {
+		return g, nil
+	}
+
+	g, err := glob.Compile(pattern, delimiters...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, _ := c.globs.ContainsOrAdd(key, g); !ok {
+		c.size.Add(key.size())
+	}
+	return g, nil
+}
+
+// GetPatternCacheStatistics returns the number and size of the patterns
+// in the pattern cache.
+func GetPatternCacheStatistics() PatternCacheStatistics {
+	return PatternCacheStatistics{
+		Entries: patterns.globs.Len(),
+		Size:    patterns.size.Load(),
+	}
+}
+
+// EvictPattern removes the compiled forms of the pattern from the pattern
+// cache, returning false if the pattern was not cached.
+func EvictPattern(pattern string) bool {
+	var evicted bool
+	for _, key := range patterns.globs.Keys() {
+		if key.pattern == pattern && patterns.globs.Remove(key) {
+			evicted = true
+		}
+	}
+	return evicted
+}
+
+// ClearPatternCache removes all the patterns from the pattern cache.
+func ClearPatternCache() {
+	patterns.globs.Purge()
+}
+
+// SetPatternCacheCapacity bounds the number of patterns the pattern cache
+// holds, evicting the least recently used patterns in excess. Capacities
+// below one are ignored.
+func SetPatternCacheCapacity(capacity int) {
+	if capacity < 1 {
+		return
+	}
+	patterns.globs.Resize(capacity)
+}
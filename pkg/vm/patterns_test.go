@@ -0,0 +1,65 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"testing"
+)
+
+func TestPatternCache(t *testing.T) {
+	ClearPatternCache()
+	t.Cleanup(func() {
+		ClearPatternCache()
+		SetPatternCacheCapacity(DefaultPatternCacheCapacity)
+	})
+
+	for _, p := range []struct {
+		pattern    string
+		delimiters []rune
+	}{
+		{"*.example.com", []rune{'.'}},
+		{"*.example.com", []rune{'.', ':'}},
+		{"api.*.com", []rune{'.'}},
+		{"api.*.com", []rune{'.'}},
+	} {
+		g, err := globCompile(nil, nil, "", p.pattern, p.delimiters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !g.Match("api.example.com") {
+			t.Fatalf("expected %s to match", p.pattern)
+		}
+	}
+
+	if exp, act := (PatternCacheStatistics{Entries: 3, Size: 14 + 15 + 10}), GetPatternCacheStatistics(); exp != act {
+		t.Fatalf("expected %+v, got %+v", exp, act)
+	}
+
+	// Evicting a pattern evicts it for all delimiters.
+	if !EvictPattern("*.example.com") || EvictPattern("*.example.com") {
+		t.Fatal("expected the pattern evicted once")
+	}
+	if exp, act := (PatternCacheStatistics{Entries: 1, Size: 10}), GetPatternCacheStatistics(); exp != act {
+		t.Fatalf("expected %+v, got %+v", exp, act)
+	}
+
+	// The least recently used patterns are evicted past the capacity.
+	SetPatternCacheCapacity(2)
+	for _, pattern := range []string{"a*", "b*", "c*"} {
+		if _, err := globCompile(nil, nil, "", pattern, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp, act := (PatternCacheStatistics{Entries: 2, Size: 4}), GetPatternCacheStatistics(); exp != act {
+		t.Fatalf("expected %+v, got %+v", exp, act)
+	}
+	if EvictPattern("a*") || !EvictPattern("c*") {
+		t.Fatal("expected a* evicted by the capacity")
+	}
+
+	ClearPatternCache()
+	if exp, act := (PatternCacheStatistics{}), GetPatternCacheStatistics(); exp != act {
+		t.Fatalf("expected %+v, got %+v", exp, act)
+	}
+}
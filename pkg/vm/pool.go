@@ -0,0 +1,67 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"sync"
+	"sync/atomic"
+
+	fjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+// Pool shares a single compiled executable, together with its string
+// cache, between any number of goroutines. Each goroutine borrows a
+// lightweight VM with Get, binds its data with WithDataNamespace or
+// WithDataJSON, evaluates, and returns the VM with Put. The executable
+// and the string cache are read-only (the latter is updated with
+// atomic operations only); all the per-evaluation state (locals,
+// result set, memoization) lives in the State and Globals created by
+// Eval.
+type Pool struct {
+	executable   Executable
+	stringsCache []atomic.Pointer[fjson.String]
+	vms          sync.Pool
+}
+
+// NewPool returns a pool of VMs backed by the executable given.
+func NewPool(executable Executable) *Pool {
+	p := &Pool{
+		executable:   executable,
+		stringsCache: make([]atomic.Pointer[fjson.String], executable.Strings().Len()),
+	}
+	p.vms.New = func() any {
+		return &VM{
+			executable:   p.executable,
+			stringsCache: p.stringsCache,
+			pool:         p,
+		}
+	}
+	return p
+}
+
+// Executable returns the shared executable.
+func (p *Pool) Executable() Executable {
+	return p.executable
+}
+
+// Get returns a VM ready for evaluation. The VM has no data bound.
+// Like a VM created by NewVM, it does not share the results cached by
+// the eval cache with the VMs obtained before: the data bound may have
+// changed since.
+func (p *Pool) Get() *VM {
+	vm := p.vms.Get().(*VM)
+	vm.cacheID = cacheIDs.Add(1)
+	return vm
+}
+
+// Put returns a VM obtained with Get back to the pool. The VM must
+// not be used after.
+func (p *Pool) Put(vm *VM) {
+	if vm.pool != p {
+		return
+	}
+
+	vm.data = nil
+	p.vms.Put(vm)
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/config"
+	"github.com/open-policy-agent/opa/v1/topdown/cache"
+
+	fjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+func TestPoolConcurrentEval(t *testing.T) {
+	policy := setup(t, testModule, "test/allow")
+	executable, err := NewCompiler().WithPolicy(&policy).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := NewPool(executable)
+	expected := ast.MustParseTerm(`{{"result": true}}`).Value
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 100 {
+				vm := pool.Get()
+				_, ctx := WithStatistics(context.Background())
+				result, err := vm.WithDataJSON(map[string]any{}).Eval(ctx, "test/allow", EvalOpts{})
+				pool.Put(vm)
+
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if expected.Compare(result) != 0 {
+					t.Errorf("unexpected value: %v", result)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolEvalCache(t *testing.T) {
+	defer hook.config.Store(nil)
+	if _, err := hook.OnConfig(context.Background(), &config.Config{
+		Extra: map[string]json.RawMessage{
+			"eval_cache": json.RawMessage(`{"enabled": true, "input_paths": ["/key"], "ttl": "5s"}`),
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	pool := NewPool(setupExecutable(t, "package test\nx := data.v", "test/x"))
+	cacheConfig, err := cache.ParseCachingConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interQueryCache := cache.NewInterQueryCache(cacheConfig)
+	now := time.Now()
+
+	// The data changes between the evaluations, with the same input:
+	// the second must not hit the result cached by the first, even if
+	// the pool hands out the same VM.
+	for _, v := range []int{1, 2} {
+		vm := pool.Get()
+		var input any = map[string]any{"key": "a"}
+		_, ctx := WithStatistics(context.Background())
+		result, err := vm.WithDataJSON(fjson.MustNew(map[string]any{"v": v})).Eval(ctx, "test/x", EvalOpts{
+			Input:                  &input,
+			Time:                   now,
+			InterQueryBuiltinCache: interQueryCache,
+		})
+		pool.Put(vm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := ast.MustParseTerm(fmt.Sprintf(`{{"result": %d}}`, v)).Value; expected.Compare(result) != 0 {
+			t.Fatalf("expected %v, got %v", expected, result)
+		}
+	}
+}
+
+func BenchmarkPoolEval(b *testing.B) {
+	policy := setup(b, testModule, "test/allow")
+	executable, err := NewCompiler().WithPolicy(&policy).Compile()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	pool := NewPool(executable)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			vm := pool.Get()
+			_, ctx := WithStatistics(context.Background())
+			if _, err := vm.Eval(ctx, "test/allow", EvalOpts{}); err != nil {
+				b.Error(err)
+			}
+			pool.Put(vm)
+		}
+	})
+}
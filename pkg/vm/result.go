@@ -0,0 +1,140 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+
+	fjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+// encodeResult stores the result set to the destination of the
+// result encoding selected.
+func (vm *VM) encodeResult(opts EvalOpts, rs fjson.Json) error {
+	switch opts.ResultEncoding {
+	case ResultEncodingBJSON:
+		*opts.ResultJSON = rs
+		return nil
+
+	case ResultEncodingJSONBytes:
+		var buf bytes.Buffer
+		if err := writeJSON(&buf, rs); err != nil {
+			return err
+		}
+
+		_, err := opts.ResultWriter.Write(buf.Bytes())
+		return err
+	}
+
+	return ErrInvalidResultEncoding
+}
+
+// writeJSON writes the value as JSON, the way ast.JSON would convert
+// it: sets are written as arrays and non-string object keys as their
+// JSON text. To keep the output deterministic, set elements and object
+// members are written in the order of their encodings.
+func writeJSON(buf *bytes.Buffer, v fjson.Json) error {
+	switch v := v.(type) {
+	case fjson.Null, fjson.Bool, fjson.Float, *fjson.String:
+		_, err := v.WriteTo(buf)
+		return err
+
+	case fjson.Array:
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, v.Iterate(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+
+	case fjson.Object:
+		buf.WriteByte('{')
+		for i, name := range v.Names() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if _, err := fjson.NewString(name).WriteTo(buf); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeJSON(buf, v.Iterate(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	case fjson.Object2:
+		type member struct {
+			key   string
+			value fjson.Json
+		}
+
+		members := make([]member, 0, v.Len())
+		if err := v.Iter(func(key, value fjson.Json) (bool, error) {
+			if s, ok := key.(*fjson.String); ok {
+				members = append(members, member{s.Value(), value})
+				return false, nil
+			}
+
+			var kbuf bytes.Buffer
+			if err := writeJSON(&kbuf, key); err != nil {
+				return true, err
+			}
+			members = append(members, member{kbuf.String(), value})
+			return false, nil
+		}); err != nil {
+			return err
+		}
+
+		slices.SortFunc(members, func(a, b member) int {
+			return cmp.Compare(a.key, b.key)
+		})
+
+		buf.WriteByte('{')
+		for i, m := range members {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if _, err := fjson.NewString(m.key).WriteTo(buf); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeJSON(buf, m.value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	case fjson.Set:
+		elems := make([][]byte, 0, v.Len())
+		if _, err := v.Iter(func(e fjson.Json) (bool, error) {
+			var ebuf bytes.Buffer
+			if err := writeJSON(&ebuf, e); err != nil {
+				return true, err
+			}
+			elems = append(elems, ebuf.Bytes())
+			return false, nil
+		}); err != nil {
+			return err
+		}
+
+		slices.SortFunc(elems, bytes.Compare)
+
+		buf.WriteByte('[')
+		buf.Write(bytes.Join(elems, []byte{','}))
+		buf.WriteByte(']')
+
+	default:
+		return fmt.Errorf("json: unsupported type %T", v)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+
+	fjson "github.com/open-policy-agent/eopa/pkg/json"
+)
+
+func TestEvalResultEncoding(t *testing.T) {
+	_, ctx := WithStatistics(context.Background())
+
+	if _, err := rego.New(rego.Query("x := input.items[_]; y := {x}"), rego.Target("vm_page_test")).PrepareForEval(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	executable, err := NewCompiler().WithPolicy(pageTestPlans.policy).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm := NewVM().WithExecutable(executable).WithDataJSON(map[string]any{})
+
+	var input any = map[string]any{"items": []any{"a", 1.5, nil, map[string]any{"b": []any{true}, "a": "\"q\""}}}
+
+	expected, err := vm.Eval(ctx, "eval", EvalOpts{Input: &input})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("bjson", func(t *testing.T) {
+		var result fjson.Json
+		r, err := vm.Eval(ctx, "eval", EvalOpts{Input: &input, ResultEncoding: ResultEncodingBJSON, ResultJSON: &result})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r != nil {
+			t.Fatalf("expected no AST result, got %v", r)
+		}
+		if actual := result.AST(); expected.Compare(actual) != 0 {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+	})
+
+	t.Run("json bytes", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := vm.Eval(ctx, "eval", EvalOpts{Input: &input, ResultEncoding: ResultEncodingJSONBytes, ResultWriter: &buf}); err != nil {
+			t.Fatal(err)
+		}
+
+		var actual any
+		if err := json.Unmarshal(buf.Bytes(), &actual); err != nil {
+			t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+		}
+
+		v, err := ast.InterfaceToValue(actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The result set is written as an array.
+		var results []*ast.Term
+		expected.(ast.Set).Foreach(func(x *ast.Term) {
+			results = append(results, ast.NewTerm(setsToArrays(x.Value)))
+		})
+		var written []*ast.Term
+		v.(*ast.Array).Foreach(func(x *ast.Term) {
+			written = append(written, x)
+		})
+		if len(written) != len(results) || ast.NewSet(written...).Compare(ast.NewSet(results...)) != 0 {
+			t.Fatalf("expected %v, got %v", results, v)
+		}
+	})
+
+	t.Run("missing destination", func(t *testing.T) {
+		for _, encoding := range []ResultEncoding{ResultEncodingBJSON, ResultEncodingJSONBytes, ResultEncoding(-1)} {
+			if _, err := vm.Eval(ctx, "eval", EvalOpts{Input: &input, ResultEncoding: encoding}); !errors.Is(err, ErrInvalidResultEncoding) {
+				t.Fatalf("expected invalid result encoding error, got %v", err)
+			}
+		}
+	})
+}
+
+func TestWriteJSON(t *testing.T) {
+	set := fjson.NewSet(0).Add(fjson.NewString("b")).Add(fjson.NewString("a"))
+	obj := fjson.NewObject2(0).
+		Insert(fjson.NewString("z"), fjson.NewFloatInt(1)).
+		Insert(fjson.NewFloatInt(2), set).
+		Insert(fjson.NewString("a"), fjson.NewArray([]fjson.File{fjson.NewNull(), fjson.NewBool(false)}, 2))
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := `{"2":["a","b"],"a":[null,false],"z":1}`, buf.String(); actual != expected {
+		t.Fatalf("expected %s, got %s", expected, actual)
+	}
+}
+
+// setsToArrays converts the sets nested in the value to sorted arrays,
+// the way they are written as JSON.
+func setsToArrays(v ast.Value) ast.Value {
+	switch v := v.(type) {
+	case ast.Set:
+		var elems []*ast.Term
+		v.Sorted().Foreach(func(x *ast.Term) {
+			elems = append(elems, ast.NewTerm(setsToArrays(x.Value)))
+		})
+		return ast.NewArray(elems...)
+	case ast.Object:
+		obj := ast.NewObject()
+		v.Foreach(func(k, x *ast.Term) {
+			obj.Insert(k, ast.NewTerm(setsToArrays(x.Value)))
+		})
+		return obj
+	}
+	return v
+}
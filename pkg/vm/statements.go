@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"unsafe"
 
 	"github.com/open-policy-agent/opa/v1/ast"
@@ -33,6 +34,11 @@ func (p plan) Execute(state *State) error {
 		}
 	}
 
+	var unsupported *UnsupportedStatementError
+	if errors.As(err, &unsupported) && unsupported.Plan == "" {
+		unsupported.Plan = p.Name()
+	}
+
 	return err
 }
 
@@ -61,6 +67,7 @@ func (f function) Execute(state *State, args []Value) error {
 
 			if !isUndefinedType(value) {
 				state.SetReturnValue(ret, value)
+				state.recordRuleHit(index)
 			}
 			// else {
 			// undefined return
@@ -87,6 +94,10 @@ func (f function) Execute(state *State, args []Value) error {
 		// TODO: No need to wrap the statements of the last block.
 	}
 
+	if _, defined := state.Return(); defined && err == nil {
+		state.recordRuleHit(index)
+	}
+
 	if memoize {
 		var value Value
 		if local, defined := state.Return(); defined {
@@ -102,7 +113,7 @@ func (f function) Execute(state *State, args []Value) error {
 }
 
 func (builtin specializedBuiltin) Execute(state *State, args []Value) error {
-	n := builtin.Num() & 31
+	n := builtin.Num() & 127
 	return specializedBuiltinsByNum[n](state, args)
 }
 
@@ -138,10 +149,13 @@ func (builtin builtin) Execute(state *State, args []Value) error {
 			return nil
 		}
 
-		v, err := state.ValueOps().ToAST(state.Globals.Ctx, args[i])
+		v, hit, err := state.ValueOps().builtinOperandAST(state.Globals.Ctx, args[i])
 		if err != nil {
 			return err
 		}
+		if hit {
+			state.stats.SharedASTCacheHits++
+		}
 
 		a[i] = ast.NewTerm(v)
 	}
@@ -337,10 +351,29 @@ func (s statement) Execute(state *State) (stop bool, index uint32, size int, err
 	t, size = s.Type()
 	t = t & 63
 	ex := exs[t]
+	if ex == nil {
+		return false, 0, size, &UnsupportedStatementError{Type: t}
+	}
 	stop, index, err = ex(s, state)
 	return
 }
 
+// UnsupportedStatementError is returned if the executable holds a
+// statement type the VM does not implement, such as a statement of a
+// newer executable version. It matches ErrUnsupportedStatement.
+type UnsupportedStatementError struct {
+	Type uint32 // Statement type.
+	Plan string // Plan executing the statement.
+}
+
+func (e *UnsupportedStatementError) Error() string {
+	return fmt.Sprintf("%v: type %d in plan %q", ErrUnsupportedStatement, e.Type, e.Plan)
+}
+
+func (*UnsupportedStatementError) Is(target error) bool {
+	return target == ErrUnsupportedStatement
+}
+
 func (nop) Execute(*State) (bool, uint32, error) {
 	return false, 0, nil
 }
@@ -370,12 +403,12 @@ func (a assignVarOnce) Execute(state *State) (bool, uint32, error) {
 	}
 
 	state.Set(target, source)
-	return false, 0, nil
+	return false, 0, state.checkLocals()
 }
 
 func (a assignVar) Execute(state *State) (bool, uint32, error) {
 	state.Set(a.Target(), a.Source())
-	return false, 0, nil
+	return false, 0, state.checkLocals()
 }
 
 func (s scan) Execute(state *State) (bool, uint32, error) {
@@ -672,7 +705,7 @@ func record(state *State, fid int, resultValue any) error {
 		}
 
 		if resultValue != nil {
-			v, err := state.Globals.vm.ops.ToInterface(state.Globals.Ctx, resultValue)
+			v, err := state.ValueOps().ToInterface(state.Globals.Ctx, resultValue)
 			if err != nil {
 				return err
 			}
@@ -940,7 +973,7 @@ func (o objectMerge) Execute(state *State) (bool, uint32, error) {
 
 func (r resetLocal) Execute(state *State) (bool, uint32, error) {
 	state.Unset(r.Target())
-	return false, 0, nil
+	return false, 0, state.checkLocals()
 }
 
 func (r resultSetAdd) Execute(state *State) (bool, uint32, error) {
@@ -950,12 +983,18 @@ func (r resultSetAdd) Execute(state *State) (bool, uint32, error) {
 		return false, 0, nil
 	}
 
+	n := state.Globals.ResultSet.Len()
 	newSet, err := state.ValueOps().SetAdd(state.Globals.Ctx, state.Globals.ResultSet, valueValue)
 	if err != nil {
 		return false, 0, err
 	}
 
 	state.Globals.ResultSet = newSet.(fjson.Set)
+
+	if page := state.Globals.page; page != nil && state.Globals.ResultSet.Len() > n {
+		return false, 0, page.add(valueValue)
+	}
+
 	return false, 0, nil
 }
 
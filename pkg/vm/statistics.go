@@ -17,6 +17,7 @@ type (
 		EvalInstructions   int64 `json:"eval_instructions"`
 		VirtualCacheHits   int64 `json:"virtual_cache_hits"`
 		VirtualCacheMisses int64 `json:"virtual_cache_misses"`
+		SharedASTCacheHits int64 `json:"shared_ast_cache_hits"` // Builtin operands reusing a conversion of EvalOpts.SharedASTCache.
 	}
 )
 
@@ -0,0 +1,71 @@
+// Copyright 2025 The OPA Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vm
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	policy := setup(t, testModule, "test/allow")
+	executable, err := NewCompiler().WithPolicy(&policy).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	// Without a tracer provider nor a span in the context, no spans are recorded.
+	_, ctx := WithStatistics(context.Background())
+	if _, err := NewVM().WithExecutable(executable).Eval(ctx, "test/allow", EvalOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(recorder.Ended()); n != 0 {
+		t.Fatalf("expected no spans, got %d", n)
+	}
+
+	// The tracer provider of the span of the context is used by default.
+	ctx, parent := tp.Tracer("test").Start(ctx, "parent")
+	if _, err := NewVM().WithExecutable(executable).Eval(ctx, "test/allow", EvalOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+
+	plan, eval := spans[0], spans[1]
+	if plan.Name() != "vm.plan" || eval.Name() != "vm.eval" {
+		t.Fatalf("unexpected spans %s, %s", plan.Name(), eval.Name())
+	}
+	if plan.Parent().SpanID() != eval.SpanContext().SpanID() || eval.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("unexpected span parents")
+	}
+
+	attrs := make(map[string]any)
+	for _, attr := range plan.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.AsInterface()
+	}
+	if attrs["entrypoint"] != "test/allow" || attrs["results"] != int64(1) || attrs["steps"].(int64) <= 0 {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+
+	// Errors are recorded with the tracer provider of the options.
+	_, ctx = WithStatistics(context.Background())
+	if _, err := NewVM().WithExecutable(executable).Eval(ctx, "test/missing", EvalOpts{TracerProvider: tp}); err != ErrQueryNotFound {
+		t.Fatalf("expected query not found, got %v", err)
+	}
+
+	spans = recorder.Ended()
+	if eval := spans[len(spans)-1]; eval.Name() != "vm.eval" || eval.Status().Description != ErrQueryNotFound.Error() {
+		t.Fatalf("unexpected span %s, status %v", eval.Name(), eval.Status())
+	}
+}
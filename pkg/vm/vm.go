@@ -8,8 +8,10 @@ import (
 	"context"
 	gjson "encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"slices"
 	gstrings "strings"
 	"sync"
 	"sync/atomic"
@@ -23,6 +25,8 @@ import (
 	"github.com/open-policy-agent/opa/v1/topdown/cache"
 	"github.com/open-policy-agent/opa/v1/topdown/print"
 	"github.com/open-policy-agent/opa/v1/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	fjson "github.com/open-policy-agent/eopa/pkg/json"
 )
@@ -34,6 +38,10 @@ var (
 	ErrInvalidExecutable         = errors.New("invalid executable")
 	ErrQueryNotFound             = errors.New("query not found")
 	ErrInstructionsLimitExceeded = errors.New("instructions limit exceeded")
+	ErrInvalidResultEncoding     = errors.New("invalid result encoding")
+	ErrUnsupportedStatement      = errors.New("unsupported statement")
+	ErrEntrypointDisabled        = errors.New("entrypoint disabled")
+	ErrLocalLimitExceeded        = errors.New("local limit exceeded")
 
 	DefaultLimits = Limits{
 		Instructions: 100000000,
@@ -50,6 +58,18 @@ var (
 	intermediateResultsMode = gstrings.ToUpper(gstrings.TrimSpace(os.Getenv("OPA_DECISIONS_INTERMEDIATE_RESULTS"))) // Legal values are NO_VALUE, SHA256, VALUE
 )
 
+const (
+	// ResultEncodingAST returns the result set from Eval as an
+	// ast.Value.
+	ResultEncodingAST ResultEncoding = iota
+	// ResultEncodingBJSON stores the result set, as a fjson.Json, to
+	// EvalOpts.ResultJSON without converting it to AST.
+	ResultEncodingBJSON
+	// ResultEncodingJSONBytes writes the result set, as JSON, to
+	// EvalOpts.ResultWriter without converting it to AST.
+	ResultEncodingJSONBytes
+)
+
 const (
 	intermediateResultsDisabled    = ""
 	intermediateResultsNoValueMode = "NO_VALUE"
@@ -58,11 +78,20 @@ const (
 )
 
 type (
+	// ResultEncoding selects how Eval returns the result set.
+	ResultEncoding int
+
+	// VM evaluates the plans of an executable. Once configured, Eval
+	// can be called concurrently; the With* methods are not thread
+	// safe. To share an executable between goroutines without
+	// allocating a string cache per VM, use a Pool.
 	VM struct {
 		executable   Executable
 		data         *any
 		ops          DataOperations
 		stringsCache []atomic.Pointer[fjson.String]
+		pool         *Pool  // nil if not obtained from a pool
+		cacheID      uint64 // Eval cache key, renewed whenever obtained from a pool.
 	}
 
 	EvalOpts struct {
@@ -83,6 +112,16 @@ type (
 		StrictBuiltinErrors         bool
 		ExternalCancel              topdown.Cancel
 		QueryTracers                []topdown.QueryTracer
+		CacheAST                    bool // Convert each binary data value to AST only once during the evaluation.
+		ResultEncoding              ResultEncoding
+		ResultJSON                  *fjson.Json           // Result set destination for ResultEncodingBJSON.
+		ResultWriter                io.Writer             // Result set destination for ResultEncodingJSONBytes.
+		DisabledEntrypoints         []string              // Plan names, such as "test/allow", or rule paths, such as "data.test.allow", to reject with ErrEntrypointDisabled.
+		TracerProvider              trace.TracerProvider  // Tracer provider for the evaluation spans. If nil, the tracer provider of the span of the context is used.
+		MaxLocals                   int                   // Maximum number of locals allocated at once, across the function calls, before failing with ErrLocalLimitExceeded. Zero for no limit. Locals are allocated by blocks of 32, each counting in full.
+		SharedASTCache              *fjson.SharedASTCache // AST conversions of data precomputed with PrecomputeAST, for the builtins delegated to topdown to reuse.
+		TrackRuleHits               bool                  // Record the rules producing a value during the evaluation, bypassing the eval cache. Partial rules always produce a value, if empty.
+		RuleHits                    *[]string             // Rule hits destination with TrackRuleHits: the paths, such as "data.test.allow", of the rules producing a value, sorted.
 	}
 
 	// State holds all the evaluation state and is passed along the statements as the evaluation progresses.
@@ -117,6 +156,11 @@ type (
 		StrictBuiltinErrors         bool
 		IntermediateResults         map[int]any
 		QueryTracers                []topdown.QueryTracer
+		page                        *page // nil unless evaluating with EvalPage or EvalChan
+		ops                         DataOperations
+		maxLocals                   int              // zero for no limit
+		locals                      int              // locals allocated by the live states, by register blocks, only counted with a limit
+		ruleHits                    map[int]struct{} // indices of the functions returning a value, nil unless tracked
 	}
 
 	Limits struct {
@@ -207,11 +251,12 @@ func newStateElement() any {
 }
 
 func NewVM() *VM {
-	return &VM{}
+	return &VM{cacheID: cacheIDs.Add(1)}
 }
 
 func (vm *VM) WithExecutable(executable Executable) *VM {
 	vm.executable = executable
+	vm.pool = nil
 	vm.stringsCache = make([]atomic.Pointer[fjson.String], executable.Strings().Len())
 	return vm
 }
@@ -231,12 +276,42 @@ func (vm *VM) WithDataJSON(data any) *VM {
 }
 
 // Eval evaluates the query with the options given. Eval is thread
-// safe. Return value is of ast.Value for now.
+// safe. The result set is returned as ast.Value, unless the options
+// select another result encoding: then Eval returns nil, and the
+// result set is stored to the destination of the encoding instead.
 func (vm *VM) Eval(ctx context.Context, name string, opts EvalOpts) (ast.Value, error) {
+	switch opts.ResultEncoding {
+	case ResultEncodingAST:
+	case ResultEncodingBJSON:
+		if opts.ResultJSON == nil {
+			return nil, ErrInvalidResultEncoding
+		}
+	case ResultEncodingJSONBytes:
+		if opts.ResultWriter == nil {
+			return nil, ErrInvalidResultEncoding
+		}
+	default:
+		return nil, ErrInvalidResultEncoding
+	}
+
+	return vm.eval(ctx, name, opts, nil)
+}
+
+// eval evaluates the query. If a page is given, the results are
+// collected into it instead of returned, and the evaluation stops as
+// soon as the page is full. The eval cache is bypassed for pages.
+func (vm *VM) eval(ctx context.Context, name string, opts EvalOpts, page *page) (result ast.Value, err error) {
+	ctx, span := startSpan(ctx, opts.TracerProvider, "vm.eval", attribute.String("entrypoint", name))
+	defer func() { EndSpan(span, err) }()
+
 	if !vm.executable.IsValid() {
 		return nil, ErrInvalidExecutable
 	}
 
+	if entrypointDisabled(opts.DisabledEntrypoints, name) {
+		return nil, fmt.Errorf("%w: %s", ErrEntrypointDisabled, name)
+	}
+
 	plans := vm.executable.Plans()
 	n := plans.Len()
 
@@ -261,11 +336,24 @@ func (vm *VM) Eval(ctx context.Context, name string, opts EvalOpts) (ast.Value,
 			opts.Time = time.Now()
 		}
 
-		cacheKey, err := vm.getEvalCacheKey(ctx, i, input)
-		if err != nil {
-			return nil, err
-		} else if result, ok := vm.checkEvalCache(opts.InterQueryBuiltinCache, cacheKey, opts.Time); ok {
-			return result, nil
+		var cacheKey ast.Object
+		if page == nil && !opts.TrackRuleHits {
+			var err error
+			cacheKey, err = vm.getEvalCacheKey(ctx, i, input)
+			if err != nil {
+				return nil, err
+			} else if result, ok := vm.checkEvalCache(opts.InterQueryBuiltinCache, cacheKey, opts.Time); ok {
+				if opts.ResultEncoding == ResultEncodingAST {
+					return result, nil
+				}
+
+				v, err := vm.ops.FromInterface(ctx, result)
+				if err != nil {
+					return nil, err
+				}
+
+				return nil, vm.encodeResult(opts, v)
+			}
 		}
 
 		if opts.Limits == nil {
@@ -299,7 +387,17 @@ func (vm *VM) Eval(ctx context.Context, name string, opts EvalOpts) (ast.Value,
 			InterQueryBuiltinValueCache: opts.InterQueryBuiltinValueCache,
 			IntermediateResults:         make(map[int]any),
 			QueryTracers:                opts.QueryTracers,
+			page:                        page,
+			ops:                         vm.ops,
+			maxLocals:                   opts.MaxLocals,
+		}
+		if opts.TrackRuleHits {
+			globals.ruleHits = make(map[int]struct{})
 		}
+		if opts.CacheAST {
+			globals.ops.astCache = fjson.NewASTCache()
+		}
+		globals.ops.sharedASTCache = opts.SharedASTCache
 		// If we're provided an external (probably shared) topdown.Cancel, let's
 		// use it.
 		if opts.ExternalCancel != nil {
@@ -323,10 +421,11 @@ func (vm *VM) Eval(ctx context.Context, name string, opts EvalOpts) (ast.Value,
 			StrictBuiltinErrors: globals.StrictBuiltinErrors,
 			NDBCache:            globals.NDBCache,
 			Capabilities:        globals.Capabilities,
+			MaxLocals:           globals.maxLocals,
 		})
 		globals.Ctx = context.WithValue(globals.Ctx, regoEvalNamespaceContextKey{}, vm.data)
 
-		if err := plan.Execute(state); err != nil {
+		if err := vm.executePlan(plan, state, opts.TracerProvider, name); err != nil && !errors.Is(err, errPageFull) {
 			return nil, err
 		}
 
@@ -334,6 +433,10 @@ func (vm *VM) Eval(ctx context.Context, name string, opts EvalOpts) (ast.Value,
 			return nil, globals.BuiltinErrors[0]
 		}
 
+		if opts.TrackRuleHits && opts.RuleHits != nil {
+			*opts.RuleHits = vm.ruleHits(globals.ruleHits)
+		}
+
 		switch intermediateResultsMode {
 		case intermediateResultsDisabled: // nothing to do
 		case intermediateResultsNoValueMode, intermediateResultsHashMode, intermediateResultsValueMode:
@@ -369,6 +472,14 @@ func (vm *VM) Eval(ctx context.Context, name string, opts EvalOpts) (ast.Value,
 			}
 		}
 
+		if page != nil {
+			return nil, nil
+		}
+
+		if opts.ResultEncoding != ResultEncodingAST && (opts.InterQueryBuiltinCache == nil || cacheKey == nil) {
+			return nil, vm.encodeResult(opts, globals.ResultSet)
+		}
+
 		r, err := vm.ops.ToAST(ctx, globals.ResultSet)
 		if err != nil {
 			return nil, err
@@ -376,12 +487,35 @@ func (vm *VM) Eval(ctx context.Context, name string, opts EvalOpts) (ast.Value,
 
 		vm.putEvalCache(opts.InterQueryBuiltinCache, cacheKey, r, globals.Time)
 
+		if opts.ResultEncoding != ResultEncodingAST {
+			return nil, vm.encodeResult(opts, globals.ResultSet)
+		}
+
 		return r, nil
 	}
 
 	return nil, ErrQueryNotFound
 }
 
+// executePlan executes the plan within a span, recording the number of
+// instructions executed and results produced.
+func (vm *VM) executePlan(p plan, state *State, tp trace.TracerProvider, name string) error {
+	ctx := state.Globals.Ctx
+	var span trace.Span
+	state.Globals.Ctx, span = startSpan(ctx, tp, "vm.plan", attribute.String("entrypoint", name))
+	defer func() { state.Globals.Ctx = ctx }()
+
+	instructions := state.stats.EvalInstructions
+	err := p.Execute(state)
+
+	span.SetAttributes(
+		attribute.Int64("steps", state.stats.EvalInstructions-instructions),
+		attribute.Int("results", state.Globals.ResultSet.Len()),
+	)
+	EndSpan(span, err)
+	return err
+}
+
 func getIntermediateResults(ctx context.Context) map[string]any {
 	// Result is recorded into context in statements call.Execute.
 	v := ctx.Value(server.IntermediateResultsContextKey{})
@@ -397,6 +531,42 @@ func getIntermediateResults(ctx context.Context) map[string]any {
 	return m
 }
 
+// ruleHits returns the rule paths of the functions, by their indices,
+// sorted.
+func (vm *VM) ruleHits(indices map[int]struct{}) []string {
+	hits := make([]string, 0, len(indices))
+	fs := vm.executable.Functions()
+	for i := range fs.Len() {
+		f := fs.Function(i)
+		if _, ok := indices[f.Index()]; !ok || f.IsBuiltin() || f.PathLen() == 0 {
+			continue
+		}
+
+		// The paths are rooted at "g0", for data.
+		path := f.Path()
+		hits = append(hits, gstrings.Join(append([]string{"data"}, path[1:]...), "."))
+	}
+
+	slices.Sort(hits)
+	return slices.Compact(hits)
+}
+
+// entrypointDisabled checks if the plan is disabled, by its name or
+// its rule path.
+func entrypointDisabled(disabled []string, name string) bool {
+	if len(disabled) == 0 {
+		return false
+	}
+
+	path := "data." + gstrings.ReplaceAll(name, "/", ".")
+	for _, d := range disabled {
+		if d == name || d == path {
+			return true
+		}
+	}
+	return false
+}
+
 func (vm *VM) runtime(ctx context.Context, v any) (*ast.Term, error) {
 	var runtime ast.Value
 	if v != nil {
@@ -494,6 +664,9 @@ func newState(globals *Globals, stats *Statistics) *State {
 	s.stats = stats
 
 	s.locals.data.set(int(Data), true)
+	if globals.maxLocals > 0 {
+		globals.locals += registersSize
+	}
 
 	if globals.Input != nil {
 		s.SetValue(Input, *globals.Input)
@@ -506,9 +679,17 @@ func newState(globals *Globals, stats *Statistics) *State {
 }
 
 func (s *State) Release() {
+	counted := s.Globals.maxLocals > 0
+	if counted {
+		s.Globals.locals -= registersSize
+	}
+
 	p := s.locals.registers.next
 	var next *registersList
 	for ; p != nil; p = next {
+		if counted {
+			s.Globals.locals -= registersSize
+		}
 		for i := range p.registers {
 			p.registers[i] = undefined() // release Values
 		}
@@ -526,7 +707,7 @@ func (s *State) New() *State {
 }
 
 func (s *State) ValueOps() *DataOperations {
-	return &s.Globals.vm.ops
+	return &s.Globals.ops
 }
 
 func (s *State) Func(f int) function {
@@ -611,7 +792,7 @@ func (s *State) Set(target Local, source LocalOrConst) {
 
 	case boolConstType:
 		v := source.BoolConst()
-		s.findReg(target).registers[int(target)%registersSize] = s.Globals.vm.ops.MakeBoolean(bool(v))
+		s.findReg(target).registers[int(target)%registersSize] = s.Globals.ops.MakeBoolean(bool(v))
 
 	case stringIndexConstType:
 		v := source.StringIndexConst()
@@ -770,6 +951,26 @@ func (s *State) Instr(i int64) error {
 	return nil
 }
 
+// checkLocals returns ErrLocalLimitExceeded if the live states hold more
+// locals than the evaluation allows. The locals are counted by the
+// register blocks allocated, of registersSize locals each: a state
+// using a single local still counts for a full block.
+func (s *State) checkLocals() error {
+	if limit := s.Globals.maxLocals; limit > 0 && s.Globals.locals > limit {
+		return ErrLocalLimitExceeded
+	}
+
+	return nil
+}
+
+// recordRuleHit records the function returned a value, if the rule hits
+// are tracked.
+func (s *State) recordRuleHit(index int) {
+	if hits := s.Globals.ruleHits; hits != nil {
+		hits[index] = struct{}{}
+	}
+}
+
 func (s *State) Args(n int) []Value {
 	if cap(s.args) >= n {
 		return s.args[0:n]
@@ -792,6 +993,9 @@ func (s *State) findReg(v Local) *registersList {
 	for range buckets {
 		if r.next == nil {
 			r.next = registersPool.Get().(*registersList)
+			if s.Globals.maxLocals > 0 {
+				s.Globals.locals += registersSize
+			}
 		}
 		r = r.next
 	}
@@ -811,6 +1015,9 @@ func (s *State) find2Regs(v1, v2 Local) (*registersList, *registersList) {
 		if next == nil {
 			next = registersPool.Get().(*registersList)
 			r.next = next
+			if s.Globals.maxLocals > 0 {
+				s.Globals.locals += registersSize
+			}
 		}
 
 		r = next
@@ -6,27 +6,37 @@ package vm
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/bundle"
 	"github.com/open-policy-agent/opa/v1/compile"
 	"github.com/open-policy-agent/opa/v1/ir"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	fjson "github.com/open-policy-agent/eopa/pkg/json"
 )
 
-func setup(tb testing.TB) ir.Policy {
+const testModule = "package test\nallow := x if {\nx := true}"
+
+// setup plans the module for the entrypoints, such as "test/allow".
+func setup(tb testing.TB, module string, entrypoints ...string) ir.Policy {
 	b := &bundle.Bundle{
 		Modules: []bundle.ModuleFile{
 			{
 				URL:    "/url",
 				Path:   "/foo.rego",
-				Raw:    []byte("package test\nallow := x if {\nx := true}"),
-				Parsed: ast.MustParseModule("package test\nallow := x if {\nx := true}"),
+				Raw:    []byte(module),
+				Parsed: ast.MustParseModuleWithOpts(module, ast.ParserOptions{ProcessAnnotation: true}),
 			},
 		},
 	}
 
-	compiler := compile.New().WithTarget(compile.TargetPlan).WithBundle(b).WithEntrypoints("test/allow")
+	compiler := compile.New().WithTarget(compile.TargetPlan).WithBundle(b).WithEntrypoints(entrypoints...)
 	if err := compiler.Build(context.Background()); err != nil {
 		tb.Fatal(err)
 	}
@@ -40,6 +50,16 @@ func setup(tb testing.TB) ir.Policy {
 	return policy
 }
 
+// setupExecutable compiles the plans of setup.
+func setupExecutable(tb testing.TB, module string, entrypoints ...string) Executable {
+	policy := setup(tb, module, entrypoints...)
+	executable, err := NewCompiler().WithPolicy(&policy).Compile()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return executable
+}
+
 func testCompiler(tb testing.TB, policy ir.Policy) {
 	if _, err := NewCompiler().WithPolicy(&policy).Compile(); err != nil {
 		tb.Fatal(err)
@@ -47,15 +67,303 @@ func testCompiler(tb testing.TB, policy ir.Policy) {
 }
 
 func TestCompiler(t *testing.T) {
-	policy := setup(t)
+	policy := setup(t, testModule, "test/allow")
 	testCompiler(t, policy)
 }
 
 func BenchmarkCompiler(b *testing.B) {
-	policy := setup(b)
+	policy := setup(b, testModule, "test/allow")
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
 		testCompiler(b, policy)
 	}
 }
+
+// BenchmarkArrayIndex evaluates data.bigarray[i] against binary data:
+// the timings should not depend on the length of the array.
+func BenchmarkArrayIndex(b *testing.B) {
+	executable := setupExecutable(b, "package test\nx := data.bigarray[input.i]", "test/x")
+
+	for _, n := range []int{1000, 1000000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			elems := make([]any, n)
+			for i := range elems {
+				elems[i] = i
+			}
+
+			bs, err := fjson.Marshal(fjson.MustNew(map[string]any{"bigarray": elems}))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			data, err := fjson.NewFromBinary(bs)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			vm := NewVM().WithExecutable(executable).WithDataJSON(data)
+			var input any = map[string]any{"i": n / 2}
+			expected := ast.MustParseTerm(fmt.Sprintf(`{{"result": %d}}`, n/2)).Value
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_, ctx := WithStatistics(context.Background())
+				result, err := vm.Eval(ctx, "test/x", EvalOpts{Input: &input})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if expected.Compare(result) != 0 {
+					b.Fatalf("unexpected value: %v", result)
+				}
+			}
+		})
+	}
+}
+
+func TestUnsupportedStatement(t *testing.T) {
+	stmt := nop{}.Write()
+	putTypeLength(stmt, 0, 63, uint32(len(stmt))) // No such statement type.
+
+	functions := appendOffsetIndex(appendUint32(nil, 0), 0)
+	p := plan{}.Write("test", blocks{}.Write([][]byte{block{}.Write([][]byte{stmt})}))
+	executable := Executable(Executable{}.Write(strings{}.Write(nil), functions, plans{}.Write([][]byte{p})))
+
+	_, ctx := WithStatistics(context.Background())
+	_, err := NewVM().WithExecutable(executable).Eval(ctx, "test", EvalOpts{})
+
+	var unsupported *UnsupportedStatementError
+	if !errors.Is(err, ErrUnsupportedStatement) || !errors.As(err, &unsupported) {
+		t.Fatalf("expected unsupported statement, got %v", err)
+	}
+	if unsupported.Type != 63 || unsupported.Plan != "test" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestDisabledEntrypoints(t *testing.T) {
+	policy := setup(t, testModule, "test/allow")
+	executable, err := NewCompiler().WithPolicy(&policy).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := NewVM().WithExecutable(executable)
+
+	for _, tc := range []struct {
+		note     string
+		disabled []string
+		err      bool
+	}{
+		{note: "none"},
+		{note: "other", disabled: []string{"test/deny", "data.test"}},
+		{note: "plan name", disabled: []string{"test/allow"}, err: true},
+		{note: "rule path", disabled: []string{"data.test.allow"}, err: true},
+	} {
+		t.Run(tc.note, func(t *testing.T) {
+			_, ctx := WithStatistics(context.Background())
+			result, err := vm.Eval(ctx, "test/allow", EvalOpts{DisabledEntrypoints: tc.disabled})
+			switch {
+			case tc.err && !errors.Is(err, ErrEntrypointDisabled):
+				t.Fatalf("expected entrypoint disabled, got %v", err)
+			case !tc.err && err != nil:
+				t.Fatal(err)
+			case !tc.err && ast.MustParseTerm(`{{"result": true}}`).Value.Compare(result) != 0:
+				t.Fatalf("unexpected result %v", result)
+			}
+		})
+	}
+}
+
+func TestMaxLocals(t *testing.T) {
+	rego := `package test
+
+f(x) := y if {
+	y := g(x) + 1
+}
+
+g(x) := y if {
+	y := x * 2
+}
+
+allow := f(input.x)
+`
+
+	executable := setupExecutable(t, rego, "test/allow")
+	vm := NewVM().WithExecutable(executable)
+
+	for _, tc := range []struct {
+		note      string
+		maxLocals int
+		err       bool
+	}{
+		{note: "unlimited"},
+		{note: "within", maxLocals: 1000},
+		{note: "exceeded", maxLocals: registersSize, err: true}, // Every function call holds registersSize locals at least.
+	} {
+		t.Run(tc.note, func(t *testing.T) {
+			var input any = map[string]any{"x": 1}
+			_, ctx := WithStatistics(context.Background())
+			result, err := vm.Eval(ctx, "test/allow", EvalOpts{Input: &input, MaxLocals: tc.maxLocals})
+			switch {
+			case tc.err && !errors.Is(err, ErrLocalLimitExceeded):
+				t.Fatalf("expected local limit exceeded, got %v", err)
+			case !tc.err && err != nil:
+				t.Fatal(err)
+			case !tc.err && ast.MustParseTerm(`{{"result": 3}}`).Value.Compare(result) != 0:
+				t.Fatalf("unexpected result %v", result)
+			}
+		})
+	}
+}
+
+func TestTrackRuleHits(t *testing.T) {
+	rego := `package test
+
+admin if input.user == "admin"
+
+owner if input.user == input.owner
+
+never if false
+
+allow if admin
+
+allow if owner
+
+deny contains "blocked" if input.blocked
+`
+
+	executable := setupExecutable(t, rego, "test/allow", "test/deny")
+	vm := NewVM().WithExecutable(executable)
+
+	for _, tc := range []struct {
+		note       string
+		entrypoint string
+		input      map[string]any
+		hits       []string
+	}{
+		{note: "admin", entrypoint: "test/allow", input: map[string]any{"user": "admin"}, hits: []string{"data.test.admin", "data.test.allow"}},
+		{note: "owner", entrypoint: "test/allow", input: map[string]any{"user": "a", "owner": "a"}, hits: []string{"data.test.allow", "data.test.owner"}},
+		{note: "none", entrypoint: "test/allow", input: map[string]any{"user": "a", "owner": "b"}, hits: []string{}},
+		{note: "partial set", entrypoint: "test/deny", input: map[string]any{"blocked": true}, hits: []string{"data.test.deny"}},
+		{note: "empty partial set", entrypoint: "test/deny", input: map[string]any{"blocked": false}, hits: []string{"data.test.deny"}},
+	} {
+		t.Run(tc.note, func(t *testing.T) {
+			var input any = tc.input
+			var hits []string
+			_, ctx := WithStatistics(context.Background())
+			if _, err := vm.Eval(ctx, tc.entrypoint, EvalOpts{Input: &input, TrackRuleHits: true, RuleHits: &hits}); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(hits, tc.hits) {
+				t.Fatalf("expected hits %v, got %v", tc.hits, hits)
+			}
+		})
+	}
+}
+
+// The rego.metadata.rule and rego.metadata.chain calls are replaced with
+// the annotations by the compiler, before planning: the VM evaluates
+// them as constants, without builtin support.
+func TestRegoMetadata(t *testing.T) {
+	rego := `# METADATA
+# title: pkg
+package test
+
+# METADATA
+# title: rule
+# custom:
+#   severity: high
+allow := [rego.metadata.rule(), rego.metadata.chain()]
+`
+
+	executable := setupExecutable(t, rego, "test/allow")
+
+	_, ctx := WithStatistics(context.Background())
+	result, err := NewVM().WithExecutable(executable).Eval(ctx, "test/allow", EvalOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ast.MustParseTerm(`{{"result": [
+		{"custom": {"severity": "high"}, "scope": "rule", "title": "rule"},
+		[
+			{"annotations": {"custom": {"severity": "high"}, "scope": "rule", "title": "rule"}, "path": ["test", "allow"]},
+			{"annotations": {"scope": "package", "title": "pkg"}, "path": ["test"]}
+		]
+	]}}`).Value
+	if expected.Compare(result) != 0 {
+		t.Fatalf("unexpected result %v", result)
+	}
+}
+
+func TestProtoInput(t *testing.T) {
+	rego := `package test
+allow if {
+	input.user == "alice"
+	time.parse_rfc3339_ns(input.expires) > 0
+	input.attributes.roles[_] == "admin"
+}`
+
+	executable := setupExecutable(t, rego, "test/allow")
+
+	attributes, err := structpb.NewStruct(map[string]any{"roles": []any{"admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := structpb.NewStruct(map[string]any{"user": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Fields["expires"] = structpb.NewStringValue("2025-01-02T03:04:05Z")
+	msg.Fields["attributes"] = structpb.NewStructValue(attributes)
+
+	doc, err := fjson.NewFromProto(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := any(doc)
+	_, ctx := WithStatistics(context.Background())
+	result, err := NewVM().WithExecutable(executable).Eval(ctx, "test/allow", EvalOpts{Input: &input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ast.MustParseTerm(`{{"result": true}}`).Value.Compare(result) != 0 {
+		t.Fatalf("unexpected result %v", result)
+	}
+}
+
+// The with keyword replacing time.now_ns is planned by specializing the
+// functions of the rules evaluated in its scope: the VM evaluates the
+// replacements without builtin support, nested scopes stacking and
+// unwinding with the plans.
+func TestWithTimeNow(t *testing.T) {
+	rego := `package test
+now := time.now_ns()
+mocked := x if { x := now with time.now_ns as 1 }
+mock_now := 5
+allow := [a, b, c, d, e] if {
+	a := mocked with time.now_ns as 2
+	b := now with time.now_ns as input.t
+	c := now with time.now_ns as mock_now
+	d := now
+	e := time.now_ns()
+}`
+
+	executable := setupExecutable(t, rego, "test/allow")
+
+	input := any(map[string]any{"t": 3})
+	_, ctx := WithStatistics(context.Background())
+	result, err := NewVM().WithExecutable(executable).Eval(ctx, "test/allow", EvalOpts{
+		Input: &input,
+		Time:  time.Unix(0, 4),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ast.MustParseTerm(`{{"result": [1, 3, 5, 4, 4]}}`).Value.Compare(result) != 0 {
+		t.Fatalf("unexpected result %v", result)
+	}
+}